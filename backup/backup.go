@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes and reads backup archives.
+ */
+
+// Package backup makes archives of everything in the cookbook, and restores
+// them. An archive is a zip file with a manifest, a JSON document for each
+// kind of record, and the stored files of photos and attachments under
+// blobs/.
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Format names what manifest.json describes, so other zip files aren't taken
+// for backups.
+const Format = "recipes-backup"
+
+// Version is the version of the archive format written. It changes whenever
+// the documents in it do in a way older versions can't read. Version 2 added
+// ratings.
+const Version = 2
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// How many records of each kind the archive has, by document.
+	Counts map[string]int `json:"counts"`
+}
+
+// The names of the documents in an archive.
+const (
+	manifestFile    = "manifest.json"
+	usersFile       = "users.json"
+	recipesFile     = "recipes.json"
+	revisionsFile   = "revisions.json"
+	tagsFile        = "tags.json"
+	linksFile       = "links.json"
+	photosFile      = "photos.json"
+	attachmentsFile = "attachments.json"
+	ratingsFile     = "ratings.json"
+)
+
+// blobPrefix is where blobs are kept in an archive, under their keys.
+const blobPrefix = "blobs/"
+
+// documents maps the documents in an archive to where they are in a backup.
+func documents(b *defs.Backup) map[string]interface{} {
+	return map[string]interface{}{
+		usersFile:       &b.Users,
+		recipesFile:     &b.Recipes,
+		revisionsFile:   &b.Revisions,
+		tagsFile:        &b.Tags,
+		linksFile:       &b.Links,
+		photosFile:      &b.Photos,
+		attachmentsFile: &b.Attachments,
+		ratingsFile:     &b.Ratings,
+	}
+}
+
+// blobKeys returns the keys of the blobs a backup's photos and attachments
+// are stored under.
+func blobKeys(b *defs.Backup) []string {
+	var keys []string
+	for _, p := range b.Photos {
+		for name := range blob.PhotoFiles {
+			keys = append(keys, blob.PhotoKey(p.ID, name))
+		}
+	}
+	for _, a := range b.Attachments {
+		keys = append(keys, blob.AttachmentKey(a.ID))
+	}
+	return keys
+}
+
+// Write writes an archive of b to w, with the blobs of its photos and
+// attachments from store. Blobs that are missing from the store are left
+// out, and restore without them.
+func Write(w io.Writer, b *defs.Backup, store blob.Store) error {
+	var z = zip.NewWriter(w)
+	var manifest = Manifest{
+		Format:  Format,
+		Version: Version,
+		Created: time.Now().UTC(),
+		Counts: map[string]int{
+			usersFile:       len(b.Users),
+			recipesFile:     len(b.Recipes),
+			revisionsFile:   len(b.Revisions),
+			tagsFile:        len(b.Tags.Recipes),
+			linksFile:       len(b.Links),
+			photosFile:      len(b.Photos),
+			attachmentsFile: len(b.Attachments),
+			ratingsFile:     len(b.Ratings),
+		},
+	}
+	var err = writeJSON(z, manifestFile, &manifest)
+	if err != nil {
+		return err
+	}
+	for name, doc := range documents(b) {
+		err = writeJSON(z, name, doc)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range blobKeys(b) {
+		var data, err = store.Get(key)
+		if err == blob.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		// Photos and most attachments are compressed already.
+		f, err := z.CreateHeader(&zip.FileHeader{
+			Name:   blobPrefix + key,
+			Method: zip.Store,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// writeJSON adds a JSON document to an archive.
+func writeJSON(z *zip.Writer, name string, v interface{}) error {
+	var f, err = z.Create(name)
+	if err != nil {
+		return err
+	}
+	var enc = json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Archive is a backup archive that has been read.
+type Archive struct {
+	Manifest Manifest
+	Backup   defs.Backup
+	// The blobs in the archive, by key, read as they're needed.
+	blobs map[string]*zip.File
+}
+
+// ErrNotBackup is returned for files that aren't backup archives.
+var ErrNotBackup = errors.New("not a backup archive")
+
+// Read reads an archive of size bytes from r. It doesn't check that what's
+// in it makes sense; use Check for that.
+func Read(r io.ReaderAt, size int64) (*Archive, error) {
+	var z, err = zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrNotBackup
+	}
+	var a = Archive{blobs: make(map[string]*zip.File)}
+	var docs = documents(&a.Backup)
+	docs[manifestFile] = &a.Manifest
+	var found = make(map[string]bool)
+	for _, f := range z.File {
+		if strings.HasPrefix(f.Name, blobPrefix) {
+			a.blobs[strings.TrimPrefix(f.Name, blobPrefix)] = f
+			continue
+		}
+		var doc, ok = docs[f.Name]
+		if !ok {
+			continue
+		}
+		err = readJSON(f, doc)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", f.Name, err)
+		}
+		found[f.Name] = true
+	}
+	if !found[manifestFile] || a.Manifest.Format != Format {
+		return nil, ErrNotBackup
+	}
+	if a.Manifest.Version > Version {
+		return nil, fmt.Errorf("backup is version %d, but only up to %d "+
+			"can be read", a.Manifest.Version, Version)
+	}
+	for name := range docs {
+		// Archives from before ratings were kept have none.
+		if name == ratingsFile && a.Manifest.Version < 2 {
+			continue
+		}
+		if !found[name] {
+			return nil, fmt.Errorf("backup has no %s", name)
+		}
+	}
+	return &a, nil
+}
+
+// readJSON decodes a JSON document in an archive into v.
+func readJSON(f *zip.File, v interface{}) error {
+	var r, err = f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Blob returns the blob stored under key in the archive, or blob.ErrNotFound.
+func (a *Archive) Blob(key string) ([]byte, error) {
+	var f, ok = a.blobs[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	var r, err = f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/defs"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// sample returns a small backup that makes sense.
+func sample() *defs.Backup {
+	return &defs.Backup{
+		Users: []defs.BackupUser{{ID: 1, Email: "jo@example.com", Name: "Jo"}},
+		Recipes: []defs.BackupRecipe{
+			{ID: 4, AuthorID: 1, Title: "Bread", Slug: "bread",
+				Directions: defs.DirectionSections{{Items: []defs.Direction{
+					{Text: "Knead.", Attachments: []int{9}}}}}},
+			{ID: 5, AuthorID: 1, Title: "Rye bread", Slug: "rye-bread"},
+		},
+		Revisions: []defs.BackupRevision{
+			{RecipeID: 4, Revision: 3, OldSlugs: []string{"loaf"}},
+			{RecipeID: 5, Revision: 1, ParentID: null.IntFrom(4),
+				ParentRevision: null.IntFrom(2)},
+		},
+		Tags: defs.BackupTags{
+			Recipes: []defs.BackupRecipeTag{{RecipeID: 4, Tag: "bread"}},
+			Info:    []defs.BackupTagInfo{{Tag: "bread", Color: "#a0522d"}},
+		},
+		Links:  []defs.BackupLink{{Src: 5, Dest: 4, Role: "variation"}},
+		Photos: []defs.BackupPhoto{{ID: 7, RecipeID: 4, Cover: true}},
+		Attachments: []defs.BackupAttachment{{ID: 9, RecipeID: 4,
+			Filename: "shaping.pdf", ContentType: "application/pdf"}},
+		Ratings: []defs.BackupRating{{RecipeID: 4, UserID: 1, Rating: 5}},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	var store, err = blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put("photos/7/full.jpg", []byte("full"), "image/jpeg")
+	store.Put("photos/7/thumb.jpg", []byte("thumb"), "image/jpeg")
+	store.Put("attachments/9", []byte("%PDF"), "application/pdf")
+	store.Put("photos/8/full.jpg", []byte("no one's"), "image/jpeg")
+
+	var buf bytes.Buffer
+	err = Write(&buf, sample(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Manifest.Version != Version || a.Manifest.Counts[recipesFile] != 2 {
+		t.Errorf("got manifest %+v", a.Manifest)
+	}
+	err = a.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b = a.Backup
+	if len(b.Recipes) != 2 || b.Recipes[0].Directions[0].Items[0].Attachments[0] != 9 ||
+		b.Revisions[1].ParentID.Int64 != 4 || b.Revisions[0].OldSlugs[0] != "loaf" ||
+		b.Tags.Info[0].Color != "#a0522d" || b.Attachments[0].Filename != "shaping.pdf" ||
+		len(b.Ratings) != 1 || b.Ratings[0].Rating != 5 {
+		t.Errorf("got backup %+v", b)
+	}
+	for key, want := range map[string]string{
+		"photos/7/full.jpg":  "full",
+		"photos/7/thumb.jpg": "thumb",
+		"attachments/9":      "%PDF",
+	} {
+		var got, err = a.Blob(key)
+		if err != nil || string(got) != want {
+			t.Errorf("blob %s: got %q, %v", key, got, err)
+		}
+	}
+	for _, key := range []string{"photos/7/thumb.webp", "photos/8/full.jpg"} {
+		var _, err = a.Blob(key)
+		if err != blob.ErrNotFound {
+			t.Errorf("blob %s: got %v", key, err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	var _, err = Read(strings.NewReader("not a zip"), 9)
+	if err != ErrNotBackup {
+		t.Errorf("got %v for a file that isn't a zip", err)
+	}
+
+	var archive = func(manifest string, docs ...string) *bytes.Reader {
+		var buf bytes.Buffer
+		var z = zip.NewWriter(&buf)
+		var f, _ = z.Create(manifestFile)
+		f.Write([]byte(manifest))
+		for _, name := range docs {
+			f, _ = z.Create(name)
+			f.Write([]byte("null"))
+		}
+		z.Close()
+		return bytes.NewReader(buf.Bytes())
+	}
+	var other = archive(`{"format": "something-else", "version": 1}`)
+	_, err = Read(other, other.Size())
+	if err != ErrNotBackup {
+		t.Errorf("got %v for another app's zip", err)
+	}
+	var newer = archive(`{"format": "recipes-backup", "version": 99}`)
+	_, err = Read(newer, newer.Size())
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("got %v for a newer version", err)
+	}
+	var partial = archive(`{"format": "recipes-backup", "version": 1}`)
+	_, err = Read(partial, partial.Size())
+	if err == nil || !strings.Contains(err.Error(), "has no") {
+		t.Errorf("got %v for an archive missing documents", err)
+	}
+	// Version 1 had no ratings.
+	var docs = []string{usersFile, recipesFile, revisionsFile, linksFile,
+		photosFile, attachmentsFile}
+	var v1 = archive(`{"format": "recipes-backup", "version": 1}`,
+		docs...)
+	_, err = Read(v1, v1.Size())
+	if err == nil || !strings.Contains(err.Error(), "has no "+tagsFile) {
+		t.Errorf("got %v for an archive missing tags", err)
+	}
+	v1 = archive(`{"format": "recipes-backup", "version": 1}`,
+		append(docs, tagsFile)...)
+	a, err := Read(v1, v1.Size())
+	if err != nil || len(a.Backup.Ratings) != 0 {
+		t.Errorf("got %v, %v for a version 1 archive", a, err)
+	}
+	var v2 = archive(`{"format": "recipes-backup", "version": 2}`,
+		append(docs, tagsFile)...)
+	_, err = Read(v2, v2.Size())
+	if err == nil || !strings.Contains(err.Error(), "has no "+ratingsFile) {
+		t.Errorf("got %v for a version 2 archive without ratings", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var b = sample()
+	b.Users = append(b.Users, defs.BackupUser{ID: 2, Email: "JO@example.com"})
+	b.Recipes[1].AuthorID = 3
+	b.Revisions = b.Revisions[:1]
+	b.Links = append(b.Links, defs.BackupLink{Src: 5, Dest: 6})
+	b.Photos = append(b.Photos, defs.BackupPhoto{ID: 7, RecipeID: 4, Cover: true})
+	b.Recipes[1].Directions = defs.DirectionSections{{Items: []defs.Direction{
+		{Text: "Bake.", Attachments: []int{9}}}}}
+	b.Ratings = append(b.Ratings, defs.BackupRating{RecipeID: 5, UserID: 3,
+		Rating: 4}, defs.BackupRating{RecipeID: 4, UserID: 1, Rating: 6})
+	var a = Archive{Backup: *b}
+	var err = a.Check()
+	var errs, ok = err.(Errors)
+	if !ok {
+		t.Fatalf("got %v", err)
+	}
+	var all = strings.Join(errs, "\n")
+	for _, want := range []string{
+		"more than one user has email JO@example.com",
+		"recipe 5 is by user 3",
+		"recipe 5 has no revision",
+		"link from recipe 5 to 6",
+		"photo 7 is there twice",
+		"recipe 4 has more than one cover photo",
+		"recipe 5 shows attachment 9",
+		"rating of recipe 5 by user 3",
+		"user 1 rated recipe 4 twice",
+		"user 1 rated recipe 4 6",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in:\n%s", want, all)
+		}
+	}
+	if len(errs) != 10 {
+		t.Errorf("got %d errors:\n%s", len(errs), all)
+	}
+}
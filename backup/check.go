@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file checks that a backup archive makes sense before it's restored.
+ */
+
+package backup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Errors lists everything wrong with an archive.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid backup: " + strings.Join(e, "; ")
+}
+
+// Check makes sure every record in the archive is whole, that IDs are
+// unique, and that everything refers to records that are in it. Anything
+// wrong is returned as Errors.
+func (a *Archive) Check() error {
+	var errs Errors
+	var fail = func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	var b = &a.Backup
+	var counts = map[string]int{
+		usersFile:       len(b.Users),
+		recipesFile:     len(b.Recipes),
+		revisionsFile:   len(b.Revisions),
+		tagsFile:        len(b.Tags.Recipes),
+		linksFile:       len(b.Links),
+		photosFile:      len(b.Photos),
+		attachmentsFile: len(b.Attachments),
+		ratingsFile:     len(b.Ratings),
+	}
+	for name, n := range counts {
+		if want, ok := a.Manifest.Counts[name]; ok && want != n {
+			fail("%s has %d records, but the manifest says %d", name, n, want)
+		}
+	}
+
+	var users = make(map[int]bool)
+	var emails = make(map[string]bool)
+	for _, u := range b.Users {
+		if users[u.ID] {
+			fail("user %d is there twice", u.ID)
+		}
+		users[u.ID] = true
+		var email = strings.ToLower(u.Email)
+		if email == "" {
+			fail("user %d has no email", u.ID)
+		} else if emails[email] {
+			fail("more than one user has email %s", u.Email)
+		}
+		emails[email] = true
+	}
+
+	var recipes = make(map[int]bool)
+	for _, r := range b.Recipes {
+		if recipes[r.ID] {
+			fail("recipe %d is there twice", r.ID)
+		}
+		recipes[r.ID] = true
+		if r.Title == "" {
+			fail("recipe %d has no title", r.ID)
+		}
+		if !users[r.AuthorID] {
+			fail("recipe %d is by user %d, who isn't in the backup",
+				r.ID, r.AuthorID)
+		}
+	}
+
+	var revisions = make(map[int]bool)
+	for _, r := range b.Revisions {
+		if !recipes[r.RecipeID] {
+			fail("revision of recipe %d, which isn't in the backup", r.RecipeID)
+		} else if revisions[r.RecipeID] {
+			fail("recipe %d has more than one revision", r.RecipeID)
+		}
+		revisions[r.RecipeID] = true
+		if r.ParentID.Valid && !recipes[int(r.ParentID.Int64)] {
+			fail("recipe %d is forked from recipe %d, which isn't in the "+
+				"backup", r.RecipeID, r.ParentID.Int64)
+		}
+	}
+	for id := range recipes {
+		if !revisions[id] {
+			fail("recipe %d has no revision", id)
+		}
+	}
+
+	for _, t := range b.Tags.Recipes {
+		if !recipes[t.RecipeID] {
+			fail("tag %q is on recipe %d, which isn't in the backup",
+				t.Tag, t.RecipeID)
+		}
+	}
+	var links = make(map[[2]int]bool)
+	for _, l := range b.Links {
+		if !recipes[l.Src] || !recipes[l.Dest] {
+			fail("link from recipe %d to %d, which aren't both in the backup",
+				l.Src, l.Dest)
+		}
+		if links[[2]int{l.Src, l.Dest}] {
+			fail("recipe %d is linked to %d twice", l.Src, l.Dest)
+		}
+		links[[2]int{l.Src, l.Dest}] = true
+	}
+
+	var photos = make(map[int]bool)
+	var covers = make(map[int]bool)
+	for _, p := range b.Photos {
+		if photos[p.ID] {
+			fail("photo %d is there twice", p.ID)
+		}
+		photos[p.ID] = true
+		if !recipes[p.RecipeID] {
+			fail("photo %d is of recipe %d, which isn't in the backup",
+				p.ID, p.RecipeID)
+		}
+		if p.Cover && covers[p.RecipeID] {
+			fail("recipe %d has more than one cover photo", p.RecipeID)
+		}
+		covers[p.RecipeID] = covers[p.RecipeID] || p.Cover
+	}
+
+	var attachments = make(map[int]int)
+	for _, at := range b.Attachments {
+		if _, ok := attachments[at.ID]; ok {
+			fail("attachment %d is there twice", at.ID)
+		}
+		attachments[at.ID] = at.RecipeID
+		if !recipes[at.RecipeID] {
+			fail("attachment %d is on recipe %d, which isn't in the backup",
+				at.ID, at.RecipeID)
+		}
+	}
+	var ratings = make(map[[2]int]bool)
+	for _, r := range b.Ratings {
+		if !recipes[r.RecipeID] || !users[r.UserID] {
+			fail("rating of recipe %d by user %d, which aren't both in the "+
+				"backup", r.RecipeID, r.UserID)
+		}
+		if ratings[[2]int{r.RecipeID, r.UserID}] {
+			fail("user %d rated recipe %d twice", r.UserID, r.RecipeID)
+		}
+		ratings[[2]int{r.RecipeID, r.UserID}] = true
+		if r.Rating < defs.MinRating || r.Rating > defs.MaxRating {
+			fail("user %d rated recipe %d %d, which is out of range",
+				r.UserID, r.RecipeID, r.Rating)
+		}
+	}
+
+	for _, r := range b.Recipes {
+		for _, section := range r.Directions {
+			for _, d := range section.Items {
+				for _, id := range d.Attachments {
+					if recipe, ok := attachments[id]; !ok || recipe != r.ID {
+						fail("recipe %d shows attachment %d, which isn't "+
+							"one of its own", r.ID, id)
+					}
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
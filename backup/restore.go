@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file restores backup archives into the database and blob store.
+ */
+
+package backup
+
+import (
+	"log"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Restore checks an archive and restores it into the database, with its
+// blobs put in store under the records' new keys. With replace set,
+// everything already there is deleted first; otherwise the archive is added
+// to what's there. Either all of it is restored or none of it is.
+func Restore(a *Archive, store blob.Store, replace bool) (
+	*defs.RestoreReport, error) {
+	var err = a.Check()
+	if err != nil {
+		return nil, err
+	}
+	// New IDs are never ones already used, so these keys are never ones
+	// anything else is stored under.
+	var written []string
+	var put = func(from, to string, contentType string) error {
+		var data, err = a.Blob(from)
+		if err == blob.ErrNotFound {
+			log.Println("backup has no " + from)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = store.Put(to, data, contentType)
+		if err != nil {
+			return err
+		}
+		written = append(written, to)
+		return nil
+	}
+	report, err := db.RestoreBackup(&a.Backup, replace,
+		func(report *defs.RestoreReport) error {
+			for _, p := range a.Backup.Photos {
+				for name, contentType := range blob.PhotoFiles {
+					var err = put(blob.PhotoKey(p.ID, name),
+						blob.PhotoKey(report.PhotoIDs[p.ID], name), contentType)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			for _, at := range a.Backup.Attachments {
+				var err = put(blob.AttachmentKey(at.ID),
+					blob.AttachmentKey(report.AttachmentIDs[at.ID]),
+					at.ContentType)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		deleteBlobs(store, written)
+		return nil, err
+	}
+
+	// What was replaced is gone from the database, so its blobs can go too.
+	var old []string
+	for _, id := range report.DeletedPhotoIDs {
+		for name := range blob.PhotoFiles {
+			old = append(old, blob.PhotoKey(id, name))
+		}
+	}
+	for _, id := range report.DeletedAttachmentIDs {
+		old = append(old, blob.AttachmentKey(id))
+	}
+	deleteBlobs(store, old)
+	return report, nil
+}
+
+// deleteBlobs removes blobs nothing refers to. Failures are only logged.
+func deleteBlobs(store blob.Store, keys []string) {
+	for _, key := range keys {
+		var err = store.Delete(key)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
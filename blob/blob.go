@@ -0,0 +1,27 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file defines the interface to blob storage for uploaded files.
+ */
+
+// Package blob stores uploaded files, like recipe photos, outside the
+// database.
+package blob
+
+import "errors"
+
+// ErrNotFound is returned when there is nothing stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key. Keys are slash-separated paths like
+// "photos/12/thumb.jpg".
+type Store interface {
+	// Put stores data under a key, replacing anything already there.
+	Put(key string, data []byte, contentType string) error
+	// Get returns the data stored under a key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+	// Delete removes the data under a key. Deleting a missing key is not an
+	// error.
+	Delete(key string) error
+}
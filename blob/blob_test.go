@@ -0,0 +1,109 @@
+package blob
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testStore puts, gets, and deletes a blob.
+func testStore(t *testing.T, s Store) {
+	var data = []byte("not really a jpeg")
+	var err = s.Put("photos/1/thumb.jpg", data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get("photos/1/thumb.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %q, want %q", got, data)
+	}
+	err = s.Delete("photos/1/thumb.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Get("photos/1/thumb.jpg"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err = s.Delete("photos/1/thumb.jpg"); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err = s.Put("../escape", []byte("x"), ""); err == nil {
+		t.Error("expected an error for a key outside the directory")
+	}
+}
+
+// s3StandIn is a minimal S3 server that keeps objects in memory and checks
+// that requests are signed.
+type s3StandIn struct {
+	sync.Mutex
+	objects map[string][]byte
+	t       *testing.T
+}
+
+func (s *s3StandIn) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	var auth = req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") ||
+		!strings.Contains(auth, "/us-test-1/s3/aws4_request") ||
+		!strings.Contains(auth, "Signature=") {
+		s.t.Errorf("bad authorization header: %q", auth)
+		res.WriteHeader(403)
+		return
+	}
+	var body, _ = ioutil.ReadAll(req.Body)
+	if req.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+		s.t.Error("payload hash doesn't match body")
+		res.WriteHeader(400)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, "/bucket/") {
+		res.WriteHeader(404)
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	switch req.Method {
+	case "PUT":
+		s.objects[req.URL.Path] = body
+	case "GET":
+		var data, ok = s.objects[req.URL.Path]
+		if !ok {
+			res.WriteHeader(404)
+			return
+		}
+		res.Write(data)
+	case "DELETE":
+		delete(s.objects, req.URL.Path)
+		res.WriteHeader(204)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	var server = httptest.NewServer(&s3StandIn{
+		objects: make(map[string][]byte),
+		t:       t,
+	})
+	defer server.Close()
+	testStore(t, NewS3Store(server.URL, "us-test-1", "bucket", "key",
+		"secret"))
+}
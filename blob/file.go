@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file implements blob storage on the local filesystem.
+ */
+
+package blob
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore keeps blobs as files under a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a Store that keeps blobs under dir, creating it if
+// needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	var err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path turns a key into a file path, refusing keys that would escape the
+// directory.
+func (s *FileStore) path(key string) (string, error) {
+	var clean = filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid blob key: " + key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// Put writes data to a file, via a temporary file so readers never see part
+// of it.
+func (s *FileStore) Put(key string, data []byte, contentType string) error {
+	var path, err = s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get reads a file.
+func (s *FileStore) Get(key string) ([]byte, error) {
+	var path, err = s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete removes a file.
+func (s *FileStore) Delete(key string) error {
+	var path, err = s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file says where each kind of uploaded file is kept.
+ */
+
+package blob
+
+import "strconv"
+
+// PhotoFiles are the images stored for each photo, with their content types.
+var PhotoFiles = map[string]string{
+	"full.jpg":  "image/jpeg",
+	"thumb.jpg": "image/jpeg",
+}
+
+// OldPhotoFiles are images older photos may have that we no longer make or
+// serve, which are deleted along with them.
+var OldPhotoFiles = []string{"thumb.webp"}
+
+// PhotoKey returns the key for one of a photo's images.
+func PhotoKey(photoID int, name string) string {
+	return "photos/" + strconv.Itoa(photoID) + "/" + name
+}
+
+// AttachmentKey returns the key for an attachment.
+func AttachmentKey(id int) string {
+	return "attachments/" + strconv.Itoa(id)
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file implements blob storage in an S3-compatible bucket, like AWS S3
+ * or MinIO.
+ */
+
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Store keeps blobs as objects in an S3 bucket, using path-style URLs like
+// https://endpoint/bucket/key. Requests are signed with AWS Signature Version
+// 4.
+type S3Store struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Store returns a Store for a bucket at an S3-compatible endpoint, like
+// "https://s3.us-east-1.amazonaws.com".
+func NewS3Store(endpoint, region, bucket, accessKey,
+	secretKey string) *S3Store {
+	return &S3Store{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Put uploads an object.
+func (s *S3Store) Put(key string, data []byte, contentType string) error {
+	var res, err = s.do("PUT", key, data, contentType)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// Get downloads an object.
+func (s *S3Store) Get(key string) ([]byte, error) {
+	var res, err = s.do("GET", key, nil, "")
+	if err == ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return ioutil.ReadAll(res.Body)
+}
+
+// Delete removes an object. S3 doesn't complain about missing objects.
+func (s *S3Store) Delete(key string) error {
+	var res, err = s.do("DELETE", key, nil, "")
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// do sends a signed request for an object, turning error statuses into errors.
+func (s *S3Store) do(method, key string, body []byte,
+	contentType string) (*http.Response, error) {
+	var u, err = url.Parse(s.endpoint + "/" + s.bucket + "/" + key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == 404 {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode/100 != 2 {
+		var msg, _ = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return nil, errors.New("s3 " + method + " " + key + ": " +
+			strconv.Itoa(res.StatusCode) + " " + string(msg))
+	}
+	return res, nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request.
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	var amzDate = now.Format("20060102T150405Z")
+	var date = now.Format("20060102")
+	var payloadHash = hashHex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	var canonicalRequest = strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	var scope = date + "/" + s.region + "/s3/aws4_request"
+	var stringToSign = "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hashHex([]byte(canonicalRequest))
+
+	var key = hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	var signature = hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+
+		s.accessKey+"/"+scope+", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
+
+// hashHex returns the hex-encoded SHA-256 of data.
+func hashHex(data []byte) string {
+	var sum = sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	var mac = hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,506 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file lays out recipes as a printable cookbook.
+ */
+
+// Package cookbook makes a printable PDF cookbook from a selection of
+// recipes. It has a title page, a table of contents, each recipe starting on
+// its own page with its photos, and an index of tags, with every page after
+// the title numbered. Entries in the contents and index link to their
+// recipes.
+package cookbook
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/pdf"
+)
+
+// Book is what goes in a cookbook.
+type Book struct {
+	Title string
+	// Shown under the title, like who it's for. May be empty.
+	Subtitle string
+	// The recipes, in the order they're printed.
+	Recipes []defs.Recipe
+	// Photo returns the full size JPEG of a photo. Photos it fails for are
+	// left out, and a nil Photo leaves them all out.
+	Photo func(photoID int) ([]byte, error)
+}
+
+// Page layout, in points.
+const (
+	margin       = 54
+	pageWidth    = pdf.LetterWidth
+	pageHeight   = pdf.LetterHeight
+	contentWidth = pageWidth - 2*margin
+	top          = pageHeight - margin
+	bottom       = margin + 18
+	// Where page numbers go.
+	footer = margin - 18
+	// The most photos shown with a recipe, and how tall they may be.
+	maxPhotos      = 3
+	maxPhotoHeight = 200
+	photoGap       = 8
+	// Lines of the contents and index.
+	entrySize  = 11
+	tocLeading = 16
+	// Room for the heading of the contents.
+	tocHeading = 48
+)
+
+// A style of text: its font, size, and the distance between lines.
+type style struct {
+	font    pdf.Font
+	size    float64
+	leading float64
+}
+
+var (
+	titleStyle   = style{pdf.Bold, 22, 26}
+	headingStyle = style{pdf.Bold, 14, 22}
+	groupStyle   = style{pdf.Bold, 11, 16}
+	metaStyle    = style{pdf.Italic, 10, 13}
+	bodyStyle    = style{pdf.Regular, 10.5, 13.5}
+	smallStyle   = style{pdf.Italic, 9, 12}
+)
+
+// layout keeps track of where the next thing goes as a book is written.
+type layout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	// The top of the next line.
+	y float64
+	// Written at the top of pages a recipe continues onto.
+	continued string
+}
+
+// Write makes a cookbook, writing it as a PDF.
+func Write(w io.Writer, book *Book) error {
+	var l = &layout{doc: pdf.New(pageWidth, pageHeight)}
+	l.doc.Title = book.Title
+	l.titlePage(book)
+
+	// The contents come before the recipes, but need their page numbers, so
+	// make room for them now and fill them in after. There's a line for each
+	// recipe and one for the index.
+	var perPage = int((top - bottom - tocHeading) / tocLeading)
+	var tocPages = make([]*pdf.Page, 1+len(book.Recipes)/perPage)
+	for i := range tocPages {
+		tocPages[i] = l.newPage()
+	}
+
+	var entries = make([]tocEntry, len(book.Recipes))
+	for i := range book.Recipes {
+		entries[i] = tocEntry{pdf.Regular, book.Recipes[i].Title,
+			l.recipe(&book.Recipes[i], book.Photo)}
+	}
+	var starts = make([]*pdf.Page, len(entries))
+	for i := range entries {
+		starts[i] = entries[i].page
+	}
+	if page := l.index(book.Recipes, starts); page != nil {
+		entries = append(entries, tocEntry{pdf.Bold, "Index", page})
+	}
+
+	for i, page := range tocPages {
+		var first = i * perPage
+		var end = first + perPage
+		if end > len(entries) {
+			end = len(entries)
+		}
+		if first > end {
+			first = end
+		}
+		tocPage(page, i == 0, entries[first:end])
+	}
+
+	var _, err = l.doc.WriteTo(w)
+	return err
+}
+
+// titlePage writes the first page, which isn't numbered.
+func (l *layout) titlePage(book *Book) {
+	var page = l.doc.AddPage()
+	var centered = func(y float64, s style, text string) {
+		page.Text((pageWidth-pdf.Width(s.font, s.size, text))/2, y, s.font,
+			s.size, text)
+	}
+	var y = pageHeight * 0.62
+	for _, line := range wrap(pdf.Bold, 32, contentWidth, book.Title) {
+		centered(y, style{pdf.Bold, 32, 38}, line)
+		y -= 38
+	}
+	if book.Subtitle != "" {
+		y -= 6
+		for _, line := range wrap(pdf.Italic, 14, contentWidth, book.Subtitle) {
+			centered(y, style{pdf.Italic, 14, 18}, line)
+			y -= 18
+		}
+	}
+	var count = strconv.Itoa(len(book.Recipes)) + " recipes"
+	if len(book.Recipes) == 1 {
+		count = "1 recipe"
+	}
+	page.Gray(0.4)
+	centered(y-24, style{pdf.Regular, 12, 16}, count)
+}
+
+// newPage starts a numbered page.
+func (l *layout) newPage() *pdf.Page {
+	l.page = l.doc.AddPage()
+	l.y = top
+	var number = strconv.Itoa(l.page.Number)
+	l.page.Gray(0.4)
+	l.page.Text((pageWidth-pdf.Width(pdf.Regular, 9, number))/2, footer,
+		pdf.Regular, 9, number)
+	l.page.Gray(0)
+	return l.page
+}
+
+// need makes sure there's room for something h points tall, going on to a
+// new page if there isn't.
+func (l *layout) need(h float64) {
+	if l.y-h >= bottom {
+		return
+	}
+	l.newPage()
+	if l.continued != "" {
+		l.page.Gray(0.4)
+		l.text(0, smallStyle, l.continued+" (continued)")
+		l.page.Gray(0)
+		l.y -= 6
+	}
+}
+
+// text writes a line at indent from the left margin, returning its
+// baseline.
+func (l *layout) text(indent float64, s style, line string) float64 {
+	l.need(s.leading)
+	var baseline = l.y - s.size
+	l.page.Text(margin+indent, baseline, s.font, s.size, line)
+	l.y -= s.leading
+	return baseline
+}
+
+// paragraph writes wrapped text at indent from the left margin. A label,
+// like a number or bullet, goes before the first line, in the indent.
+func (l *layout) paragraph(indent float64, s style, label, text string) {
+	for i, line := range wrap(s.font, s.size, contentWidth-indent, text) {
+		var baseline = l.text(indent, s, line)
+		if i == 0 && label != "" {
+			l.page.Text(margin+indent-pdf.Width(s.font, s.size, label+" "),
+				baseline, s.font, s.size, label)
+		}
+	}
+}
+
+// space leaves a gap, unless at the top of a page.
+func (l *layout) space(h float64) {
+	if l.y < top-h {
+		l.y -= h
+	}
+}
+
+// recipe writes a recipe starting on a new page, returning that page.
+func (l *layout) recipe(r *defs.Recipe, photo func(int) ([]byte, error)) *pdf.Page {
+	l.continued = ""
+	var start = l.newPage()
+	for _, line := range wrap(titleStyle.font, titleStyle.size, contentWidth,
+		r.Title) {
+		l.text(0, titleStyle, line)
+	}
+	l.continued = r.Title
+
+	var meta []string
+	if r.Amount != "" {
+		meta = append(meta, "Makes "+r.Amount)
+	}
+	if r.Time != "" {
+		meta = append(meta, "Time: "+r.Time)
+	}
+	if r.Oven != "" {
+		meta = append(meta, "Oven: "+r.Oven)
+	}
+	if r.AuthorName != "" {
+		meta = append(meta, "By "+r.AuthorName)
+	}
+	if len(meta) != 0 {
+		l.paragraph(0, metaStyle, "", strings.Join(meta, "   |   "))
+	}
+	if r.Source != "" {
+		l.paragraph(0, smallStyle, "", "Source: "+r.Source)
+	}
+	if len(r.Tags) != 0 {
+		l.paragraph(0, smallStyle, "", "Tags: "+strings.Join(r.Tags, ", "))
+	}
+	l.space(8)
+
+	if photo != nil {
+		l.photos(r.Photos, photo)
+	}
+	if r.Summary != "" {
+		for _, text := range splitParagraphs(r.Summary) {
+			l.paragraph(0, bodyStyle, "", text)
+			l.space(4)
+		}
+		l.space(4)
+	}
+
+	if len(r.Ingredients.Lines()) != 0 {
+		l.heading("Ingredients")
+		for _, section := range r.Ingredients {
+			if section.Heading != "" {
+				l.group(section.Heading)
+			}
+			for _, item := range section.Items {
+				l.paragraph(14, bodyStyle, "•", item)
+			}
+		}
+		l.space(8)
+	}
+
+	if len(r.Directions.Steps()) != 0 {
+		l.heading("Directions")
+		for _, section := range r.Directions {
+			if section.Heading != "" {
+				l.group(section.Heading)
+			}
+			for i, item := range section.Items {
+				for j, text := range splitParagraphs(item.Text) {
+					var label = ""
+					if j == 0 {
+						label = strconv.Itoa(i+1) + "."
+					}
+					l.paragraph(20, bodyStyle, label, text)
+				}
+				l.space(3)
+			}
+		}
+		l.space(5)
+	}
+
+	if r.Notes != "" {
+		l.heading("Notes")
+		for _, text := range splitParagraphs(r.Notes) {
+			l.paragraph(0, bodyStyle, "", text)
+			l.space(4)
+		}
+	}
+	return start
+}
+
+// heading writes the heading of a part of a recipe, keeping it with at least
+// one line of what follows.
+func (l *layout) heading(text string) {
+	l.need(headingStyle.leading + bodyStyle.leading)
+	l.text(0, headingStyle, text)
+}
+
+// group writes the heading of a section of ingredients or directions.
+func (l *layout) group(text string) {
+	l.need(groupStyle.leading + bodyStyle.leading)
+	l.space(2)
+	l.paragraph(0, groupStyle, "", text)
+}
+
+// photos draws up to maxPhotos of a recipe's photos in a row, the cover
+// first.
+func (l *layout) photos(list []defs.Photo, photo func(int) ([]byte, error)) {
+	var ordered []defs.Photo
+	for _, p := range list {
+		if p.Cover {
+			ordered = append([]defs.Photo{p}, ordered...)
+		} else {
+			ordered = append(ordered, p)
+		}
+	}
+	var images []*pdf.Image
+	var aspects = 0.0
+	for _, p := range ordered {
+		if len(images) == maxPhotos {
+			break
+		}
+		var data, err = photo(p.ID)
+		if err != nil {
+			continue
+		}
+		img, err := l.doc.AddJPEG(data)
+		if err != nil {
+			continue
+		}
+		images = append(images, img)
+		aspects += float64(img.Width) / float64(img.Height)
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	// Make them all the same height, as tall as fits across the page.
+	var h = (contentWidth - photoGap*float64(len(images)-1)) / aspects
+	h = math.Min(h, maxPhotoHeight)
+	l.need(h + photoGap)
+	var x = float64(margin)
+	for _, img := range images {
+		var w = h * float64(img.Width) / float64(img.Height)
+		l.page.Image(img, x, l.y-h, w, h)
+		x += w + photoGap
+	}
+	l.y -= h + 2*photoGap
+}
+
+// index writes the index of tags, returning its first page, or nil if no
+// recipe has tags.
+func (l *layout) index(recipes []defs.Recipe, starts []*pdf.Page) *pdf.Page {
+	var byTag = make(map[string][]int)
+	var tags []string
+	for i := range recipes {
+		for _, tag := range recipes[i].Tags {
+			if byTag[tag] == nil {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], i)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		var a, b = strings.ToLower(tags[i]), strings.ToLower(tags[j])
+		if a != b {
+			return a < b
+		}
+		return tags[i] < tags[j]
+	})
+
+	l.continued = ""
+	var first = l.newPage()
+	l.text(0, titleStyle, "Index")
+	l.space(8)
+	l.continued = "Index"
+	for _, tag := range tags {
+		l.need(groupStyle.leading + tocLeading)
+		l.text(0, groupStyle, tag)
+		for _, i := range byTag[tag] {
+			l.need(tocLeading)
+			writeEntry(l.page, l.y-entrySize,
+				tocEntry{pdf.Regular, recipes[i].Title, starts[i]})
+			l.y -= tocLeading
+		}
+		l.space(6)
+	}
+	return first
+}
+
+// tocEntry is a line of the contents or index.
+type tocEntry struct {
+	font  pdf.Font
+	title string
+	page  *pdf.Page
+}
+
+// tocPage fills in a page of the table of contents.
+func tocPage(page *pdf.Page, first bool, entries []tocEntry) {
+	var heading = "Contents"
+	if !first {
+		heading = "Contents (continued)"
+	}
+	page.Text(margin, top-titleStyle.size, titleStyle.font, titleStyle.size,
+		heading)
+	var y = float64(top - tocHeading)
+	for _, e := range entries {
+		writeEntry(page, y, e)
+		y -= tocLeading
+	}
+}
+
+// writeEntry writes a line of the contents or index with its baseline at y:
+// the title, dots, and the page it's on, linked to that page.
+func writeEntry(page *pdf.Page, y float64, e tocEntry) {
+	var number = strconv.Itoa(e.page.Number)
+	var numberWidth = pdf.Width(e.font, entrySize, number)
+	// Leave room for at least a few dots.
+	var room = contentWidth - numberWidth -
+		pdf.Width(e.font, entrySize, " . . . ")
+	var title = truncate(e.font, entrySize, room, e.title)
+	page.Text(margin, y, e.font, entrySize, title)
+	page.Text(pageWidth-margin-numberWidth, y, e.font, entrySize, number)
+
+	var start = margin + pdf.Width(e.font, entrySize, title+" ")
+	var end = pageWidth - margin - numberWidth -
+		pdf.Width(e.font, entrySize, " ")
+	var dot = pdf.Width(e.font, entrySize, ". ")
+	var dots = int((end - start) / dot)
+	if dots > 0 {
+		page.Gray(0.5)
+		page.Text(end-float64(dots)*dot, y, e.font, entrySize,
+			strings.Repeat(". ", dots))
+		page.Gray(0)
+	}
+	page.Link(margin, y-4, contentWidth, entrySize+4, e.page)
+}
+
+// truncate shortens text to fit within width, ending it with an ellipsis.
+func truncate(font pdf.Font, size, width float64, text string) string {
+	if pdf.Width(font, size, text) <= width {
+		return text
+	}
+	var runes = []rune(text)
+	for len(runes) > 0 &&
+		pdf.Width(font, size, string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
+
+// wrap breaks text into lines no wider than width. Words too long for a line
+// are broken wherever they have to be.
+func wrap(font pdf.Font, size, width float64, text string) []string {
+	var lines []string
+	var line = ""
+	for _, word := range strings.Fields(text) {
+		var next = word
+		if line != "" {
+			next = line + " " + word
+		}
+		if pdf.Width(font, size, next) <= width {
+			line = next
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = word
+		for pdf.Width(font, size, line) > width {
+			var runes = []rune(line)
+			var n = len(runes) - 1
+			for n > 1 && pdf.Width(font, size, string(runes[:n])) > width {
+				n--
+			}
+			lines = append(lines, string(runes[:n]))
+			line = string(runes[n:])
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitParagraphs splits text on line breaks, leaving out blank lines.
+func splitParagraphs(text string) []string {
+	var list []string
+	for _, p := range strings.Split(text, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
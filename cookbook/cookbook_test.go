@@ -0,0 +1,111 @@
+package cookbook
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/pdf"
+)
+
+func TestWrite(t *testing.T) {
+	var steps []defs.Direction
+	for i := 0; i < 80; i++ {
+		steps = append(steps, defs.Direction{Text: strings.Repeat(
+			"Stir the pot slowly and keep an eye on it. ", 3)})
+	}
+	var photo bytes.Buffer
+	var err = jpeg.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var book = Book{
+		Title:    "The Family Cookbook",
+		Subtitle: "Holidays",
+		Recipes: []defs.Recipe{{
+			Title:  "Apple Pie",
+			Tags:   []string{"dessert", "Baking"},
+			Photos: []defs.Photo{{ID: 1}, {ID: 2, Cover: true}},
+			Ingredients: defs.IngredientSections{{Heading: "Crust",
+				Items: []string{"2 cups flour"}}},
+			Directions: defs.DirectionSections{{Items: []defs.Direction{
+				{Text: "Bake."}}}},
+		}, {
+			Title:      "Long Stew",
+			Tags:       []string{"dinner"},
+			Directions: defs.DirectionSections{{Items: steps}},
+		}, {
+			Title: "Toast",
+			Tags:  []string{"dessert"},
+		}},
+		Photo: func(id int) ([]byte, error) {
+			if id == 1 {
+				return nil, errors.New("missing")
+			}
+			return photo.Bytes(), nil
+		},
+	}
+
+	var buf bytes.Buffer
+	err = Write(&buf, &book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = buf.String()
+	for _, want := range []string{
+		"(The Family Cookbook) Tj",
+		"(Contents) Tj",
+		"(Long Stew \\(continued\\)) Tj",
+		"(Index) Tj",
+		"/Im1 Do",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+	// Only the photo that could be had is in it.
+	if strings.Contains(out, "/Im2") {
+		t.Error("a missing photo was included")
+	}
+}
+
+func TestRecipePages(t *testing.T) {
+	var l = &layout{doc: pdf.New(pageWidth, pageHeight)}
+	var short = defs.Recipe{Title: "Toast", Directions: defs.DirectionSections{
+		{Items: []defs.Direction{{Text: "Toast the bread."}}}}}
+	var start = l.recipe(&short, nil)
+	if start.Number != 1 || l.page != start {
+		t.Errorf("a short recipe took pages %d to %d", start.Number,
+			l.page.Number)
+	}
+
+	// Each recipe starts on its own page, and long ones continue.
+	var long = short
+	long.Ingredients = defs.IngredientSections{{
+		Items: strings.Split(strings.Repeat("1 egg\n", 100), "\n")}}
+	start = l.recipe(&long, nil)
+	if start.Number != 2 || l.page.Number < 3 {
+		t.Errorf("a long recipe took pages %d to %d", start.Number,
+			l.page.Number)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var width = pdf.Width(pdf.Regular, 10, "aaaa bbbb")
+	var cases = map[string][]string{
+		"aaaa bbbb cccc":   {"aaaa bbbb", "cccc"},
+		"  aaaa   bbbb  ":  {"aaaa bbbb"},
+		"":                 nil,
+		"aaaaaaaaaaaaaaaa": {"aaaaaaaa", "aaaaaaaa"},
+	}
+	for text, want := range cases {
+		var got = wrap(pdf.Regular, 10, width, text)
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("wrap(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
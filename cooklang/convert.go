@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file turns Cooklang files into recipes.
+ */
+
+package cooklang
+
+import (
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Text returns a step as plain text. Ingredients and cookware are written by
+// name, and timers by how long they are.
+func (s Step) Text() string {
+	var b strings.Builder
+	for _, item := range s {
+		switch item.Kind {
+		case KindTimer:
+			b.WriteString(strings.TrimSpace(item.Quantity + " " + item.Unit))
+		default:
+			b.WriteString(item.Value)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ingredient returns an ingredient item as a defs.Ingredient.
+func (item Item) ingredient() defs.Ingredient {
+	return defs.Ingredient{Quantity: item.Quantity, Unit: item.Unit,
+		Name: item.Value, Note: item.Note}
+}
+
+// Ingredients returns the ingredients used in all steps. Ones with the same
+// name and unit are added together where their quantities allow it.
+func (r *Recipe) Ingredients() []defs.Ingredient {
+	var list []defs.Ingredient
+	for _, section := range r.Sections {
+		list = append(list, section.ingredients()...)
+	}
+	return combine(list)
+}
+
+// ingredients returns the ingredients used in a section, in order.
+func (s Section) ingredients() []defs.Ingredient {
+	var list []defs.Ingredient
+	for _, step := range s.Steps {
+		for _, item := range step {
+			if item.Kind == KindIngredient {
+				list = append(list, item.ingredient())
+			}
+		}
+	}
+	return list
+}
+
+// Cookware returns the cookware used in all steps, once each.
+func (r *Recipe) Cookware() []defs.Cookware {
+	var list []defs.Cookware
+	var seen = map[string]bool{}
+	for _, section := range r.Sections {
+		for _, step := range section.Steps {
+			for _, item := range step {
+				var key = strings.ToLower(item.Value)
+				if item.Kind != KindCookware || seen[key] {
+					continue
+				}
+				seen[key] = true
+				list = append(list, defs.Cookware{Name: item.Value,
+					Quantity: item.Quantity})
+			}
+		}
+	}
+	return list
+}
+
+// Timers returns the timers in all steps, in order.
+func (r *Recipe) Timers() []defs.Timer {
+	var list []defs.Timer
+	for _, section := range r.Sections {
+		for _, step := range section.Steps {
+			for _, item := range step {
+				if item.Kind == KindTimer {
+					list = append(list, defs.Timer{Name: item.Value,
+						Quantity: item.Quantity, Unit: item.Unit})
+				}
+			}
+		}
+	}
+	return list
+}
+
+// combine adds together ingredients with the same name, unit, and note, when
+// both quantities are numbers or both are missing.
+func combine(list []defs.Ingredient) []defs.Ingredient {
+	var out []defs.Ingredient
+	for _, ing := range list {
+		var merged = false
+		for i := range out {
+			var o = &out[i]
+			if !strings.EqualFold(o.Name, ing.Name) ||
+				!strings.EqualFold(o.Unit, ing.Unit) || o.Note != ing.Note {
+				continue
+			}
+			if o.Quantity == "" && ing.Quantity == "" {
+				merged = true
+				break
+			}
+			var a, aHigh, aOK = defs.ParseQuantity(o.Quantity)
+			var b, bHigh, bOK = defs.ParseQuantity(ing.Quantity)
+			if aOK && bOK && a == aHigh && b == bHigh {
+				o.Quantity = defs.FormatQuantity(a + b)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			out = append(out, ing)
+		}
+	}
+	return out
+}
+
+// ingredientLine writes an ingredient the way recipes list them, like
+// "2 cups flour, sifted".
+func ingredientLine(ing defs.Ingredient) string {
+	var line = strings.Join(strings.Fields(
+		ing.Quantity+" "+ing.Unit+" "+ing.Name), " ")
+	if ing.Note != "" {
+		line += ", " + ing.Note
+	}
+	return line
+}
+
+// ToRecipe returns the file as a recipe. Ingredients are listed by section,
+// in the order the steps use them. Metadata without a place in a recipe is
+// added to the notes.
+func (r *Recipe) ToRecipe() *defs.Recipe {
+	var recipe = defs.Recipe{
+		Tags:        []string{},
+		Ingredients: defs.IngredientSections{},
+		Directions:  defs.DirectionSections{},
+	}
+	var extra []string
+	for _, m := range r.Metadata {
+		switch strings.ToLower(m.Key) {
+		case "title":
+			recipe.Title = m.Value
+		case "tags":
+			for _, tag := range strings.Split(m.Value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					recipe.Tags = append(recipe.Tags, tag)
+				}
+			}
+		case "servings", "serves", "yield":
+			recipe.Amount = m.Value
+		case "time", "duration", "total time", "time required":
+			recipe.Time = m.Value
+		case "source", "source.name", "source.url", "author":
+			if recipe.Source == "" {
+				recipe.Source = m.Value
+			} else {
+				extra = append(extra, m.Key+": "+m.Value)
+			}
+		case "description", "introduction":
+			recipe.Summary = m.Value
+		case "oven":
+			recipe.Oven = m.Value
+		default:
+			extra = append(extra, m.Key+": "+m.Value)
+		}
+	}
+
+	for _, section := range r.Sections {
+		var lines = []string{}
+		for _, ing := range combine(section.ingredients()) {
+			lines = append(lines, ingredientLine(ing))
+		}
+		if len(lines) != 0 {
+			recipe.Ingredients = append(recipe.Ingredients,
+				defs.IngredientSection{Heading: section.Name, Items: lines})
+		}
+		var directions = defs.DirectionSection{Heading: section.Name,
+			Items: []defs.Direction{}}
+		for _, step := range section.Steps {
+			directions.Items = append(directions.Items,
+				defs.Direction{Text: step.Text()})
+		}
+		recipe.Directions = append(recipe.Directions, directions)
+	}
+
+	var notes = append([]string{}, r.Notes...)
+	if extra != nil {
+		notes = append(notes, strings.Join(extra, "\n"))
+	}
+	recipe.Notes = strings.Join(notes, "\n\n")
+	return &recipe
+}
@@ -0,0 +1,178 @@
+package cooklang
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected test output")
+
+func text(s string) Item {
+	return Item{Kind: KindText, Value: s}
+}
+
+func TestParseStep(t *testing.T) {
+	var cases = []struct {
+		source string
+		want   Step
+	}{
+		{"Add @salt.", Step{text("Add "),
+			{Kind: KindIngredient, Value: "salt"}, text(".")}},
+		{"Add @salt{}", Step{text("Add "),
+			{Kind: KindIngredient, Value: "salt"}}},
+		{"Add @ground black pepper{}.", Step{text("Add "),
+			{Kind: KindIngredient, Value: "ground black pepper"}, text(".")}},
+		{"@potato{2}", Step{
+			{Kind: KindIngredient, Value: "potato", Quantity: "2"}}},
+		{"@bacon strips{1%kg}", Step{{Kind: KindIngredient,
+			Value: "bacon strips", Quantity: "1", Unit: "kg"}}},
+		{"@milk{1/2 %cup}", Step{{Kind: KindIngredient,
+			Value: "milk", Quantity: "1/2", Unit: "cup"}}},
+		{"@salt{=1%tsp}", Step{{Kind: KindIngredient,
+			Value: "salt", Quantity: "1", Unit: "tsp", Fixed: true}}},
+		{"@onion{1}(peeled and finely chopped)", Step{{Kind: KindIngredient,
+			Value: "onion", Quantity: "1", Note: "peeled and finely chopped"}}},
+		{"@./sauces/Hollandaise{150%g}", Step{{Kind: KindIngredient,
+			Value: "./sauces/Hollandaise", Quantity: "150", Unit: "g"}}},
+		{"@salt and @pepper{}", Step{{Kind: KindIngredient, Value: "salt"},
+			text(" and "), {Kind: KindIngredient, Value: "pepper"}}},
+		{"Use a #pot.", Step{text("Use a "),
+			{Kind: KindCookware, Value: "pot"}, text(".")}},
+		{"#frying pan{2}", Step{
+			{Kind: KindCookware, Value: "frying pan", Quantity: "2"}}},
+		{"Bake ~{25%minutes}.", Step{text("Bake "),
+			{Kind: KindTimer, Quantity: "25", Unit: "minutes"}, text(".")}},
+		{"~eggs{3%min}", Step{
+			{Kind: KindTimer, Value: "eggs", Quantity: "3", Unit: "min"}}},
+		// Things that look like markup but aren't.
+		{"Wait ~5 min", Step{text("Wait ~5 min")}},
+		{"email me @ home", Step{text("email me @ home")}},
+		{"Mix, then add @ sugar{}", Step{text("Mix, then add @ sugar{}")}},
+		{"a lone # and @", Step{text("a lone # and @")}},
+		{"Add @flour{2%cups", Step{text("Add "),
+			{Kind: KindIngredient, Value: "flour"}, text("{2%cups")}},
+		{`Costs \@5 \#1`, Step{text("Costs @5 #1")}},
+	}
+	for _, c := range cases {
+		var got = parseLine(c.source)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %#v, want %#v", c.source, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var cases = map[string]Errors{
+		"---\ntitle: x\n":      {{Line: 1, Message: `front matter has no closing "---"`}},
+		"Mix.\n\n[- oops\nend": {{Line: 3, Message: `block comment has no closing "-]"`}},
+	}
+	for source, want := range cases {
+		var _, err = Parse([]byte(source))
+		if !reflect.DeepEqual(err, want) {
+			t.Errorf("%q: got %v, want %v", source, err, want)
+		}
+	}
+}
+
+// parsed is what the corpus tests compare.
+type parsed struct {
+	Recipe      *defs.Recipe      `json:"recipe"`
+	Ingredients []defs.Ingredient `json:"ingredients"`
+	Cookware    []defs.Cookware   `json:"cookware"`
+	Timers      []defs.Timer      `json:"timers"`
+}
+
+// TestCorpus parses each file in testdata and compares the result with the
+// JSON file of the same name. Run with -update to rewrite those.
+func TestCorpus(t *testing.T) {
+	var files, err = filepath.Glob("testdata/*.cook")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no test files", err)
+	}
+	for _, name := range files {
+		var data, err = ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		got, err := json.MarshalIndent(parsed{r.ToRecipe(), r.Ingredients(),
+			r.Cookware(), r.Timers()}, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected = strings.TrimSuffix(name, ".cook") + ".json"
+		if *update {
+			ioutil.WriteFile(expected, append(got, '\n'), 0644)
+			continue
+		}
+		want, err := ioutil.ReadFile(expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got)+"\n" != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var recipe = defs.Recipe{
+		Title:   "Pancakes",
+		Tags:    []string{"breakfast", "quick"},
+		Amount:  "4",
+		Time:    "20 minutes",
+		Oven:    "",
+		Source:  "Mom: the good one",
+		Summary: "Fluffy.",
+		Ingredients: defs.IngredientSections{{Items: []string{
+			"2 cups flour, sifted", "2 eggs", "1 pinch of salt",
+			"butter for the pan (optional)",
+		}}},
+		Directions: defs.DirectionSections{
+			{Heading: "", Items: []defs.Direction{
+				{Text: "Whisk the flour and eggs -- gently -- with a #2 whisk."},
+			}},
+			{Heading: "Cooking", Items: []defs.Direction{
+				{Text: "Fry in a pan.\nFlip once."},
+			}},
+		},
+		Notes: "> Keeps a day.\n\nFreezes well.",
+	}
+	var data = FromRecipe(&recipe).Bytes()
+	var r, err = Parse(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	var got = r.ToRecipe()
+
+	var want = recipe
+	// Ingredients the directions don't mention come first, in a step of
+	// their own.
+	want.Ingredients = defs.IngredientSections{{Items: []string{
+		"1 pinch salt", "butter for the pan, optional",
+		"2 cups flour, sifted", "2 eggs",
+	}}}
+	want.Directions = defs.DirectionSections{
+		{Heading: "", Items: []defs.Direction{
+			{Text: "Ingredients: salt, butter for the pan."},
+			{Text: "Whisk the flour and eggs -- gently -- with a #2 whisk."},
+		}},
+		{Heading: "Cooking", Items: []defs.Direction{
+			{Text: "Fry in a pan. Flip once."},
+		}},
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("got %+v\nwant %+v\n%s", got, want, data)
+	}
+}
@@ -0,0 +1,438 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file reads Cooklang files.
+ */
+
+// Package cooklang reads and writes recipes in Cooklang, a plain text format
+// where ingredients, cookware, and timers are marked up in the directions:
+//
+//	>> servings: 4
+//
+//	Boil @water{2%l} in a #large pot{}, then add @pasta{500%g}.
+//	Cook for ~{10%minutes}, then drain.
+//
+// See https://cooklang.org/docs/spec/ for the full format. Metadata may be
+// written as YAML front matter or as ">>" lines. Beyond the spec, a
+// backslash before a character with special meaning, like "\@", makes it
+// plain text.
+package cooklang
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kinds of Item.
+const (
+	KindText       = "text"
+	KindIngredient = "ingredient"
+	KindCookware   = "cookware"
+	KindTimer      = "timer"
+)
+
+// Recipe is a Cooklang file.
+type Recipe struct {
+	// In the order written.
+	Metadata []Metadata
+	Sections []Section
+	// Paragraphs written with ">", which aren't steps.
+	Notes []string
+}
+
+// Metadata is a key and its value. A list is written with commas between
+// items.
+type Metadata struct {
+	Key   string
+	Value string
+}
+
+// Section is a group of steps. The first one may not have a name.
+type Section struct {
+	Name  string
+	Steps []Step
+}
+
+// Step is one paragraph of directions, made of text and markup.
+type Step []Item
+
+// Item is a piece of a step.
+type Item struct {
+	// One of the Kind constants.
+	Kind string
+	// The text, or the name of what's marked up.
+	Value string
+	// For ingredients, cookware, and timers. Cookware has no unit.
+	Quantity string
+	Unit     string
+	// For ingredients, how it's prepared, like "chopped".
+	Note string
+	// For ingredients, whether the quantity stays the same when scaling.
+	Fixed bool
+}
+
+// Error is a problem with a Cooklang file, found at a line of it.
+type Error struct {
+	// Line numbers start at 1.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Message
+}
+
+// Errors are all the problems found in a Cooklang file, in order.
+type Errors []Error
+
+func (e Errors) Error() string {
+	var messages = make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Characters a backslash makes plain.
+const escapable = `\@#~-[=>`
+
+// Characters that can't be in a name spanning several words, so a "{" further
+// on belongs to something else.
+const notInName = "@#~{}()%.,;:!?"
+
+// Parse reads a Cooklang file. Markup the spec doesn't allow is read as
+// text, so the only errors are front matter or block comments that don't
+// end, given as Errors.
+func Parse(data []byte) (*Recipe, error) {
+	var text = strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	var lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	var r Recipe
+	var errs Errors
+
+	// YAML front matter.
+	var first = 0
+	if len(lines) != 0 && strings.TrimSpace(lines[0]) == "---" {
+		var end = 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "---" {
+			end++
+		}
+		if end == len(lines) {
+			return nil, Errors{{Line: 1,
+				Message: `front matter has no closing "---"`}}
+		}
+		r.Metadata = frontMatter(lines[1:end])
+		first = end + 1
+	}
+
+	lines, errs = stripComments(lines, first)
+	if errs != nil {
+		return nil, errs
+	}
+
+	var step Step
+	// Whether the previous line was a note, to join them into paragraphs.
+	var inNote = false
+	var endStep = func() {
+		if len(step) != 0 {
+			if len(r.Sections) == 0 {
+				r.Sections = append(r.Sections, Section{})
+			}
+			var last = &r.Sections[len(r.Sections)-1]
+			last.Steps = append(last.Steps, step)
+			step = nil
+		}
+	}
+	for _, line := range lines[first:] {
+		var trimmed = strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			endStep()
+			inNote = false
+		case strings.HasPrefix(trimmed, ">>"):
+			if m, ok := metadataLine(trimmed[2:]); ok {
+				r.Metadata = append(r.Metadata, m)
+			}
+		case strings.HasPrefix(trimmed, ">"):
+			endStep()
+			var note = unescape(strings.TrimSpace(trimmed[1:]))
+			if inNote {
+				r.Notes[len(r.Notes)-1] += "\n" + note
+			} else {
+				r.Notes = append(r.Notes, note)
+			}
+			inNote = true
+		case strings.HasPrefix(trimmed, "="):
+			endStep()
+			inNote = false
+			r.Sections = append(r.Sections, Section{
+				Name: unescape(strings.Trim(trimmed, "= \t")),
+			})
+		default:
+			inNote = false
+			if len(step) != 0 {
+				step = appendText(step, " ")
+			}
+			for _, item := range parseLine(trimmed) {
+				if item.Kind == KindText {
+					step = appendText(step, item.Value)
+				} else {
+					step = append(step, item)
+				}
+			}
+		}
+	}
+	endStep()
+	return &r, nil
+}
+
+// appendText adds text to a step, joining it with any text before it.
+func appendText(step Step, text string) Step {
+	if n := len(step); n != 0 && step[n-1].Kind == KindText {
+		step[n-1].Value += text
+		return step
+	}
+	return append(step, Item{Kind: KindText, Value: text})
+}
+
+// stripComments removes "[- -]" block comments and "--" line comments from
+// lines, starting from first. Lines inside block comments become blank, so
+// line numbers stay the same.
+func stripComments(lines []string, first int) ([]string, Errors) {
+	var out = make([]string, len(lines))
+	copy(out, lines)
+	// Where the current block comment started, or -1.
+	var open = -1
+	for n := first; n < len(out); n++ {
+		var line = out[n]
+		var b strings.Builder
+		for i := 0; i < len(line); i++ {
+			if open >= 0 {
+				if strings.HasPrefix(line[i:], "-]") {
+					open = -1
+					i++
+				}
+				continue
+			}
+			if line[i] == '\\' && i+1 < len(line) {
+				b.WriteString(line[i : i+2])
+				i++
+				continue
+			}
+			if strings.HasPrefix(line[i:], "[-") {
+				open = n
+				i++
+				continue
+			}
+			if strings.HasPrefix(line[i:], "--") {
+				break
+			}
+			b.WriteByte(line[i])
+		}
+		out[n] = b.String()
+	}
+	if open >= 0 {
+		return nil, Errors{{Line: open + 1,
+			Message: `block comment has no closing "-]"`}}
+	}
+	return out, nil
+}
+
+// parseLine reads the text and markup in a line of a step.
+func parseLine(line string) Step {
+	var step Step
+	var text strings.Builder
+	var flush = func() {
+		if text.Len() != 0 {
+			step = append(step, Item{Kind: KindText, Value: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(line); {
+		var c = line[i]
+		if c == '\\' && i+1 < len(line) &&
+			strings.IndexByte(escapable, line[i+1]) >= 0 {
+			text.WriteByte(line[i+1])
+			i += 2
+			continue
+		}
+		if c == '@' || c == '#' || c == '~' {
+			if item, n, ok := markup(line[i:]); ok {
+				flush()
+				step = append(step, item)
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return step
+}
+
+// markup reads an ingredient, cookware, or timer from the start of s, and
+// returns it with its length. It returns false if s doesn't start with one.
+func markup(s string) (Item, int, bool) {
+	var item Item
+	switch s[0] {
+	case '@':
+		item.Kind = KindIngredient
+	case '#':
+		item.Kind = KindCookware
+	case '~':
+		item.Kind = KindTimer
+	}
+	var rest = s[1:]
+	var n = 1
+
+	// A name of any length, followed by braces. References to other recipes
+	// are paths, like "./sauces/Hollandaise".
+	var forbidden = notInName
+	if strings.HasPrefix(rest, "./") {
+		forbidden = strings.Replace(forbidden, ".", "", 1)
+	}
+	var braced = false
+	if brace := strings.IndexByte(rest, '{'); brace >= 0 &&
+		!strings.ContainsAny(rest[:brace], forbidden) {
+		if end := strings.IndexByte(rest[brace:], '}'); end >= 0 {
+			item.Value = strings.TrimSpace(rest[:brace])
+			readAmount(&item, rest[brace+1:brace+end])
+			n += brace + end + 1
+			braced = true
+			// A name can't start with a space.
+			if brace != 0 && unicode.IsSpace(rune(rest[0])) {
+				return Item{}, 0, false
+			}
+		}
+	}
+	// Otherwise, a single word.
+	if !braced {
+		var end = strings.IndexFunc(rest, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		if end < 0 {
+			end = len(rest)
+		}
+		item.Value = rest[:end]
+		n += end
+	}
+	// Timers need braces, and everything else needs a name.
+	if item.Kind == KindTimer && !braced ||
+		item.Kind != KindTimer && item.Value == "" {
+		return Item{}, 0, false
+	}
+
+	// How an ingredient is prepared.
+	if item.Kind == KindIngredient && braced && strings.HasPrefix(s[n:], "(") {
+		if end := strings.IndexByte(s[n:], ')'); end >= 0 {
+			item.Note = strings.TrimSpace(s[n+1 : n+end])
+			n += end + 1
+		}
+	}
+	return item, n, true
+}
+
+// readAmount reads what's between braces, like "=1/2%cup", into item.
+func readAmount(item *Item, amount string) {
+	amount = strings.TrimSpace(amount)
+	if item.Kind == KindIngredient && strings.HasPrefix(amount, "=") {
+		item.Fixed = true
+		amount = strings.TrimSpace(amount[1:])
+	}
+	var percent = strings.IndexByte(amount, '%')
+	if percent < 0 {
+		item.Quantity = amount
+		return
+	}
+	item.Quantity = strings.TrimSpace(amount[:percent])
+	if item.Kind != KindCookware {
+		item.Unit = strings.TrimSpace(amount[percent+1:])
+	}
+}
+
+// unescape removes backslashes that make special characters plain.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) &&
+			strings.IndexByte(escapable, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// metadataLine reads "key: value" from a ">>" line.
+func metadataLine(s string) (Metadata, bool) {
+	var colon = strings.Index(s, ":")
+	if colon < 0 {
+		return Metadata{}, false
+	}
+	return Metadata{
+		Key:   strings.TrimSpace(s[:colon]),
+		Value: unescape(strings.TrimSpace(s[colon+1:])),
+	}, true
+}
+
+// frontMatter reads the simple YAML that Cooklang files use for metadata:
+// "key: value" lines, where a value may be quoted or a list in brackets or
+// on "- " lines after it.
+func frontMatter(lines []string) []Metadata {
+	var metadata []Metadata
+	for _, line := range lines {
+		var trimmed = strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		// An item of a list under the last key.
+		if strings.HasPrefix(trimmed, "- ") && len(metadata) != 0 {
+			var last = &metadata[len(metadata)-1]
+			if last.Value != "" {
+				last.Value += ", "
+			}
+			last.Value += yamlScalar(trimmed[2:])
+			continue
+		}
+		var colon = strings.Index(trimmed, ":")
+		if colon < 0 {
+			continue
+		}
+		var m = Metadata{Key: strings.TrimSpace(trimmed[:colon])}
+		var value = strings.TrimSpace(trimmed[colon+1:])
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = yamlScalar(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			m.Value = strings.Join(items, ", ")
+		} else {
+			m.Value = yamlScalar(value)
+		}
+		metadata = append(metadata, m)
+	}
+	return metadata
+}
+
+// yamlScalar reads a plain or quoted YAML value.
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	// Drop a comment.
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
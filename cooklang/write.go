@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes recipes as Cooklang.
+ */
+
+package cooklang
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Characters that can't be written in markup.
+const notInMarkup = `@#~{}()%\`
+
+// FromRecipe turns a recipe into Cooklang. Each ingredient is marked up where
+// the directions first mention it by name. Any that aren't mentioned are
+// listed in a step of their own at the start.
+func FromRecipe(recipe *defs.Recipe) *Recipe {
+	var r Recipe
+	var meta = func(key, value string) {
+		if value != "" {
+			r.Metadata = append(r.Metadata, Metadata{Key: key, Value: value})
+		}
+	}
+	meta("title", recipe.Title)
+	meta("tags", strings.Join(recipe.Tags, ", "))
+	meta("servings", recipe.Amount)
+	meta("time", recipe.Time)
+	meta("oven", recipe.Oven)
+	meta("source", recipe.Source)
+	meta("description", recipe.Summary)
+
+	for _, ds := range recipe.Directions {
+		var section = Section{Name: ds.Heading}
+		for _, d := range ds.Items {
+			// Steps are written on one line.
+			var text = strings.Join(strings.Fields(d.Text), " ")
+			if text != "" {
+				section.Steps = append(section.Steps,
+					Step{{Kind: KindText, Value: text}})
+			}
+		}
+		r.Sections = append(r.Sections, section)
+	}
+
+	var missing Step
+	for _, line := range recipe.Ingredients.Lines() {
+		var ing = defs.ParseIngredient(line)
+		if markable(ing) && markUp(r.Sections, ing) {
+			continue
+		}
+		if len(missing) == 0 {
+			missing = Step{{Kind: KindText, Value: "Ingredients: "}}
+		} else {
+			missing = appendText(missing, ", ")
+		}
+		if markable(ing) {
+			missing = append(missing, Item{Kind: KindIngredient,
+				Value: ing.Name, Quantity: ing.Quantity, Unit: ing.Unit,
+				Note: ing.Note})
+		} else {
+			missing = appendText(missing, strings.Join(strings.Fields(line), " "))
+		}
+	}
+	if missing != nil {
+		missing = appendText(missing, ".")
+		if len(r.Sections) == 0 {
+			r.Sections = append(r.Sections, Section{})
+		}
+		r.Sections[0].Steps = append([]Step{missing}, r.Sections[0].Steps...)
+	}
+
+	for _, note := range strings.Split(recipe.Notes, "\n\n") {
+		if note = strings.Trim(note, "\n"); note != "" {
+			r.Notes = append(r.Notes, note)
+		}
+	}
+	return &r
+}
+
+// markable reports whether an ingredient can be written as markup.
+func markable(ing defs.Ingredient) bool {
+	return ing.Name != "" &&
+		!strings.ContainsAny(ing.Name+ing.Quantity+ing.Unit, notInMarkup) &&
+		!strings.Contains(ing.Note, ")")
+}
+
+// markUp marks up the first mention of an ingredient's name in the text of
+// the sections. It returns false if there isn't one.
+func markUp(sections []Section, ing defs.Ingredient) bool {
+	var name = strings.ToLower(ing.Name)
+	for _, section := range sections {
+		for s, step := range section.Steps {
+			for i, item := range step {
+				if item.Kind != KindText {
+					continue
+				}
+				var at = findWord(item.Value, name)
+				if at < 0 {
+					continue
+				}
+				var end = at + len(name)
+				var marked = Item{Kind: KindIngredient,
+					Value: item.Value[at:end], Quantity: ing.Quantity,
+					Unit: ing.Unit, Note: ing.Note}
+				var replacement Step
+				if at != 0 {
+					replacement = append(replacement,
+						Item{Kind: KindText, Value: item.Value[:at]})
+				}
+				replacement = append(replacement, marked)
+				if end != len(item.Value) {
+					replacement = append(replacement,
+						Item{Kind: KindText, Value: item.Value[end:]})
+				}
+				var updated = append(Step{}, step[:i]...)
+				updated = append(updated, replacement...)
+				section.Steps[s] = append(updated, step[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findWord returns where word, which is in lower case, first appears in text
+// as a whole word, ignoring case. It returns -1 if it doesn't.
+func findWord(text, word string) int {
+	var lower = strings.ToLower(text)
+	// Changing case can change the length of some characters.
+	if len(lower) != len(text) {
+		return -1
+	}
+	for from := 0; ; {
+		var i = strings.Index(lower[from:], word)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		var end = i + len(word)
+		if (i == 0 || !isWordByte(text[i-1])) &&
+			(end == len(text) || !isWordByte(text[end])) {
+			return i
+		}
+		from = i + 1
+	}
+}
+
+// isWordByte reports whether b could be part of a single-word name.
+func isWordByte(b byte) bool {
+	var r = rune(b)
+	return b >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || b == '_'
+}
+
+// Bytes writes the file.
+func (r *Recipe) Bytes() []byte {
+	var b bytes.Buffer
+	if len(r.Metadata) != 0 {
+		b.WriteString("---\n")
+		for _, m := range r.Metadata {
+			b.WriteString(m.Key + ": " + yamlQuote(m.Value) + "\n")
+		}
+		b.WriteString("---\n")
+	}
+	for _, section := range r.Sections {
+		if section.Name != "" {
+			b.WriteString("\n= " + escape(section.Name, false) + "\n")
+		}
+		for _, step := range section.Steps {
+			b.WriteString("\n" + step.source() + "\n")
+		}
+	}
+	for _, note := range r.Notes {
+		b.WriteString("\n")
+		for _, line := range strings.Split(note, "\n") {
+			b.WriteString("> " + escape(line, false) + "\n")
+		}
+	}
+	return bytes.TrimLeft(b.Bytes(), "\n")
+}
+
+// source writes a step in Cooklang.
+func (s Step) source() string {
+	var b strings.Builder
+	for i, item := range s {
+		if item.Kind == KindText {
+			b.WriteString(escape(item.Value, i == 0))
+			continue
+		}
+		b.WriteString(map[string]string{KindIngredient: "@",
+			KindCookware: "#", KindTimer: "~"}[item.Kind])
+		b.WriteString(item.Value)
+
+		var amount = item.Quantity
+		if item.Unit != "" {
+			amount += "%" + item.Unit
+		}
+		if item.Fixed {
+			amount = "=" + amount
+		}
+		// Braces are needed to end a name that isn't one word, or to hold
+		// the amount or a note.
+		var next = i+1 < len(s) && s[i+1].Kind == KindText &&
+			s[i+1].Value != "" && isWordByte(s[i+1].Value[0])
+		if amount != "" || item.Note != "" || item.Kind == KindTimer ||
+			next || strings.IndexFunc(item.Value, func(r rune) bool {
+			return !isWordByte(byte(r)) && r < 0x80
+		}) >= 0 {
+			b.WriteString("{" + amount + "}")
+		}
+		if item.Note != "" {
+			b.WriteString("(" + item.Note + ")")
+		}
+	}
+	return b.String()
+}
+
+// escape adds backslashes before characters that would otherwise be read as
+// markup or comments. Lines starting with ">" or "=" mean something else, so
+// those are escaped at the start.
+func escape(s string, start bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		var c = s[i]
+		var next byte
+		if i+1 < len(s) {
+			next = s[i+1]
+		}
+		switch {
+		case c == '\\' || c == '@' || c == '#' || c == '~',
+			c == '-' && (next == '-' || next == ']'),
+			c == '[' && next == '-',
+			start && i == 0 && (c == '>' || c == '='):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// yamlQuote returns a front matter value, quoted if YAML would read it
+// differently otherwise.
+func yamlQuote(value string) string {
+	if value != strings.TrimSpace(value) || value == "" ||
+		strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(value, ": ") || strings.Contains(value, " #") ||
+		strings.HasSuffix(value, ":") || strings.ContainsAny(value, "\n\r\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file exposes the database interface for recipe attachments. The files
+ * are kept in blob storage; this only tracks them.
+ */
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// ErrBadAttachment is returned when a direction refers to an attachment that
+// doesn't belong to its recipe.
+var ErrBadAttachment = errors.New("direction refers to an unknown attachment")
+
+// CreateAttachment records a new attachment for a recipe, returning it with
+// its ID.
+func CreateAttachment(recipeID int, filename string, contentType string,
+	size int64) (*defs.Attachment, error) {
+	var a = defs.Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+	}
+	var err = DB.QueryRow(`INSERT INTO attachments
+                (recipe_id, filename, content_type, size)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id`,
+		recipeID, filename, contentType, size).Scan(&a.ID)
+	if err != nil {
+		return nil, err
+	}
+	a.FillURL()
+	return &a, nil
+}
+
+// FetchAttachment returns one attachment by ID. One on a recipe the viewer
+// may not see is sql.ErrNoRows.
+func FetchAttachment(id int, viewer defs.Viewer) (*defs.Attachment, error) {
+	var a defs.Attachment
+	var and, params = andVisibleSQL(viewer, []interface{}{id})
+	var err = DB.QueryRow(`SELECT attachments.id, attachments.filename,
+                attachments.content_type, attachments.size
+            FROM attachments JOIN recipes ON recipes.id = attachments.recipe_id
+            WHERE attachments.id = $1`+and, params...).Scan(&a.ID, &a.Filename,
+		&a.ContentType, &a.Size)
+	if err != nil {
+		return nil, err
+	}
+	a.FillURL()
+	return &a, nil
+}
+
+// FetchAttachmentIDs returns the IDs of all attachments of a recipe.
+func FetchAttachmentIDs(recipeID int) ([]int, error) {
+	var rows, err = DB.Query(`SELECT id FROM attachments WHERE recipe_id = $1`,
+		recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteAttachment removes an attachment from a recipe, along with any
+// references to it from the directions. It returns sql.ErrNoRows if the
+// recipe has no such attachment.
+func DeleteAttachment(recipeID int, attachmentID int) error {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM attachments
+            WHERE id = $1 AND recipe_id = $2`, attachmentID, recipeID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	var raw []byte
+	err = tx.QueryRow(`SELECT directions FROM recipes WHERE id = $1
+            FOR UPDATE`, recipeID).Scan(&raw)
+	if err != nil {
+		return err
+	}
+	var directions defs.DirectionSections
+	err = json.Unmarshal(raw, &directions)
+	if err != nil {
+		return err
+	}
+	for _, section := range directions {
+		for i, d := range section.Items {
+			var kept = d.Attachments[:0]
+			for _, id := range d.Attachments {
+				if id != attachmentID {
+					kept = append(kept, id)
+				}
+			}
+			section.Items[i].Attachments = kept
+		}
+	}
+	raw, err = json.Marshal(directions)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE recipes SET directions = $1 WHERE id = $2`,
+		raw, recipeID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkAttachments makes sure the directions of a recipe being saved only
+// refer to its own attachments, returning ErrBadAttachment if not.
+func checkAttachments(tx *sql.Tx, recipe *defs.Recipe) error {
+	var seen = make(map[int]bool)
+	var ids []int64
+	for _, d := range recipe.Directions.Steps() {
+		for _, id := range d.Attachments {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, int64(id))
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var found int
+	var err = tx.QueryRow(`SELECT COUNT(*) FROM attachments
+            WHERE recipe_id = $1 AND id = ANY($2)`,
+		recipe.ID, pq.Array(ids)).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != len(ids) {
+		return ErrBadAttachment
+	}
+	return nil
+}
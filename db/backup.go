@@ -0,0 +1,520 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file reads the whole database for backups, and restores them.
+ */
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// queryEach runs a query, calling scan for each row.
+func queryEach(tx *sql.Tx, query string, scan func(*sql.Rows) error) error {
+	var rows, err = tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// revisionsQuery selects where recipes are in their history, for
+// scanRevision.
+var revisionsQuery = `SELECT id, revision, parent_id, parent_revision,
+                COALESCE((SELECT json_agg(slug ORDER BY slug)
+                        FROM recipe_slugs
+                        WHERE recipe_slugs.recipe_id = recipes.id),
+                    '[]'::json)
+            FROM recipes`
+
+// scanRevision reads a row selected by revisionsQuery.
+func scanRevision(rows *sql.Rows) (defs.BackupRevision, error) {
+	var r defs.BackupRevision
+	var slugs []byte
+	var err = rows.Scan(&r.RecipeID, &r.Revision, &r.ParentID,
+		&r.ParentRevision, &slugs)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(slugs, &r.OldSlugs)
+	return r, err
+}
+
+// FetchRevisions returns where each recipe by an author is in its history.
+func FetchRevisions(authorID int) ([]defs.BackupRevision, error) {
+	var rows, err = DB.Query(revisionsQuery+`
+            WHERE author_id = $1 ORDER BY id`, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revisions = []defs.BackupRevision{}
+	for rows.Next() {
+		var r, err = scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// FetchBackup reads everything in the database, as it was at one moment, for
+// a backup.
+func FetchBackup() (*defs.Backup, error) {
+	var tx, err = DB.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var b defs.Backup
+
+	err = queryEach(tx, `SELECT id, email, name, role, creation_date, lastlog
+            FROM users ORDER BY id`, func(rows *sql.Rows) error {
+		var u defs.BackupUser
+		var err = rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role,
+			&u.CreationDate, &u.Lastlog)
+		b.Users = append(b.Users, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT id, author_id, title, slug, amount,
+                directions, ingredients, notes, oven, source, summary, time,
+                minutes, visibility, creation_date, modified_date
+            FROM recipes ORDER BY id`, func(rows *sql.Rows) error {
+		var r defs.BackupRecipe
+		var directions, ingredients []byte
+		var err = rows.Scan(&r.ID, &r.AuthorID, &r.Title, &r.Slug, &r.Amount,
+			&directions, &ingredients, &r.Notes, &r.Oven, &r.Source,
+			&r.Summary, &r.Time, &r.Minutes, &r.Visibility, &r.CreationDate,
+			&r.ModifiedDate)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(directions, &r.Directions)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(ingredients, &r.Ingredients)
+		b.Recipes = append(b.Recipes, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, revisionsQuery+` ORDER BY id`,
+		func(rows *sql.Rows) error {
+			var r, err = scanRevision(rows)
+			b.Revisions = append(b.Revisions, r)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT recipe_id, tag FROM tags
+            ORDER BY recipe_id, tag`, func(rows *sql.Rows) error {
+		var t defs.BackupRecipeTag
+		var err = rows.Scan(&t.RecipeID, &t.Tag)
+		b.Tags.Recipes = append(b.Tags.Recipes, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = queryEach(tx, `SELECT tag, description, color FROM tag_info
+            ORDER BY tag`, func(rows *sql.Rows) error {
+		var t defs.BackupTagInfo
+		var err = rows.Scan(&t.Tag, &t.Description, &t.Color)
+		b.Tags.Info = append(b.Tags.Info, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = queryEach(tx, `SELECT synonym, tag FROM tag_synonyms
+            ORDER BY synonym`, func(rows *sql.Rows) error {
+		var t defs.BackupTagSynonym
+		var err = rows.Scan(&t.Synonym, &t.Tag)
+		b.Tags.Synonyms = append(b.Tags.Synonyms, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = queryEach(tx, `SELECT tag, parent FROM tag_parents
+            ORDER BY tag, parent`, func(rows *sql.Rows) error {
+		var t defs.BackupTagParent
+		var err = rows.Scan(&t.Tag, &t.Parent)
+		b.Tags.Parents = append(b.Tags.Parents, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT src, dest, role, quantity FROM linked_recipes
+            ORDER BY src, dest`, func(rows *sql.Rows) error {
+		var l defs.BackupLink
+		var err = rows.Scan(&l.Src, &l.Dest, &l.Role, &l.Quantity)
+		b.Links = append(b.Links, l)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT id, recipe_id, position, cover, width, height,
+                creation_date
+            FROM photos ORDER BY id`, func(rows *sql.Rows) error {
+		var p defs.BackupPhoto
+		var err = rows.Scan(&p.ID, &p.RecipeID, &p.Position, &p.Cover,
+			&p.Width, &p.Height, &p.CreationDate)
+		b.Photos = append(b.Photos, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT id, recipe_id, filename, content_type, size,
+                creation_date
+            FROM attachments ORDER BY id`, func(rows *sql.Rows) error {
+		var a defs.BackupAttachment
+		var err = rows.Scan(&a.ID, &a.RecipeID, &a.Filename, &a.ContentType,
+			&a.Size, &a.CreationDate)
+		b.Attachments = append(b.Attachments, a)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(tx, `SELECT recipe_id, user_id, rating
+            FROM ratings ORDER BY recipe_id, user_id`,
+		func(rows *sql.Rows) error {
+			var r defs.BackupRating
+			var err = rows.Scan(&r.RecipeID, &r.UserID, &r.Rating)
+			b.Ratings = append(b.Ratings, r)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// RestoreBackup adds everything in a backup to the database, all at once or
+// not at all. Records get new IDs, and references between them follow. Users
+// already here, found by email, are kept as they are, and recipes whose slugs
+// are taken get new ones. With replace set, everything already in the
+// database is deleted first.
+//
+// The backup must already be checked to be consistent. Before anything is
+// committed, finish is called with the new IDs, to store the files that go
+// with them. If it fails, nothing is restored.
+func RestoreBackup(b *defs.Backup, replace bool,
+	finish func(*defs.RestoreReport) error) (*defs.RestoreReport, error) {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var report = defs.RestoreReport{
+		UserIDs:       make(map[int]int),
+		RecipeIDs:     make(map[int]int),
+		PhotoIDs:      make(map[int]int),
+		AttachmentIDs: make(map[int]int),
+	}
+
+	if replace {
+		err = clearDatabase(tx, &report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range b.Users {
+		var id int
+		err = tx.QueryRow(`SELECT id FROM users WHERE lower(email) = lower($1)
+                ORDER BY id LIMIT 1`, u.Email).Scan(&id)
+		if err == sql.ErrNoRows {
+			err = tx.QueryRow(`INSERT INTO users
+                        (email, name, role, creation_date, lastlog)
+                    VALUES ($1, $2, $3, $4, $5)
+                    RETURNING id`,
+				u.Email, u.Name, u.Role, u.CreationDate, u.Lastlog).Scan(&id)
+			report.Users++
+		} else {
+			report.MatchedUsers++
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.UserIDs[u.ID] = id
+	}
+
+	var revisions = make(map[int]defs.BackupRevision, len(b.Revisions))
+	for _, r := range b.Revisions {
+		revisions[r.RecipeID] = r
+	}
+	for _, r := range b.Recipes {
+		var slug = r.Slug
+		var taken, err = slugTaken(tx, slug, 0)
+		if err != nil {
+			return nil, err
+		}
+		if taken || slug == "" {
+			slug, err = uniqueSlug(tx, r.Title, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		directions, err := json.Marshal(r.Directions)
+		if err != nil {
+			return nil, err
+		}
+		ingredients, err := json.Marshal(r.Ingredients)
+		if err != nil {
+			return nil, err
+		}
+		var id int
+		err = tx.QueryRow(`INSERT INTO recipes (revision, amount, author_id,
+                    directions, ingredients, notes, oven, source, summary,
+                    time, title, slug, minutes, creation_date, modified_date,
+                    visibility)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+                    $14, $15, COALESCE(NULLIF($16, ''), 'public'))
+                RETURNING id`,
+			revisions[r.ID].Revision, r.Amount, report.UserIDs[r.AuthorID],
+			directions, ingredients, r.Notes, r.Oven, r.Source, r.Summary,
+			r.Time, r.Title, slug, r.Minutes, r.CreationDate,
+			r.ModifiedDate, r.Visibility).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		report.RecipeIDs[r.ID] = id
+		report.Recipes++
+	}
+
+	// Forks and old slugs can only be restored once every recipe has its new
+	// ID.
+	for _, r := range b.Revisions {
+		var id = report.RecipeIDs[r.RecipeID]
+		if parent, ok := report.RecipeIDs[int(r.ParentID.Int64)]; ok &&
+			r.ParentID.Valid {
+			_, err = tx.Exec(`UPDATE recipes
+                    SET (parent_id, parent_revision) = ($1, $2)
+                    WHERE id = $3`, parent, r.ParentRevision, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, slug := range r.OldSlugs {
+			var taken, err = slugTaken(tx, slug, id)
+			if err != nil {
+				return nil, err
+			}
+			if taken {
+				continue
+			}
+			_, err = tx.Exec(`INSERT INTO recipe_slugs (slug, recipe_id)
+                    SELECT $1::text, $2
+                    WHERE NOT EXISTS (SELECT 1 FROM recipes WHERE slug = $1)`,
+				slug, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = restoreTags(tx, &b.Tags, &report)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range b.Links {
+		_, err = tx.Exec(`INSERT INTO linked_recipes (src, dest, role, quantity)
+                VALUES ($1, $2, $3, $4)`,
+			report.RecipeIDs[l.Src], report.RecipeIDs[l.Dest], l.Role,
+			l.Quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range b.Photos {
+		var id int
+		err = tx.QueryRow(`INSERT INTO photos
+                    (recipe_id, position, cover, width, height, creation_date)
+                VALUES ($1, $2, $3, $4, $5, $6)
+                RETURNING id`,
+			report.RecipeIDs[p.RecipeID], p.Position, p.Cover, p.Width,
+			p.Height, p.CreationDate).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		report.PhotoIDs[p.ID] = id
+		report.Photos++
+	}
+	for _, a := range b.Attachments {
+		var id int
+		err = tx.QueryRow(`INSERT INTO attachments
+                    (recipe_id, filename, content_type, size, creation_date)
+                VALUES ($1, $2, $3, $4, $5)
+                RETURNING id`,
+			report.RecipeIDs[a.RecipeID], a.Filename, a.ContentType, a.Size,
+			a.CreationDate).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		report.AttachmentIDs[a.ID] = id
+		report.Attachments++
+	}
+	// Recipes always get new IDs, so restored ratings can't collide with
+	// ones already here, even from matched users.
+	for _, r := range b.Ratings {
+		_, err = tx.Exec(`INSERT INTO ratings (recipe_id, user_id, rating)
+                VALUES ($1, $2, $3)`,
+			report.RecipeIDs[r.RecipeID], report.UserIDs[r.UserID], r.Rating)
+		if err != nil {
+			return nil, err
+		}
+		report.Ratings++
+	}
+	// Directions refer to attachments, which have new IDs now.
+	for _, r := range b.Recipes {
+		if !remapAttachments(r.Directions, report.AttachmentIDs) {
+			continue
+		}
+		directions, err := json.Marshal(r.Directions)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`UPDATE recipes SET directions = $1 WHERE id = $2`,
+			directions, report.RecipeIDs[r.ID])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = finish(&report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, tx.Commit()
+}
+
+// clearDatabase deletes everything, noting which photos and attachments were
+// deleted so their files can be too.
+func clearDatabase(tx *sql.Tx, report *defs.RestoreReport) error {
+	var ids = map[string]*[]int{
+		"photos":      &report.DeletedPhotoIDs,
+		"attachments": &report.DeletedAttachmentIDs,
+	}
+	for table, list := range ids {
+		var list = list
+		var err = queryEach(tx, `SELECT id FROM `+table+` ORDER BY id`,
+			func(rows *sql.Rows) error {
+				var id int
+				var err = rows.Scan(&id)
+				*list = append(*list, id)
+				return err
+			})
+		if err != nil {
+			return err
+		}
+	}
+	// Deleting recipes deletes everything that belongs to them.
+	for _, table := range []string{"recipes", "tag_info", "tag_synonyms",
+		"tag_parents", "users"} {
+		var _, err = tx.Exec(`DELETE FROM ` + table)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreTags restores tags on recipes, and what's known about tags that
+// isn't already.
+func restoreTags(tx *sql.Tx, tags *defs.BackupTags,
+	report *defs.RestoreReport) error {
+	for _, t := range tags.Recipes {
+		var _, err = tx.Exec(`INSERT INTO tags (recipe_id, tag)
+                VALUES ($1, $2)`, report.RecipeIDs[t.RecipeID], t.Tag)
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range tags.Info {
+		var _, err = tx.Exec(`INSERT INTO tag_info (tag, description, color)
+                SELECT $1::text, $2, $3
+                WHERE NOT EXISTS (SELECT 1 FROM tag_info WHERE tag = $1)`,
+			t.Tag, t.Description, t.Color)
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range tags.Synonyms {
+		var _, err = tx.Exec(`INSERT INTO tag_synonyms (synonym, tag)
+                SELECT $1::text, $2
+                WHERE NOT EXISTS (SELECT 1 FROM tag_synonyms
+                    WHERE synonym = $1)`,
+			t.Synonym, t.Tag)
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range tags.Parents {
+		var _, err = tx.Exec(`INSERT INTO tag_parents (tag, parent)
+                SELECT $1::text, $2::text
+                WHERE NOT EXISTS (SELECT 1 FROM tag_parents
+                    WHERE tag = $1 AND parent = $2)`,
+			t.Tag, t.Parent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapAttachments changes the attachment IDs in directions to new ones,
+// dropping any without one. It reports whether there were any.
+func remapAttachments(directions defs.DirectionSections,
+	ids map[int]int) bool {
+	var found = false
+	for _, section := range directions {
+		for i, d := range section.Items {
+			if len(d.Attachments) == 0 {
+				continue
+			}
+			found = true
+			var kept []int
+			for _, id := range d.Attachments {
+				if newID, ok := ids[id]; ok {
+					kept = append(kept, newID)
+				}
+			}
+			section.Items[i].Attachments = kept
+		}
+	}
+	return found
+}
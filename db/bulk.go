@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file changes many recipes at once.
+ */
+
+package db
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// recipeFiles returns the IDs of the photos and attachments of the recipes
+// where recipes.<column> is value, so their files can be deleted along with
+// them.
+func recipeFiles(tx *sql.Tx, column string, value int) ([]int, []int, error) {
+	var photos, attachments = []int{}, []int{}
+	var ids = map[string]*[]int{
+		"photos":      &photos,
+		"attachments": &attachments,
+	}
+	for table, list := range ids {
+		var rows, err = tx.Query(`SELECT `+table+`.id FROM `+table+`
+                JOIN recipes ON recipes.id = `+table+`.recipe_id
+                WHERE recipes.`+column+` = $1
+                ORDER BY `+table+`.id`, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			var id int
+			err = rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			*list = append(*list, id)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return photos, attachments, nil
+}
+
+// BulkChangeRecipes makes a change to the recipes with the given IDs, all at
+// once or not at all. As with SaveRecipe, a user may only change their own
+// recipes unless force is set; others are skipped, as are recipes that don't
+// exist. With dryRun set, the change is made and then rolled back, so the
+// report says what would happen. A new author that doesn't exist is
+// ErrNoSuchUser.
+func BulkChangeRecipes(change *defs.BulkChange, ids []int, userID int,
+	force bool, dryRun bool) (*defs.BulkReport, error) {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var report = defs.BulkReport{
+		DryRun:               dryRun,
+		Results:              make([]defs.BulkResult, 0, len(ids)),
+		DeletedPhotoIDs:      []int{},
+		DeletedAttachmentIDs: []int{},
+	}
+
+	if change.AuthorID != 0 {
+		var exists bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`,
+			change.AuthorID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrNoSuchUser
+		}
+	}
+	var add = normalizeTags(change.AddTags)
+	var remove = normalizeTags(change.RemoveTags)
+
+	var seen = make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		var result, err = bulkChangeRecipe(tx, change, id, add, remove,
+			userID, force, &report)
+		if err != nil {
+			return nil, err
+		}
+		switch result.Action {
+		case defs.BulkChanged:
+			report.Changed++
+		case defs.BulkUnchanged:
+			report.Unchanged++
+		case defs.BulkDeleted:
+			report.Deleted++
+		case defs.BulkSkipped:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, *result)
+	}
+
+	if dryRun {
+		report.DeletedPhotoIDs = nil
+		report.DeletedAttachmentIDs = nil
+		return &report, nil
+	}
+	return &report, tx.Commit()
+}
+
+// bulkChangeRecipe makes a bulk change to one recipe, noting the files of a
+// deleted recipe in report.
+func bulkChangeRecipe(tx *sql.Tx, change *defs.BulkChange, id int,
+	add []string, remove []string, userID int, force bool,
+	report *defs.BulkReport) (*defs.BulkResult, error) {
+	var result = defs.BulkResult{RecipeID: id}
+	var authorID int
+	var visibility string
+	var err = tx.QueryRow(`SELECT title, author_id, visibility FROM recipes
+            WHERE id = $1 FOR UPDATE`, id).Scan(&result.Title, &authorID,
+		&visibility)
+	if err == sql.ErrNoRows {
+		result.Action = defs.BulkSkipped
+		result.Reason = "recipe not found"
+		return &result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// Only the author may change the recipe, unless the user has sufficient
+	// privileges.
+	if !force && authorID != userID {
+		result.Action = defs.BulkSkipped
+		result.Reason = "not your recipe"
+		return &result, nil
+	}
+
+	if change.Delete {
+		photos, attachments, err := recipeFiles(tx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`DELETE FROM recipes WHERE id = $1`, id)
+		if err != nil {
+			return nil, err
+		}
+		report.DeletedPhotoIDs = append(report.DeletedPhotoIDs, photos...)
+		report.DeletedAttachmentIDs = append(report.DeletedAttachmentIDs,
+			attachments...)
+		result.Action = defs.BulkDeleted
+		return &result, nil
+	}
+
+	var changed = false
+	for _, tag := range remove {
+		var r, err = tx.Exec(`DELETE FROM tags
+                WHERE recipe_id = $1 AND tag = $2`, id, tag)
+		if err != nil {
+			return nil, err
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || n > 0
+	}
+	for _, tag := range add {
+		var r, err = tx.Exec(`INSERT INTO tags (recipe_id, tag)
+                SELECT $1, $2::text
+                WHERE NOT EXISTS (SELECT 1 FROM tags
+                    WHERE recipe_id = $1 AND tag = $2)`, id, tag)
+		if err != nil {
+			return nil, err
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || n > 0
+	}
+	if change.AuthorID != 0 && change.AuthorID != authorID {
+		_, err = tx.Exec(`UPDATE recipes SET author_id = $1 WHERE id = $2`,
+			change.AuthorID, id)
+		if err != nil {
+			return nil, err
+		}
+		authorID = change.AuthorID
+		changed = true
+	}
+	if change.Visibility != "" && change.Visibility != visibility {
+		_, err = tx.Exec(`UPDATE recipes SET visibility = $1 WHERE id = $2`,
+			change.Visibility, id)
+		if err != nil {
+			return nil, err
+		}
+		visibility = change.Visibility
+		changed = true
+	}
+
+	result.Action = defs.BulkUnchanged
+	if changed {
+		result.Action = defs.BulkChanged
+		_, err = tx.Exec(`UPDATE recipes SET (revision, modified_date) =
+                    (revision + 1, CURRENT_TIMESTAMP)
+                WHERE id = $1`, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.AuthorID = authorID
+	result.Visibility = visibility
+	var rows, e = tx.Query(`SELECT tag FROM tags WHERE recipe_id = $1`, id)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	result.Tags = []string{}
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		result.Tags = append(result.Tags, tag)
+	}
+	sort.Strings(result.Tags)
+	return &result, rows.Err()
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file implements the opaque cursors used for keyset pagination.
+ */
+
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// ErrBadCursor is returned when a client sends a cursor we didn't make, or one
+// made for a different sort order.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// cursor marks a position in a sorted result set by the sort value and ID of
+// the row next to it. Pages are fetched after the row, or before it if
+// Backward is set.
+type cursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       int    `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// encode turns the cursor into a URL-safe string for the client.
+func (c cursor) encode() string {
+	// This can't fail; the struct only holds strings, ints, and bools.
+	var j, _ = json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+// decodeCursor reads a cursor made by encode, checking it was made for the
+// given sort key.
+func decodeCursor(s string, sort string) (cursor, error) {
+	var c cursor
+	var j, err = base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrBadCursor
+	}
+	err = json.Unmarshal(j, &c)
+	if err != nil || c.Sort != sort {
+		return c, ErrBadCursor
+	}
+	return c, nil
+}
+
+// keysetSQL builds the condition selecting rows on the far side of the cursor,
+// given the sort expression, its SQL type, the ID column, and whether rows are
+// being read in descending order.
+func keysetSQL(c cursor, expr, sqlType, idColumn string, descending bool,
+	params []interface{}) (string, []interface{}) {
+	var op = " > "
+	if descending {
+		op = " < "
+	}
+	params = append(params, c.Value, c.ID)
+	return "(" + expr + ", " + idColumn + ")" + op +
+		"($" + strconv.Itoa(len(params)-1) + "::" + sqlType +
+		", $" + strconv.Itoa(len(params)) + ")", params
+}
+
+// pageCursors works out the cursors around a page of rows. It is given the
+// cursor used to fetch the page, if any, the number of rows asked for, the
+// number actually returned (one more than asked if there are more), and
+// whether offset paging skipped earlier rows. It returns whether each
+// neighboring page exists and which end of the rows holds the extra row.
+func pageCursors(from *cursor, count, got int,
+	skipped bool) (hasNext, hasPrev, trimFront bool) {
+	var more = got > count
+	switch {
+	case from == nil:
+		return more, skipped, false
+	case from.Backward:
+		// We came back from a later page, and rows were read in reverse, so
+		// the extra is before the first.
+		return true, more, more
+	default:
+		return more, true, false
+	}
+}
@@ -0,0 +1,46 @@
+package db
+
+import "testing"
+
+func TestCursorRoundTrip(t *testing.T) {
+	var c = cursor{Sort: "title", Value: "Banana bread", ID: 12, Backward: true}
+	var got, err = decodeCursor(c.encode(), "title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c {
+		t.Errorf("got %+v, want %+v", got, c)
+	}
+	// A cursor for another sort order must be rejected.
+	if _, err = decodeCursor(c.encode(), "created"); err != ErrBadCursor {
+		t.Errorf("expected ErrBadCursor, got %v", err)
+	}
+	if _, err = decodeCursor("not a cursor!", "title"); err != ErrBadCursor {
+		t.Errorf("expected ErrBadCursor, got %v", err)
+	}
+}
+
+func TestPageCursors(t *testing.T) {
+	var forward = &cursor{ID: 1}
+	var backward = &cursor{ID: 1, Backward: true}
+	var cases = []struct {
+		from                        *cursor
+		got                         int
+		skipped                     bool
+		hasNext, hasPrev, trimFront bool
+	}{
+		{nil, 10, false, false, false, false},
+		{nil, 11, false, true, false, false},
+		{nil, 11, true, true, true, false},
+		{forward, 5, false, false, true, false},
+		{forward, 11, false, true, true, false},
+		{backward, 10, false, true, false, false},
+		{backward, 11, false, true, true, true},
+	}
+	for i, c := range cases {
+		var n, p, f = pageCursors(c.from, 10, c.got, c.skipped)
+		if n != c.hasNext || p != c.hasPrev || f != c.trimFront {
+			t.Errorf("case %d: got %v %v %v", i, n, p, f)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file computes facets over recipe search results.
+ */
+
+package db
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// The max_time values we count recipes under, in minutes.
+var timeFacetBuckets = []int{15, 30, 60, 120}
+
+// FetchRecipeFacets counts the recipes matching the filter by tag, author, and
+// time. Pagination in the filter is ignored, so the counts cover every match.
+func FetchRecipeFacets(filter defs.ItemFilter) (*defs.Facets, error) {
+	var where, params = recipeFilterSQL(filter, nil)
+	// The IDs of all recipes in the result set.
+	var matching = "SELECT recipes.id FROM recipes " + where
+
+	var facets = defs.Facets{
+		Tags:    make([]defs.TagFacet, 0, 20),
+		Authors: make([]defs.AuthorFacet, 0, 5),
+		Times:   make([]defs.TimeFacet, 0, len(timeFacetBuckets)),
+	}
+
+	// Count tags.
+	var rows, err = DB.Query(`SELECT tag, COUNT(*) FROM tags
+            WHERE recipe_id IN (`+matching+`)
+            GROUP BY tag
+            ORDER BY COUNT(*) DESC, tag`, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f defs.TagFacet
+		err = rows.Scan(&f.Tag, &f.Count)
+		if err != nil {
+			return nil, err
+		}
+		facets.Tags = append(facets.Tags, f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Count authors.
+	rows, err = DB.Query(`SELECT users.id, users.name, COUNT(*) FROM users
+            JOIN recipes ON users.id = recipes.author_id
+            WHERE recipes.id IN (`+matching+`)
+            GROUP BY users.id
+            ORDER BY COUNT(*) DESC, users.name`, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f defs.AuthorFacet
+		err = rows.Scan(&f.ID, &f.Name, &f.Count)
+		if err != nil {
+			return nil, err
+		}
+		facets.Authors = append(facets.Authors, f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Count times, one column per bucket, all in one row.
+	var columns = make([]string, len(timeFacetBuckets))
+	var dest = make([]interface{}, len(timeFacetBuckets))
+	for i, max := range timeFacetBuckets {
+		columns[i] = "COUNT(*) FILTER (WHERE minutes BETWEEN 1 AND " +
+			strconv.Itoa(max) + ")"
+		facets.Times = append(facets.Times, defs.TimeFacet{MaxMinutes: max})
+		dest[i] = &facets.Times[i].Count
+	}
+	err = DB.QueryRow(`SELECT `+strings.Join(columns, ", ")+`
+            FROM recipes WHERE id IN (`+matching+`)`, params...).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return &facets, nil
+}
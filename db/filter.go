@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file turns an ItemFilter into SQL for searching recipes.
+ */
+
+package db
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// ingredientLinesSQL lists each ingredient line of a recipe as i.line. Older
+// recipes store a flat array of lines; newer ones store sections.
+const ingredientLinesSQL = `(SELECT s #>> '{}' AS line
+            FROM jsonb_array_elements(recipes.ingredients) s
+            WHERE jsonb_typeof(s) = 'string'
+        UNION ALL
+        SELECT jsonb_array_elements_text(s->'items')
+            FROM jsonb_array_elements(recipes.ingredients) s
+            WHERE jsonb_typeof(s) = 'object') i`
+
+// sortColumn describes something recipes can be sorted by.
+type sortColumn struct {
+	// The SQL expression to sort by.
+	expr string
+	// The SQL type of expr, for reading values back from cursors.
+	sqlType string
+	// Read the value of expr from a recipe, for making cursors.
+	value func(r *defs.Recipe) string
+}
+
+// Map the sort keys accepted in an ItemFilter to what they sort by.
+var recipeSortColumns = map[string]sortColumn{
+	defs.SortByTitle: {"recipes.title", "text",
+		func(r *defs.Recipe) string { return r.Title }},
+	defs.SortByCreated: {"recipes.creation_date", "timestamptz",
+		func(r *defs.Recipe) string {
+			return r.CreationDate.Format(time.RFC3339Nano)
+		}},
+	defs.SortByModified: {"recipes.modified_date", "timestamptz",
+		func(r *defs.Recipe) string {
+			return r.ModifiedDate.Format(time.RFC3339Nano)
+		}},
+	defs.SortByTime: {"recipes.minutes", "integer",
+		func(r *defs.Recipe) string { return strconv.Itoa(r.Minutes) }},
+}
+
+// recipeSortKey returns the filter's sort key, or the default if it has none.
+func recipeSortKey(filter defs.ItemFilter) string {
+	if _, ok := recipeSortColumns[filter.Sort]; ok {
+		return filter.Sort
+	}
+	return defs.SortByTitle
+}
+
+// recipeFilterSQL builds a WHERE clause that restricts the recipes table to
+// those matching the filter. New parameters are appended to params, which is
+// returned along with the SQL. The SQL is empty if the filter matches
+// everything.
+func recipeFilterSQL(filter defs.ItemFilter,
+	params []interface{}) (string, []interface{}) {
+	// Each of these must be true for a recipe to match.
+	var conditions []string
+	// Add a parameter and return its placeholder.
+	var param = func(v interface{}) string {
+		params = append(params, v)
+		return "$" + strconv.Itoa(len(params))
+	}
+
+	// Tokenize search string on spaces. Each term must be matched in the title
+	// or tags for a recipe to be returned.
+	for _, term := range strings.Split(filter.Query, " ") {
+		// Ignore blank terms (comes from leading/trailing spaces).
+		if term == "" {
+			continue
+		}
+		var p = param("%" + term + "%")
+		conditions = append(conditions, "(recipes.title ILIKE "+p+
+			"\n\t\t OR EXISTS (SELECT 1 FROM tags t"+
+			" WHERE t.recipe_id = recipes.id AND (t.tag ILIKE "+p+
+			" OR t.tag IN (SELECT tag FROM tag_synonyms"+
+			" WHERE synonym ILIKE "+p+"))))")
+	}
+	// Searching by a tag also finds its synonyms and the tags under it.
+	for _, tag := range normalizeTags(filter.AllTags) {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM tags t"+
+			" WHERE t.recipe_id = recipes.id AND t.tag IN "+
+			tagTreeSQL(param(pq.Array([]string{tag})))+")")
+	}
+	if len(filter.AnyTags) != 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM tags t"+
+			" WHERE t.recipe_id = recipes.id AND t.tag IN "+
+			tagTreeSQL(param(pq.Array(normalizeTags(filter.AnyTags))))+")")
+	}
+	if len(filter.NoTags) != 0 {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM tags t"+
+			" WHERE t.recipe_id = recipes.id AND t.tag IN "+
+			tagTreeSQL(param(pq.Array(normalizeTags(filter.NoTags))))+")")
+	}
+	if filter.AuthorID != 0 {
+		conditions = append(conditions,
+			"recipes.author_id = "+param(filter.AuthorID))
+	}
+	// Ingredients are free text, so look for a substring in any of them.
+	for _, ingredient := range filter.Ingredients {
+		conditions = append(conditions, "EXISTS (SELECT 1"+
+			" FROM "+ingredientLinesSQL+
+			" WHERE i.line ILIKE "+param("%"+ingredient+"%")+")")
+	}
+	for _, ingredient := range filter.NoIngredients {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1"+
+			" FROM "+ingredientLinesSQL+
+			" WHERE i.line ILIKE "+param("%"+ingredient+"%")+")")
+	}
+	// A time of zero means we couldn't parse it, so those never match.
+	if filter.MaxMinutes != 0 {
+		conditions = append(conditions,
+			"recipes.minutes BETWEEN 1 AND "+param(filter.MaxMinutes))
+	}
+	if filter.HasPhoto.Valid {
+		var exists = "EXISTS (SELECT 1 FROM photos" +
+			" WHERE photos.recipe_id = recipes.id)"
+		if !filter.HasPhoto.Bool {
+			exists = "NOT " + exists
+		}
+		conditions = append(conditions, exists)
+	}
+	// Unrated recipes have no average, so they never match.
+	if filter.MinRating != 0 {
+		conditions = append(conditions, "(SELECT AVG(ratings.rating)"+
+			" FROM ratings WHERE ratings.recipe_id = recipes.id) >= "+
+			param(filter.MinRating))
+	}
+	// Private recipes are only seen by their authors, and admins and
+	// moderators.
+	if !filter.Viewer.All {
+		conditions = append(conditions, visibleSQL(filter.Viewer, param))
+	}
+	var timeBound = func(column, op string, t time.Time) {
+		if !t.IsZero() {
+			conditions = append(conditions, column+" "+op+" "+param(t))
+		}
+	}
+	timeBound("recipes.creation_date", ">=", filter.CreatedAfter)
+	timeBound("recipes.creation_date", "<", filter.CreatedBefore)
+	timeBound("recipes.modified_date", ">=", filter.ModifiedAfter)
+	timeBound("recipes.modified_date", "<", filter.ModifiedBefore)
+
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return "\n\t WHERE " + strings.Join(conditions, "\n\t AND "), params
+}
+
+// visibleSQL builds a condition on the recipes table that matches the recipes
+// viewer may see, unless they may see them all. Param adds a parameter and
+// returns its placeholder.
+func visibleSQL(viewer defs.Viewer, param func(interface{}) string) string {
+	return "(recipes.visibility = 'public' OR recipes.author_id = " +
+		param(viewer.UserID) + ")"
+}
+
+// andVisibleSQL is visibleSQL as an extra condition for a query that joins
+// the recipes table, starting with AND, or nothing if the viewer may see every
+// recipe. New parameters are appended to params.
+func andVisibleSQL(viewer defs.Viewer,
+	params []interface{}) (string, []interface{}) {
+	if viewer.All {
+		return "", params
+	}
+	var condition = visibleSQL(viewer, func(v interface{}) string {
+		params = append(params, v)
+		return "$" + strconv.Itoa(len(params))
+	})
+	return " AND " + condition, params
+}
+
+// recipeOrderSQL builds an ORDER BY clause for a sort key. Ties are broken by
+// ID so the order is stable.
+func recipeOrderSQL(sort string, descending bool) string {
+	var direction = " ASC"
+	if descending {
+		direction = " DESC"
+	}
+	return "\n\t ORDER BY " + recipeSortColumns[sort].expr + direction +
+		", recipes.id" + direction
+}
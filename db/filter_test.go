@@ -0,0 +1,29 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestRecipeFilterVisibility(t *testing.T) {
+	var where, params = recipeFilterSQL(defs.ItemFilter{}, nil)
+	if !strings.Contains(where, "recipes.visibility = 'public'") ||
+		!reflect.DeepEqual(params, []interface{}{0}) {
+		t.Errorf("anyone sees private recipes: %s %v", where, params)
+	}
+	where, params = recipeFilterSQL(defs.ItemFilter{
+		Viewer: defs.Viewer{UserID: 3}}, []interface{}{12})
+	if !strings.Contains(where, "recipes.author_id = $2") ||
+		!reflect.DeepEqual(params, []interface{}{12, 3}) {
+		t.Errorf("authors don't see their private recipes: %s %v", where,
+			params)
+	}
+	where, _ = recipeFilterSQL(defs.ItemFilter{
+		Viewer: defs.Viewer{UserID: 3, All: true}}, nil)
+	if where != "" {
+		t.Errorf("admins don't see every recipe: %s", where)
+	}
+}
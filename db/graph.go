@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file exposes the links between recipes as a graph.
+ */
+
+package db
+
+import "github.com/rwestlund/recipes/defs"
+
+// FetchRecipeGraph returns the links between the recipes the viewer may see.
+// If linkedOnly is set, recipes without any links are left out.
+func FetchRecipeGraph(linkedOnly bool,
+	viewer defs.Viewer) (*defs.RecipeGraph, error) {
+	var where, params = recipeFilterSQL(defs.ItemFilter{Viewer: viewer}, nil)
+	if linkedOnly {
+		var linked = `EXISTS (SELECT 1 FROM linked_recipes
+                WHERE src = recipes.id OR dest = recipes.id)`
+		if where == "" {
+			where = "\n\t WHERE " + linked
+		} else {
+			where += "\n\t AND " + linked
+		}
+	}
+	var rows, err = DB.Query(`SELECT id, title FROM recipes`+where+
+		"\n\t ORDER BY id", params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var graph = defs.RecipeGraph{
+		Nodes: []defs.GraphNode{},
+		Edges: []defs.GraphEdge{},
+	}
+	// Only links between recipes the viewer sees are shown.
+	var seen = make(map[int]bool)
+	for rows.Next() {
+		var n defs.GraphNode
+		err = rows.Scan(&n.ID, &n.Title)
+		if err != nil {
+			return nil, err
+		}
+		graph.Nodes = append(graph.Nodes, n)
+		seen[n.ID] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.Query(`SELECT src, dest, role, quantity
+            FROM linked_recipes ORDER BY src, dest`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e defs.GraphEdge
+		err = rows.Scan(&e.Src, &e.Dest, &e.Role, &e.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		if seen[e.Src] && seen[e.Dest] {
+			graph.Edges = append(graph.Edges, e)
+		}
+	}
+	return &graph, rows.Err()
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file exposes the database interface for recipe photos. The images are
+ * kept in blob storage; this only tracks them.
+ */
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// CreatePhoto records a new photo for a recipe, returning it with its ID. The
+// first photo of a recipe becomes its cover.
+func CreatePhoto(recipeID int, width int, height int) (*defs.Photo, error) {
+	var rows, err = DB.Query(`INSERT INTO photos
+                (recipe_id, position, cover, width, height)
+            SELECT $1, COALESCE(MAX(position), 0) + 1, COUNT(*) = 0, $2, $3
+                FROM photos WHERE recipe_id = $1
+            RETURNING id, cover, width, height`,
+		recipeID, width, height)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	var p defs.Photo
+	err = rows.Scan(&p.ID, &p.Cover, &p.Width, &p.Height)
+	if err != nil {
+		return nil, err
+	}
+	p.FillURLs()
+	return &p, nil
+}
+
+// FetchPhotoIDs returns the IDs of all photos of a recipe.
+func FetchPhotoIDs(recipeID int) ([]int, error) {
+	var rows, err = DB.Query(`SELECT id FROM photos WHERE recipe_id = $1`,
+		recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FetchPhotoExists reports whether a photo is recorded.
+func FetchPhotoExists(photoID int) (bool, error) {
+	var exists bool
+	var err = DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM photos WHERE id = $1)`,
+		photoID).Scan(&exists)
+	return exists, err
+}
+
+// FetchPhotoVisible reports whether a photo is recorded on a recipe the viewer
+// may see, and whether that recipe is public.
+func FetchPhotoVisible(photoID int, viewer defs.Viewer) (bool, bool, error) {
+	var visible, public bool
+	var and, params = andVisibleSQL(viewer, []interface{}{photoID})
+	var err = DB.QueryRow(`SELECT true, recipes.visibility = 'public'
+            FROM photos JOIN recipes ON recipes.id = photos.recipe_id
+            WHERE photos.id = $1`+and, params...).Scan(&visible, &public)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	return visible, public, err
+}
+
+// DeletePhoto removes a photo from a recipe. If it was the cover, the next
+// photo takes its place. It returns sql.ErrNoRows if the recipe has no such
+// photo.
+func DeletePhoto(recipeID int, photoID int) error {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM photos WHERE id = $1 AND recipe_id = $2`,
+		photoID, recipeID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = tx.Exec(`UPDATE photos SET cover = true
+            WHERE id = (SELECT id FROM photos WHERE recipe_id = $1
+                    ORDER BY position LIMIT 1)
+                AND NOT EXISTS (SELECT 1 FROM photos
+                    WHERE recipe_id = $1 AND cover)`, recipeID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetCoverPhoto makes a photo the cover of its recipe. It returns
+// sql.ErrNoRows if the recipe has no such photo.
+func SetCoverPhoto(recipeID int, photoID int) error {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE photos SET cover = false
+            WHERE recipe_id = $1 AND cover`, recipeID)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`UPDATE photos SET cover = true
+            WHERE id = $1 AND recipe_id = $2`, photoID, recipeID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file exposes the database interface for ratings of recipes.
+ */
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// RateRecipe records a user's rating of a recipe, replacing any they gave it
+// before. A rating of zero removes theirs. It returns sql.ErrNoRows if there
+// is no such recipe.
+func RateRecipe(recipeID int, userID int, rating int) error {
+	var exists bool
+	var err = DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM recipes WHERE id = $1)`,
+		recipeID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	if rating == 0 {
+		_, err = DB.Exec(`DELETE FROM ratings
+                WHERE recipe_id = $1 AND user_id = $2`, recipeID, userID)
+		return err
+	}
+	// One statement, so two first ratings at once can't both insert.
+	_, err = DB.Exec(`INSERT INTO ratings (recipe_id, user_id, rating)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (recipe_id, user_id)
+                DO UPDATE SET rating = EXCLUDED.rating`,
+		recipeID, userID, rating)
+	return err
+}
+
+// FetchUserRatings returns the ratings a user has given.
+func FetchUserRatings(userID int) ([]defs.BackupRating, error) {
+	var rows, err = DB.Query(`SELECT recipe_id, user_id, rating FROM ratings
+            WHERE user_id = $1 ORDER BY recipe_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ratings = []defs.BackupRating{}
+	for rows.Next() {
+		var r defs.BackupRating
+		err = rows.Scan(&r.RecipeID, &r.UserID, &r.Rating)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
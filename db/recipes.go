@@ -10,44 +10,112 @@ package db
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"strconv"
-	"strings"
 
 	"github.com/rwestlund/recipes/defs"
 )
 
+// ErrLinkCycle is returned when saving a recipe would make it, through its
+// components, a component of itself.
+var ErrLinkCycle = errors.New("recipe would be a component of itself")
+
 // SQL to select recipes.
 var queryRows = `SELECT recipes.id, recipes.revision,
             recipes.amount, recipes.author_id, recipes.directions,
             recipes.ingredients, recipes.notes, recipes.oven,
             recipes.source, recipes.summary, recipes.time, recipes.title,
+            recipes.slug, recipes.visibility,
+            recipes.minutes, recipes.creation_date, recipes.modified_date,
             COALESCE(json_agg(tags.tag) FILTER (WHERE tags.tag IS NOT NULL),
                     '[]'::json)
                 AS tags,
             users.name,
+            (SELECT json_build_object(
+                        'id', parent.id,
+                        'title', parent.title,
+                        'revision', recipes.parent_revision,
+                        'current_revision', parent.revision,
+                        'author_id', parent.author_id,
+                        'visibility', parent.visibility)
+                    FROM recipes parent
+                    WHERE parent.id = recipes.parent_id)
+                AS parent,
             COALESCE((SELECT json_agg(json_build_object(
                         'id', linked_recipes.dest,
-                        'title', lr.title))
+                        'title', lr.title,
+                        'role', linked_recipes.role,
+                        'quantity', linked_recipes.quantity))
                     FROM linked_recipes, recipes lr
                     WHERE recipes.id = linked_recipes.src
                         AND linked_recipes.dest = lr.id),
                 '[]'::json)
-                AS linked_recipes
+                AS linked_recipes,
+            COALESCE((SELECT json_agg(json_build_object(
+                        'id', linked_recipes.src,
+                        'title', lr.title,
+                        'role', linked_recipes.role,
+                        'quantity', linked_recipes.quantity,
+                        'author_id', lr.author_id,
+                        'visibility', lr.visibility)
+                    ORDER BY lr.title)
+                    FROM linked_recipes, recipes lr
+                    WHERE recipes.id = linked_recipes.dest
+                        AND linked_recipes.src = lr.id),
+                '[]'::json)
+                AS used_in,
+            COALESCE((SELECT json_agg(json_build_object(
+                        'id', photos.id,
+                        'cover', photos.cover,
+                        'width', photos.width,
+                        'height', photos.height)
+                    ORDER BY photos.position)
+                    FROM photos
+                    WHERE photos.recipe_id = recipes.id),
+                '[]'::json)
+                AS photos,
+            COALESCE((SELECT json_agg(json_build_object(
+                        'id', attachments.id,
+                        'filename', attachments.filename,
+                        'content_type', attachments.content_type,
+                        'size', attachments.size)
+                    ORDER BY attachments.id)
+                    FROM attachments
+                    WHERE attachments.recipe_id = recipes.id),
+                '[]'::json)
+                AS attachments,
+            (SELECT COALESCE(AVG(ratings.rating), 0)::float8
+                    FROM ratings
+                    WHERE ratings.recipe_id = recipes.id)
+                AS rating,
+            (SELECT COUNT(*)
+                    FROM ratings
+                    WHERE ratings.recipe_id = recipes.id)
+                AS rating_count
         FROM recipes
         JOIN users
             ON recipes.author_id = users.id
         LEFT JOIN tags
             ON recipes.id = tags.recipe_id `
 
+// Who may see a recipe that another is related to, read along with it.
+type relatedVisibility struct {
+	AuthorID   int    `json:"author_id"`
+	Visibility string `json:"visibility"`
+}
+
 // scanRecipe is a helper function to read Recipe out of a sql.Rows object.
-func scanRecipe(row *sql.Rows) (*defs.Recipe, error) {
+// Related recipes the viewer may not see are left out.
+func scanRecipe(row *sql.Rows, viewer defs.Viewer) (*defs.Recipe, error) {
 	// JSON fields need special handling.
 	var ingredients, directions, tags string
-	var linkedRecipes []byte
+	var parent, linkedRecipes, usedIn, photos, attachments []byte
 	var r defs.Recipe
 	err := row.Scan(&r.ID, &r.Revision, &r.Amount, &r.AuthorID, &directions,
 		&ingredients, &r.Notes, &r.Oven, &r.Source, &r.Summary,
-		&r.Time, &r.Title, &tags, &r.AuthorName, &linkedRecipes)
+		&r.Time, &r.Title, &r.Slug, &r.Visibility, &r.Minutes, &r.CreationDate, &r.ModifiedDate,
+		&tags, &r.AuthorName, &parent, &linkedRecipes, &usedIn, &photos,
+		&attachments, &r.Rating, &r.RatingCount)
 	if err != nil {
 		return nil, err
 	}
@@ -64,55 +132,162 @@ func scanRecipe(row *sql.Rows) (*defs.Recipe, error) {
 	if e != nil {
 		return nil, e
 	}
+	// This is NULL unless the recipe is a fork.
+	if parent != nil {
+		var p struct {
+			defs.RecipeParent
+			relatedVisibility
+		}
+		e = json.Unmarshal(parent, &p)
+		if e != nil {
+			return nil, e
+		}
+		if viewer.Sees(p.AuthorID, p.Visibility) {
+			r.Parent = &p.RecipeParent
+		}
+	}
 	e = json.Unmarshal(linkedRecipes, &r.LinkedRecipes)
 	if e != nil {
 		return nil, e
 	}
+	var usedInWho []struct {
+		defs.LinkedRecipe
+		relatedVisibility
+	}
+	e = json.Unmarshal(usedIn, &usedInWho)
+	if e != nil {
+		return nil, e
+	}
+	r.UsedIn = []defs.LinkedRecipe{}
+	for _, u := range usedInWho {
+		if viewer.Sees(u.AuthorID, u.Visibility) {
+			r.UsedIn = append(r.UsedIn, u.LinkedRecipe)
+		}
+	}
+	e = json.Unmarshal(photos, &r.Photos)
+	if e != nil {
+		return nil, e
+	}
+	for i := range r.Photos {
+		r.Photos[i].FillURLs()
+	}
+	e = json.Unmarshal(attachments, &r.Attachments)
+	if e != nil {
+		return nil, e
+	}
+	for i := range r.Attachments {
+		r.Attachments[i].FillURL()
+	}
 	return &r, nil
 }
 
 // FetchRecipes returns all recipes from the database that match the given
 // filter. The query in the filter can match either the title or the tag.
 func FetchRecipes(filter defs.ItemFilter) ([]defs.Recipe, error) {
+	var recipes, _, err = fetchRecipes(filter, filter.Count)
+	return recipes, err
+}
+
+// FetchRecipePage returns the recipes matching the filter like FetchRecipes,
+// along with the total number of matches and cursors for the pages on either
+// side.
+func FetchRecipePage(filter defs.ItemFilter) (*defs.RecipeResults, error) {
+	// Ask for one more than we need to see if there's another page.
+	var limit = filter.Count
+	if limit != 0 {
+		limit++
+	}
+	var recipes, from, err = fetchRecipes(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	var results defs.RecipeResults
+	if filter.Count != 0 {
+		var hasNext, hasPrev, trimFront = pageCursors(from, filter.Count,
+			len(recipes), filter.Skip != 0)
+		if len(recipes) > filter.Count {
+			if trimFront {
+				recipes = recipes[1:]
+			} else {
+				recipes = recipes[:filter.Count]
+			}
+		}
+		if len(recipes) != 0 {
+			var sort = recipeSortKey(filter)
+			var column = recipeSortColumns[sort]
+			var first, last = &recipes[0], &recipes[len(recipes)-1]
+			if hasNext {
+				results.Next = cursor{Sort: sort, Value: column.value(last),
+					ID: last.ID}.encode()
+			}
+			if hasPrev {
+				results.Prev = cursor{Sort: sort, Value: column.value(first),
+					ID: first.ID, Backward: true}.encode()
+			}
+		}
+	}
+	results.Recipes = recipes
+
+	// Count every match, ignoring pagination.
+	var where, params = recipeFilterSQL(filter, nil)
+	err = DB.QueryRow("SELECT COUNT(*) FROM recipes "+where,
+		params...).Scan(&results.Total)
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// fetchRecipes runs a search for recipes, returning at most limit of them. If
+// the filter has a cursor, it is decoded and returned too.
+func fetchRecipes(filter defs.ItemFilter,
+	limit int) ([]defs.Recipe, *cursor, error) {
 	// Hold the dynamically generated portion of our SQL.
 	var queryText string
 	// Hold all the parameters for our query.
 	var params []interface{}
 
-	// Tokenize search string on spaces. Each term must be matched in the title
-	// or tags for a recipe to be returned.
-	var terms = strings.Split(filter.Query, " ")
-	for i, term := range terms {
-		// Ignore blank terms (comes from leading/trailing spaces).
-		if term == "" {
-			continue
+	queryText, params = recipeFilterSQL(filter, params)
+	var sort = recipeSortKey(filter)
+	var descending = filter.Descending
+
+	// A cursor replaces the offset with a condition on the sort key.
+	var from *cursor
+	if filter.Cursor != "" {
+		var c, err = decodeCursor(filter.Cursor, sort)
+		if err != nil {
+			return nil, nil, err
+		}
+		from = &c
+		// Read backward pages in reverse, then flip them around below.
+		if c.Backward {
+			descending = !descending
 		}
-		if i == 0 {
-			queryText += "\n\t HAVING (title ILIKE $"
+		var keyset string
+		keyset, params = keysetSQL(c, recipeSortColumns[sort].expr,
+			recipeSortColumns[sort].sqlType, "recipes.id", descending, params)
+		if queryText == "" {
+			queryText = "\n\t WHERE " + keyset
 		} else {
-			queryText += " AND (title ILIKE $"
+			queryText += "\n\t AND " + keyset
 		}
-		params = append(params, "%"+term+"%")
-		queryText += strconv.Itoa(len(params)) +
-			"\n\t\t OR string_agg(tags.tag, ' ') ILIKE $" +
-			strconv.Itoa(len(params)) + ") "
 	}
-	queryText += "\n\t ORDER BY title "
 
-	if filter.Count != 0 {
-		params = append(params, filter.Count)
+	queryText += "\n\t GROUP BY recipes.id, users.name "
+	queryText += recipeOrderSQL(sort, descending)
+
+	if limit != 0 {
+		params = append(params, limit)
 		queryText += "\n\t LIMIT $" + strconv.Itoa(len(params))
 	}
-	if filter.Skip != 0 {
+	if filter.Skip != 0 && from == nil {
 		params = append(params, filter.Count*filter.Skip)
 		queryText += "\n\t OFFSET $" + strconv.Itoa(len(params))
 	}
 	// Run the actual query.
-	var rows, err = DB.Query(queryRows+
-		"\n\t GROUP BY recipes.id, users.name "+
-		queryText, params...)
+	var rows, err = DB.Query(queryRows+queryText, params...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -123,21 +298,28 @@ func FetchRecipes(filter defs.ItemFilter) ([]defs.Recipe, error) {
 	var r *defs.Recipe
 	// Iterate over rows, reading in each Recipe as we go.
 	for rows.Next() {
-		r, err = scanRecipe(rows)
+		r, err = scanRecipe(rows, filter.Viewer)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		recipes = append(recipes, *r)
 	}
-	return recipes, rows.Err()
+	if from != nil && from.Backward {
+		for i, j := 0, len(recipes)-1; i < j; i, j = i+1, j-1 {
+			recipes[i], recipes[j] = recipes[j], recipes[i]
+		}
+	}
+	return recipes, from, rows.Err()
 }
 
-// FetchRecipeTitles returns a JSON list of existing titles.
-func FetchRecipeTitles() ([]byte, error) {
+// FetchRecipeTitles returns a JSON list of the titles of recipes the viewer may
+// see.
+func FetchRecipeTitles(viewer defs.Viewer) ([]byte, error) {
+	var where, params = recipeFilterSQL(defs.ItemFilter{Viewer: viewer}, nil)
 	// Return them all in one row.
 	var rows, err = DB.Query(`SELECT json_agg(
             json_build_object('id', id, 'title', title) ORDER BY title)
-            FROM recipes`)
+            FROM recipes`+where, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,10 +336,18 @@ func FetchRecipeTitles() ([]byte, error) {
 	return titles, err
 }
 
-// FetchRecipe returns one Recipe by ID.
-func FetchRecipe(id int) (*defs.Recipe, error) {
-	var rows, err = DB.Query(queryRows+
-		" WHERE recipes.id = $1 GROUP BY recipes.id, users.name", id)
+// FetchRecipe returns one Recipe by ID. A recipe the viewer may not see is
+// sql.ErrNoRows, as if it didn't exist.
+func FetchRecipe(id int, viewer defs.Viewer) (*defs.Recipe, error) {
+	var where, params = recipeFilterSQL(defs.ItemFilter{Viewer: viewer},
+		[]interface{}{id})
+	if where == "" {
+		where = "\n\t WHERE recipes.id = $1"
+	} else {
+		where += "\n\t AND recipes.id = $1"
+	}
+	var rows, err = DB.Query(queryRows+where+
+		" GROUP BY recipes.id, users.name", params...)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +355,7 @@ func FetchRecipe(id int) (*defs.Recipe, error) {
 	if !rows.Next() {
 		return nil, sql.ErrNoRows
 	}
-	return scanRecipe(rows)
+	return scanRecipe(rows, viewer)
 }
 
 // CreateRecipe creates a recipe in the database, returning fields in the
@@ -173,23 +363,28 @@ func FetchRecipe(id int) (*defs.Recipe, error) {
 // read.
 func CreateRecipe(recipe *defs.Recipe) (*defs.Recipe, error) {
 	//TODO some input validation on would be nice
-	var rows, err = DB.Query(`INSERT INTO recipes (title, summary, author_id)
-            VALUES ($1, $2, $3)
-                RETURNING id`,
-		recipe.Title, recipe.Summary, recipe.AuthorID)
+	var tx, err = DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	if !rows.Next() {
-		return nil, sql.ErrNoRows
+	defer tx.Rollback()
+	slug, err := uniqueSlug(tx, recipe.Title, 0)
+	if err != nil {
+		return nil, err
 	}
 	var id int
-	err = rows.Scan(&id)
+	err = tx.QueryRow(`INSERT INTO recipes (title, slug, summary, author_id)
+            VALUES ($1, $2, $3, $4)
+                RETURNING id`,
+		recipe.Title, slug, recipe.Summary, recipe.AuthorID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
-	return FetchRecipe(id)
+	return FetchRecipe(id, defs.Viewer{UserID: recipe.AuthorID})
 }
 
 // SaveRecipe takes a Recipe to save and the userID of the current user trying
@@ -216,16 +411,19 @@ func SaveRecipe(recipe *defs.Recipe, userID int, force bool) (*defs.Recipe, erro
 	var params []interface{}
 
 	queryText = `UPDATE recipes SET (revision, amount, directions,
-                ingredients, notes, oven, source, summary, time, title) =
-                (revision + 1, $1, $2, $3, $4, $5, $6, $7, $8, $9)
-            WHERE id = $10 `
+                ingredients, notes, oven, source, summary, time, title,
+                minutes, visibility, modified_date) =
+                (revision + 1, $1, $2, $3, $4, $5, $6, $7, $8, $9,
+                $10, COALESCE(NULLIF($11, ''), visibility), CURRENT_TIMESTAMP)
+            WHERE id = $12 `
 	params = []interface{}{recipe.Amount, directions, ingredients,
 		recipe.Notes, recipe.Oven, recipe.Source, recipe.Summary,
-		recipe.Time, recipe.Title, recipe.ID}
+		recipe.Time, recipe.Title, defs.ParseMinutes(recipe.Time),
+		recipe.Visibility, recipe.ID}
 	// If force is not set, we need to make sure the author is the one making
 	// this change.
 	if force == false {
-		queryText += "AND author_id = $11 "
+		queryText += "AND author_id = $13 "
 		params = append(params, userID)
 	}
 
@@ -241,7 +439,7 @@ func SaveRecipe(recipe *defs.Recipe, userID int, force bool) (*defs.Recipe, erro
 		return nil, err
 	}
 	// Insert the new tags.
-	for _, tag := range recipe.Tags {
+	for _, tag := range normalizeTags(recipe.Tags) {
 		_, err = tx.Exec(`INSERT INTO tags (recipe_id, tag)
                 VALUES ($1, $2)`, recipe.ID, tag)
 		if err != nil {
@@ -259,12 +457,31 @@ func SaveRecipe(recipe *defs.Recipe, userID int, force bool) (*defs.Recipe, erro
 	// Insert the new linked_recipes.
 	var lr defs.LinkedRecipe
 	for _, lr = range recipe.LinkedRecipes {
-		_, err = tx.Exec(`INSERT INTO linked_recipes (src, dest)
-                VALUES ($1, $2)`, recipe.ID, lr.ID)
+		_, err = tx.Exec(`INSERT INTO linked_recipes (src, dest, role,
+                    quantity)
+                VALUES ($1, $2, $3, $4)`, recipe.ID, lr.ID, lr.Role,
+			lr.Quantity)
 		if err != nil {
 			return nil, err
 		}
 	}
+	// A recipe can't be made from itself, however far down.
+	err = checkLinkCycle(tx, recipe.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new title gets a new slug.
+	err = updateSlug(tx, recipe.ID, recipe.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	// Directions may only refer to this recipe's attachments.
+	err = checkAttachments(tx, recipe)
+	if err != nil {
+		return nil, err
+	}
 
 	// Finally, run the actual query to update the Recipe fields.
 	rows, err := tx.Query(queryText+"RETURNING id", params...)
@@ -285,7 +502,96 @@ func SaveRecipe(recipe *defs.Recipe, userID int, force bool) (*defs.Recipe, erro
 	if err != nil {
 		return nil, err
 	}
-	return FetchRecipe(id)
+	return FetchRecipe(id, defs.Viewer{UserID: userID, All: force})
+}
+
+// ForkRecipe copies a recipe into a new one owned by the given user, noting
+// the original as its parent. The fields, tags, and links are copied, but not
+// photos or attachments, so directions lose their attachments. Only public
+// recipes and the user's own may be forked, unless force is set; others are
+// sql.ErrNoRows.
+func ForkRecipe(parentID int, userID int, force bool) (*defs.Recipe, error) {
+	var parent, err = FetchRecipe(parentID,
+		defs.Viewer{UserID: userID, All: force})
+	if err != nil {
+		return nil, err
+	}
+	var directions = make(defs.DirectionSections, len(parent.Directions))
+	for i, section := range parent.Directions {
+		directions[i] = defs.DirectionSection{Heading: section.Heading,
+			Items: make([]defs.Direction, len(section.Items))}
+		for j, d := range section.Items {
+			directions[i].Items[j] = defs.Direction{Text: d.Text}
+		}
+	}
+	directionsJSON, err := json.Marshal(directions)
+	if err != nil {
+		return nil, err
+	}
+	ingredients, err := json.Marshal(parent.Ingredients)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	slug, err := uniqueSlug(tx, parent.Title, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO recipes (author_id, amount, directions,
+                ingredients, notes, oven, source, summary, time, title,
+                slug, minutes, parent_id, parent_revision)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+                $14)
+            RETURNING id`,
+		userID, parent.Amount, directionsJSON, ingredients, parent.Notes,
+		parent.Oven, parent.Source, parent.Summary, parent.Time,
+		parent.Title, slug, parent.Minutes, parent.ID,
+		parent.Revision).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO tags (recipe_id, tag)
+            SELECT $1, tag FROM tags WHERE recipe_id = $2`, id, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO linked_recipes (src, dest, role, quantity)
+            SELECT $1, dest, role, quantity FROM linked_recipes
+                WHERE src = $2`, id, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return FetchRecipe(id, defs.Viewer{UserID: userID, All: force})
+}
+
+// ImportRecipe creates a complete recipe owned by the given user, such as one
+// read from another format. Since CreateRecipe only takes a title and summary,
+// the rest is saved after, and the recipe is removed again if that fails.
+func ImportRecipe(recipe *defs.Recipe, userID int) (*defs.Recipe, error) {
+	recipe.AuthorID = userID
+	var created, err = CreateRecipe(recipe)
+	if err != nil {
+		return nil, err
+	}
+	recipe.ID = created.ID
+	saved, err := SaveRecipe(recipe, userID, false)
+	if err != nil {
+		// Report why saving failed, rather than any trouble cleaning up.
+		DeleteRecipe(created.ID, userID, false)
+		return nil, err
+	}
+	return saved, nil
 }
 
 // DeleteRecipe takes a Recipe id to delete and the userID of the current user
@@ -320,3 +626,27 @@ func DeleteRecipe(recipeID int, userID int, force bool) error {
 	}
 	return nil
 }
+
+// checkLinkCycle returns ErrLinkCycle if a recipe is, through its components,
+// a component of itself.
+func checkLinkCycle(tx *sql.Tx, recipeID int) error {
+	var cycle bool
+	var err = tx.QueryRow(`WITH RECURSIVE components (id) AS (
+                SELECT dest FROM linked_recipes
+                    WHERE src = $1 AND role = 'component'
+            UNION
+                SELECT linked_recipes.dest
+                    FROM linked_recipes, components
+                    WHERE linked_recipes.src = components.id
+                        AND linked_recipes.role = 'component'
+            )
+            SELECT EXISTS (SELECT 1 FROM components WHERE id = $1)`,
+		recipeID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrLinkCycle
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file manages recipe slugs, the readable names recipes can be fetched
+ * by in place of their IDs.
+ */
+
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Paths under /recipes that aren't recipes, so no recipe may have them as a
+// slug.
+var reservedSlugs = map[string]bool{
+	"bulk":       true,
+	"cookable":   true,
+	"duplicates": true,
+	"graph":      true,
+	"import":     true,
+	"titles":     true,
+}
+
+// slugFits reports whether slug was made from base, either as it is or with a
+// collision suffix like "-2".
+func slugFits(slug string, base string) bool {
+	if slug == base {
+		return true
+	}
+	if !strings.HasPrefix(slug, base+"-") {
+		return false
+	}
+	var _, err = strconv.Atoi(slug[len(base)+1:])
+	return err == nil
+}
+
+// uniqueSlug returns a slug for the title that no other recipe has now or had
+// before, adding a suffix like "-2" if needed. A recipe may take back one of
+// its own old slugs. Use a recipeID of 0 for a new recipe.
+func uniqueSlug(tx *sql.Tx, title string, recipeID int) (string, error) {
+	var base = defs.Slugify(title)
+	for n := 1; ; n++ {
+		var slug = base
+		if n > 1 {
+			slug += "-" + strconv.Itoa(n)
+		}
+		var taken, err = slugTaken(tx, slug, recipeID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+	}
+}
+
+// slugTaken reports whether a slug is reserved, or another recipe has it now
+// or had it before.
+func slugTaken(tx *sql.Tx, slug string, recipeID int) (bool, error) {
+	if reservedSlugs[slug] {
+		return true, nil
+	}
+	var taken bool
+	var err = tx.QueryRow(`SELECT
+            EXISTS (SELECT 1 FROM recipes
+                WHERE slug = $1 AND id != $2)
+            OR EXISTS (SELECT 1 FROM recipe_slugs
+                WHERE slug = $1 AND recipe_id != $2)`,
+		slug, recipeID).Scan(&taken)
+	return taken, err
+}
+
+// updateSlug gives a recipe a new slug if its title changed, keeping the old
+// one so links to it still work.
+func updateSlug(tx *sql.Tx, recipeID int, title string) error {
+	var current string
+	var err = tx.QueryRow(`SELECT slug FROM recipes WHERE id = $1`,
+		recipeID).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if slugFits(current, defs.Slugify(title)) {
+		return nil
+	}
+	slug, err := uniqueSlug(tx, title, recipeID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM recipe_slugs WHERE slug = $1`, slug)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO recipe_slugs (slug, recipe_id)
+            VALUES ($1, $2)`, current, recipeID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE recipes SET slug = $1 WHERE id = $2`, slug,
+		recipeID)
+	return err
+}
+
+// FetchRecipeIDBySlug finds the recipe with the given slug, now or in the
+// past, and returns its ID and current slug. It returns sql.ErrNoRows if no
+// recipe ever had the slug.
+func FetchRecipeIDBySlug(slug string) (int, string, error) {
+	var id int
+	var current string
+	var err = DB.QueryRow(`SELECT id, slug FROM recipes WHERE slug = $1
+        UNION ALL
+        SELECT recipes.id, recipes.slug
+            FROM recipe_slugs, recipes
+            WHERE recipe_slugs.slug = $1
+                AND recipe_slugs.recipe_id = recipes.id
+        LIMIT 1`, slug).Scan(&id, &current)
+	return id, current, err
+}
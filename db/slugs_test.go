@@ -0,0 +1,20 @@
+package db
+
+import "testing"
+
+func TestSlugFits(t *testing.T) {
+	var cases = []struct {
+		slug, base string
+		want       bool
+	}{
+		{"banana-bread", "banana-bread", true},
+		{"banana-bread-2", "banana-bread", true},
+		{"banana-bread-muffins", "banana-bread", false},
+		{"banana", "banana-bread", false},
+	}
+	for _, c := range cases {
+		if got := slugFits(c.slug, c.base); got != c.want {
+			t.Errorf("slugFits(%q, %q) = %v", c.slug, c.base, got)
+		}
+	}
+}
@@ -7,6 +7,48 @@
 
 package db
 
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/rwestlund/recipes/config"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// ErrTagCycle is returned when setting a tag's parents, or merging tags, would
+// make a tag fall under itself.
+var ErrTagCycle = errors.New("tag would be its own ancestor")
+
+// normalizeTags cleans up a list of tags with defs.NormalizeTag, dropping
+// blanks and duplicates.
+func normalizeTags(tags []string) []string {
+	var seen = make(map[string]bool, len(tags))
+	var normalized = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = defs.NormalizeTag(tag, config.TagForm)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// tagTreeSQL returns a subquery for searching by the tags in the text[]
+// parameter p. It gives those tags, the tags they are synonyms of, and every
+// tag under any of them in the hierarchy.
+func tagTreeSQL(p string) string {
+	return `(WITH RECURSIVE tree(tag) AS (
+                SELECT tag FROM (SELECT unnest(` + p + `::text[]) AS tag
+                    UNION SELECT tag FROM tag_synonyms
+                        WHERE synonym = ANY(` + p + `)) start
+                UNION SELECT tag_parents.tag FROM tag_parents
+                    JOIN tree ON tag_parents.parent = tree.tag)
+            SELECT tag FROM tree)`
+}
+
 // FetchTags retuns a JSON list of all tags in the database.
 func FetchTags() ([]byte, error) {
 	var rows, err = DB.Query("SELECT json_agg(DISTINCT tag ORDER BY tag) FROM tags")
@@ -25,3 +67,261 @@ func FetchTags() ([]byte, error) {
 	err = rows.Scan(&tags)
 	return tags, err
 }
+
+// FetchTagDetails returns every tag that is used or described, along with its
+// usage count, description, synonyms, and parents.
+func FetchTagDetails() ([]defs.Tag, error) {
+	var rows, err = DB.Query(`SELECT names.tag,
+                COALESCE(used.count, 0),
+                COALESCE(tag_info.description, ''),
+                COALESCE(tag_info.color, ''),
+                COALESCE((SELECT json_agg(synonym ORDER BY synonym)
+                        FROM tag_synonyms WHERE tag = names.tag),
+                    '[]'::json),
+                COALESCE((SELECT json_agg(parent ORDER BY parent)
+                        FROM tag_parents WHERE tag = names.tag),
+                    '[]'::json)
+            FROM (SELECT tag FROM tags
+                UNION SELECT tag FROM tag_info
+                UNION SELECT tag FROM tag_synonyms
+                UNION SELECT tag FROM tag_parents
+                UNION SELECT parent FROM tag_parents) names
+            LEFT JOIN (SELECT tag, COUNT(*) AS count FROM tags GROUP BY tag) used
+                ON used.tag = names.tag
+            LEFT JOIN tag_info
+                ON tag_info.tag = names.tag
+            ORDER BY names.tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags = make([]defs.Tag, 0, 50)
+	for rows.Next() {
+		var t defs.Tag
+		// JSON fields need special handling.
+		var synonyms, parents []byte
+		err = rows.Scan(&t.Tag, &t.Count, &t.Description, &t.Color,
+			&synonyms, &parents)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(synonyms, &t.Synonyms)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(parents, &t.Parents)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// DescribeTag sets the description and color of a tag, whether or not any
+// recipes use it yet.
+func DescribeTag(tag *defs.Tag) error {
+	tag.Tag = defs.NormalizeTag(tag.Tag, config.TagForm)
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Update the existing description, or make a new one if there isn't one.
+	result, err := tx.Exec(`UPDATE tag_info SET (description, color) = ($1, $2)
+            WHERE tag = $3`, tag.Description, tag.Color, tag.Tag)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		_, err = tx.Exec(`INSERT INTO tag_info (tag, description, color)
+                VALUES ($1, $2, $3)`, tag.Tag, tag.Description, tag.Color)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MergeTags replaces every use of the tags in from with the tag to, on every
+// recipe and in the hierarchy. Renaming a tag is merging one tag into a new
+// one. If to has no description, it takes the first one found in from. It
+// returns ErrTagCycle if to would end up under itself, as when one of the
+// tags was under another.
+func MergeTags(from []string, to string) error {
+	from = normalizeTags(from)
+	to = defs.NormalizeTag(to, config.TagForm)
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Tag every recipe that had an old tag with the new one, unless it
+	// already has it.
+	_, err = tx.Exec(`INSERT INTO tags (recipe_id, tag)
+            SELECT DISTINCT recipe_id, $2::text FROM tags old
+                WHERE old.tag = ANY($1)
+                    AND NOT EXISTS (SELECT 1 FROM tags
+                        WHERE recipe_id = old.recipe_id AND tag = $2)`,
+		pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO tag_info (tag, description, color)
+            SELECT $2::text, description, color FROM tag_info
+                WHERE tag = ANY($1)
+                    AND NOT EXISTS (SELECT 1 FROM tag_info WHERE tag = $2)
+                LIMIT 1`,
+		pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE tag_synonyms SET tag = $2 WHERE tag = ANY($1)`,
+		pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	// Move links in the hierarchy, skipping any that already exist or that
+	// would link the new tag to itself.
+	_, err = tx.Exec(`INSERT INTO tag_parents (tag, parent)
+            SELECT DISTINCT $2::text, parent FROM tag_parents old
+                WHERE old.tag = ANY($1) AND old.parent != $2
+                    AND NOT EXISTS (SELECT 1 FROM tag_parents
+                        WHERE tag = $2 AND parent = old.parent)`,
+		pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO tag_parents (tag, parent)
+            SELECT DISTINCT tag, $2::text FROM tag_parents old
+                WHERE old.parent = ANY($1) AND old.tag != $2
+                    AND NOT EXISTS (SELECT 1 FROM tag_parents
+                        WHERE tag = old.tag AND parent = $2)`,
+		pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	// Now the old tags can go.
+	for _, table := range []string{"tags", "tag_info"} {
+		_, err = tx.Exec(`DELETE FROM `+table+`
+                WHERE tag = ANY($1) AND tag != $2`, pq.Array(from), to)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM tag_parents
+            WHERE (tag = ANY($1) AND tag != $2)
+                OR (parent = ANY($1) AND parent != $2)`, pq.Array(from), to)
+	if err != nil {
+		return err
+	}
+	// Every link moved involves the new tag, so any cycle runs through it.
+	var cycles int
+	err = tx.QueryRow(`WITH RECURSIVE tree(tag) AS (
+                SELECT tag FROM tag_parents WHERE parent = $1
+                UNION SELECT tag_parents.tag FROM tag_parents
+                    JOIN tree ON tag_parents.parent = tree.tag)
+            SELECT COUNT(*) FROM tree WHERE tag = $1`, to).Scan(&cycles)
+	if err != nil {
+		return err
+	}
+	if cycles != 0 {
+		return ErrTagCycle
+	}
+	return tx.Commit()
+}
+
+// DeleteTag removes a tag from every recipe, along with its description,
+// synonyms, and place in the hierarchy.
+func DeleteTag(tag string) error {
+	tag = defs.NormalizeTag(tag, config.TagForm)
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"tags", "tag_info", "tag_synonyms"} {
+		_, err = tx.Exec(`DELETE FROM `+table+` WHERE tag = $1`, tag)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM tag_parents WHERE tag = $1 OR parent = $1`,
+		tag)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetTagSynonyms replaces the list of other names that find a tag in a search.
+// A synonym can only point to one tag, so it is taken from any other tag that
+// had it.
+func SetTagSynonyms(tag string, synonyms []string) error {
+	tag = defs.NormalizeTag(tag, config.TagForm)
+	synonyms = normalizeTags(synonyms)
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM tag_synonyms
+            WHERE tag = $1 OR synonym = ANY($2)`, tag, pq.Array(synonyms))
+	if err != nil {
+		return err
+	}
+	for _, synonym := range synonyms {
+		if synonym == tag {
+			continue
+		}
+		_, err = tx.Exec(`INSERT INTO tag_synonyms (synonym, tag)
+                VALUES ($1, $2)`, synonym, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetTagParents replaces the list of broader tags a tag falls under. It
+// returns ErrTagCycle if a parent is already under the tag.
+func SetTagParents(tag string, parents []string) error {
+	tag = defs.NormalizeTag(tag, config.TagForm)
+	parents = normalizeTags(parents)
+	var tx, err = DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM tag_parents WHERE tag = $1`, tag)
+	if err != nil {
+		return err
+	}
+	// Look for the new parents among the tag and everything under it.
+	var cycles int
+	err = tx.QueryRow(`WITH RECURSIVE tree(tag) AS (
+                SELECT $1::text
+                UNION SELECT tag_parents.tag FROM tag_parents
+                    JOIN tree ON tag_parents.parent = tree.tag)
+            SELECT COUNT(*) FROM tree WHERE tag = ANY($2)`,
+		tag, pq.Array(parents)).Scan(&cycles)
+	if err != nil {
+		return err
+	}
+	if cycles != 0 {
+		return ErrTagCycle
+	}
+	for _, parent := range parents {
+		_, err = tx.Exec(`INSERT INTO tag_parents (tag, parent)
+                VALUES ($1, $2)`, tag, parent)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -8,3 +8,10 @@ func TestFetchTags(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestFetchTagDetails(t *testing.T) {
+	var _, err = FetchTagDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
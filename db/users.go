@@ -9,12 +9,18 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rwestlund/recipes/defs"
 )
 
+// ErrNoSuchUser is returned when recipes would be given to a user who doesn't
+// exist, or to the user being deleted.
+var ErrNoSuchUser = errors.New("no such user to reassign recipes to")
+
 // SQL to select users.
 var usersQuery = `SELECT users.id, users.email, users.name,
             users.role, users.lastlog, users.creation_date,
@@ -31,43 +37,142 @@ func scanUser(rows *sql.Rows) (*defs.User, error) {
 	return &u, err
 }
 
+// The expression users are sorted by. Users who never logged in go last.
+var userSortExpr = "COALESCE(users.lastlog, 'epoch'::timestamptz)"
+
+// userFilterSQL builds a WHERE clause that restricts the users table to those
+// matching the filter, appending to params like recipeFilterSQL.
+func userFilterSQL(filter defs.ItemFilter,
+	params []interface{}) (string, []interface{}) {
+	var conditions []string
+	// Tokenize search string on spaces. Each term must be matched in the
+	// name or email for a user to be returned.
+	for _, term := range strings.Split(filter.Query, " ") {
+		// Ignore blank terms (comes from leading/trailing spaces).
+		if term == "" {
+			continue
+		}
+		params = append(params, "%"+term+"%")
+		var p = "$" + strconv.Itoa(len(params))
+		conditions = append(conditions, "(name ILIKE "+p+
+			"\n\t\t OR email ILIKE "+p+
+			"\n\t\t OR role ILIKE "+p+")")
+	}
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return "\n\t WHERE " + strings.Join(conditions, "\n\t AND "), params
+}
+
+// userCursorValue gives the value of userSortExpr for a user.
+func userCursorValue(u *defs.User) string {
+	if !u.Lastlog.Valid {
+		return "epoch"
+	}
+	return u.Lastlog.Time.Format(time.RFC3339Nano)
+}
+
 // FetchUsers returns all users in the database that match the given filter.
 // The query in the filter can match either the name, email, or role.
 func FetchUsers(filter defs.ItemFilter) ([]defs.User, error) {
+	var users, _, err = fetchUsers(filter, filter.Count)
+	return users, err
+}
+
+// FetchUserPage returns the users matching the filter like FetchUsers, along
+// with the total number of matches and cursors for the pages on either side.
+func FetchUserPage(filter defs.ItemFilter) (*defs.UserResults, error) {
+	// Ask for one more than we need to see if there's another page.
+	var limit = filter.Count
+	if limit != 0 {
+		limit++
+	}
+	var users, from, err = fetchUsers(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	var results defs.UserResults
+	if filter.Count != 0 {
+		var hasNext, hasPrev, trimFront = pageCursors(from, filter.Count,
+			len(users), filter.Skip != 0)
+		if len(users) > filter.Count {
+			if trimFront {
+				users = users[1:]
+			} else {
+				users = users[:filter.Count]
+			}
+		}
+		if len(users) != 0 {
+			var first, last = &users[0], &users[len(users)-1]
+			if hasNext {
+				results.Next = cursor{Sort: "lastlog",
+					Value: userCursorValue(last), ID: last.ID}.encode()
+			}
+			if hasPrev {
+				results.Prev = cursor{Sort: "lastlog",
+					Value: userCursorValue(first), ID: first.ID,
+					Backward: true}.encode()
+			}
+		}
+	}
+	results.Users = users
+
+	// Count every match, ignoring pagination.
+	var where, params = userFilterSQL(filter, nil)
+	err = DB.QueryRow("SELECT COUNT(*) FROM users "+where,
+		params...).Scan(&results.Total)
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// fetchUsers runs a search for users, returning at most limit of them. If the
+// filter has a cursor, it is decoded and returned too.
+func fetchUsers(filter defs.ItemFilter, limit int) ([]defs.User, *cursor, error) {
 	// Hold the dynamically generated portion of our SQL.
 	var queryText string
 	// Hold all the parameters for our query.
 	var params []interface{}
 
-	// Tokenize search string on spaces. Each term must be matched in the
-	// name or email for a user to be returned.
-	var terms = strings.Split(filter.Query, " ")
-	for i, term := range terms {
-		// Ignore blank terms (comes from leading/trailing spaces).
-		if term == "" {
-			continue
-		}
+	queryText, params = userFilterSQL(filter, params)
+	// Most recent logins come first.
+	var descending = true
 
-		if i == 0 {
-			queryText += "\n\t WHERE (name ILIKE $"
+	// A cursor replaces the offset with a condition on the sort key.
+	var from *cursor
+	if filter.Cursor != "" {
+		var c, err = decodeCursor(filter.Cursor, "lastlog")
+		if err != nil {
+			return nil, nil, err
+		}
+		from = &c
+		// Read backward pages in reverse, then flip them around below.
+		if c.Backward {
+			descending = !descending
+		}
+		var keyset string
+		keyset, params = keysetSQL(c, userSortExpr, "timestamptz",
+			"users.id", descending, params)
+		if queryText == "" {
+			queryText = "\n\t WHERE " + keyset
 		} else {
-			queryText += " AND (name ILIKE $"
+			queryText += "\n\t AND " + keyset
 		}
-		params = append(params, "%"+term+"%")
-		queryText += strconv.Itoa(len(params)) +
-			"\n\t\t OR email ILIKE $" +
-			strconv.Itoa(len(params)) +
-			"\n\t\t OR role ILIKE $" +
-			strconv.Itoa(len(params)) + ") "
+	}
+	var direction = " ASC"
+	if descending {
+		direction = " DESC"
 	}
 	queryText += "\n\t GROUP BY users.id "
-	queryText += "\n\t ORDER BY lastlog DESC NULLS LAST "
+	queryText += "\n\t ORDER BY " + userSortExpr + direction +
+		", users.id" + direction
 
-	if filter.Count != 0 {
-		params = append(params, filter.Count)
+	if limit != 0 {
+		params = append(params, limit)
 		queryText += "\n\t LIMIT $" + strconv.Itoa(len(params))
 	}
-	if filter.Skip != 0 {
+	if filter.Skip != 0 && from == nil {
 		params = append(params, filter.Count*filter.Skip)
 		queryText += "\n\t OFFSET $" + strconv.Itoa(len(params))
 	}
@@ -75,7 +180,7 @@ func FetchUsers(filter defs.ItemFilter) ([]defs.User, error) {
 	// Run the actual query.
 	var rows, err = DB.Query(usersQuery+queryText, params...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -88,11 +193,16 @@ func FetchUsers(filter defs.ItemFilter) ([]defs.User, error) {
 	for rows.Next() {
 		user, err = scanUser(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		users = append(users, *user)
 	}
-	return users, rows.Err()
+	if from != nil && from.Backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	return users, from, rows.Err()
 }
 
 // CreateUser creates a new User in the database, returning fields in the
@@ -138,10 +248,73 @@ func UpdateUser(id int, user *defs.User) (*defs.User, error) {
 	return scanUser(rows)
 }
 
-// DeleteUser deletes a User by ID.
-func DeleteUser(id int) error {
-	var _, err = DB.Exec(`DELETE FROM users WHERE id = $1`, id)
-	return err
+// DeleteUser deletes a User by ID, along with their recipes. If reassignTo is
+// another user's ID, their recipes are given to that user instead. It returns
+// what was deleted, or sql.ErrNoRows if there's no such user.
+func DeleteUser(id int, reassignTo int) (*defs.DeletedUser, error) {
+	var tx, err = DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var deleted = defs.DeletedUser{
+		PhotoIDs:      []int{},
+		AttachmentIDs: []int{},
+	}
+
+	if reassignTo != 0 {
+		if reassignTo == id {
+			return nil, ErrNoSuchUser
+		}
+		var exists bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`,
+			reassignTo).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrNoSuchUser
+		}
+		result, err := tx.Exec(`UPDATE recipes SET author_id = $1
+                WHERE author_id = $2`, reassignTo, id)
+		if err != nil {
+			return nil, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		deleted.Reassigned = int(n)
+	} else {
+		// Note the files that go with the recipes, so they can be deleted too.
+		deleted.PhotoIDs, deleted.AttachmentIDs, err = recipeFiles(tx,
+			"author_id", id)
+		if err != nil {
+			return nil, err
+		}
+		result, err := tx.Exec(`DELETE FROM recipes WHERE author_id = $1`, id)
+		if err != nil {
+			return nil, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		deleted.Recipes = int(n)
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &deleted, tx.Commit()
 }
 
 // UserLogout destroys a login token.
@@ -171,6 +344,20 @@ func GoogleLogin(email string, name string, token string) (*defs.User, error) {
 	return scanUser(rows)
 }
 
+// FetchUser returns the User with the given ID.
+func FetchUser(id int) (*defs.User, error) {
+	var rows, err = DB.Query(usersQuery+
+		`WHERE users.id = $1 GROUP BY users.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanUser(rows)
+}
+
 // FetchUserByToken returns the User that matches the given token.
 func FetchUserByToken(token string) (*defs.User, error) {
 	var rows, err = DB.Query(usersQuery+
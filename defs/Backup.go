@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v3"
+)
+
+// Backup is everything in the database, as kept in a backup archive, where
+// each part is its own file. IDs are the ones records had when backed up;
+// restoring gives them new ones.
+type Backup struct {
+	Users       []BackupUser
+	Recipes     []BackupRecipe
+	Revisions   []BackupRevision
+	Tags        BackupTags
+	Links       []BackupLink
+	Photos      []BackupPhoto
+	Attachments []BackupAttachment
+	Ratings     []BackupRating
+}
+
+// BackupUser is a user in a backup. Login tokens aren't kept, so everyone
+// logs in again after a restore.
+type BackupUser struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	CreationDate time.Time `json:"creation_date"`
+	Lastlog      null.Time `json:"lastlog"`
+}
+
+// BackupRecipe is the content of a recipe in a backup.
+type BackupRecipe struct {
+	ID           int                `json:"id"`
+	AuthorID     int                `json:"author_id"`
+	Title        string             `json:"title"`
+	Slug         string             `json:"slug"`
+	Amount       string             `json:"amount"`
+	Directions   DirectionSections  `json:"directions"`
+	Ingredients  IngredientSections `json:"ingredients"`
+	Notes        string             `json:"notes"`
+	Oven         string             `json:"oven"`
+	Source       string             `json:"source"`
+	Summary      string             `json:"summary"`
+	Time         string             `json:"time"`
+	Minutes      int                `json:"minutes"`
+	CreationDate time.Time          `json:"creation_date"`
+	ModifiedDate time.Time          `json:"modified_date"`
+	// Empty in backups made before recipes had it, meaning public.
+	Visibility string `json:"visibility"`
+}
+
+// BackupRevision is where a recipe is in its history: how many times it has
+// been saved, what it was forked from, and the slugs it used to have.
+type BackupRevision struct {
+	RecipeID int `json:"recipe_id"`
+	Revision int `json:"revision"`
+	// The recipe it was forked from and that recipe's revision at the time.
+	ParentID       null.Int `json:"parent_id"`
+	ParentRevision null.Int `json:"parent_revision"`
+	OldSlugs       []string `json:"old_slugs"`
+}
+
+// BackupTags are the tags on recipes and what's known about them.
+type BackupTags struct {
+	Recipes  []BackupRecipeTag  `json:"recipes"`
+	Info     []BackupTagInfo    `json:"info"`
+	Synonyms []BackupTagSynonym `json:"synonyms"`
+	Parents  []BackupTagParent  `json:"parents"`
+}
+
+// BackupRecipeTag is a tag on a recipe.
+type BackupRecipeTag struct {
+	RecipeID int    `json:"recipe_id"`
+	Tag      string `json:"tag"`
+}
+
+// BackupTagInfo is the description and color of a tag.
+type BackupTagInfo struct {
+	Tag         string `json:"tag"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+}
+
+// BackupTagSynonym is another name for a tag.
+type BackupTagSynonym struct {
+	Synonym string `json:"synonym"`
+	Tag     string `json:"tag"`
+}
+
+// BackupTagParent is a broader tag a tag falls under.
+type BackupTagParent struct {
+	Tag    string `json:"tag"`
+	Parent string `json:"parent"`
+}
+
+// BackupLink is a link from one recipe to another.
+type BackupLink struct {
+	Src      int    `json:"src"`
+	Dest     int    `json:"dest"`
+	Role     string `json:"role"`
+	Quantity string `json:"quantity"`
+}
+
+// BackupPhoto is a photo of a recipe. The images are kept as blobs.
+type BackupPhoto struct {
+	ID           int       `json:"id"`
+	RecipeID     int       `json:"recipe_id"`
+	Position     int       `json:"position"`
+	Cover        bool      `json:"cover"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+// BackupAttachment is a file attached to a recipe. The file is kept as a
+// blob.
+type BackupAttachment struct {
+	ID           int       `json:"id"`
+	RecipeID     int       `json:"recipe_id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+// BackupRating is a user's rating of a recipe.
+type BackupRating struct {
+	RecipeID int `json:"recipe_id"`
+	UserID   int `json:"user_id"`
+	Rating   int `json:"rating"`
+}
+
+// RestoreReport says what restoring a backup did.
+type RestoreReport struct {
+	// Users created, and users in the backup that were already here, found
+	// by email.
+	Users        int `json:"users"`
+	MatchedUsers int `json:"matched_users"`
+	Recipes      int `json:"recipes"`
+	Photos       int `json:"photos"`
+	Attachments  int `json:"attachments"`
+	Ratings      int `json:"ratings"`
+	// The new IDs of what was restored, by ID in the backup.
+	UserIDs       map[int]int `json:"user_ids"`
+	RecipeIDs     map[int]int `json:"recipe_ids"`
+	PhotoIDs      map[int]int `json:"photo_ids"`
+	AttachmentIDs map[int]int `json:"attachment_ids"`
+	// Photos and attachments that were here before a restore that replaced
+	// everything, whose blobs are no longer needed.
+	DeletedPhotoIDs      []int `json:"deleted_photo_ids"`
+	DeletedAttachmentIDs []int `json:"deleted_attachment_ids"`
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// BulkChange is a change to make to many recipes at once.
+type BulkChange struct {
+	// The recipes to change. Without any, the change is made to every recipe
+	// matching the filter parameters of the request.
+	IDs        []int    `json:"ids"`
+	AddTags    []string `json:"add_tags"`
+	RemoveTags []string `json:"remove_tags"`
+	// Give the recipes to this user. Zero leaves them with their authors.
+	AuthorID int `json:"author_id"`
+	// Make the recipes VisibilityPublic or VisibilityPrivate. Empty leaves
+	// them as they are.
+	Visibility string `json:"visibility"`
+	// Delete the recipes. Nothing else may be changed along with this.
+	Delete bool `json:"delete"`
+}
+
+// What a bulk change did, or would do, to a recipe.
+const (
+	BulkChanged   = "changed"
+	BulkUnchanged = "unchanged"
+	BulkDeleted   = "deleted"
+	BulkSkipped   = "skipped"
+)
+
+// BulkResult is what a bulk change did, or would do, to one recipe.
+type BulkResult struct {
+	RecipeID int    `json:"recipe_id"`
+	Title    string `json:"title"`
+	// One of BulkChanged, BulkUnchanged, BulkDeleted, or BulkSkipped.
+	Action string `json:"action"`
+	// Why a recipe is skipped.
+	Reason string `json:"reason,omitempty"`
+	// The recipe's tags, author, and visibility after the change, unless
+	// it's deleted or skipped.
+	Tags       []string `json:"tags,omitempty"`
+	AuthorID   int      `json:"author_id,omitempty"`
+	Visibility string   `json:"visibility,omitempty"`
+}
+
+// BulkReport is the outcome of a bulk change.
+type BulkReport struct {
+	// Whether this is only what would happen, with nothing saved.
+	DryRun    bool         `json:"dry_run"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Deleted   int          `json:"deleted"`
+	Skipped   int          `json:"skipped"`
+	Results   []BulkResult `json:"results"`
+	// The photos and attachments of deleted recipes.
+	DeletedPhotoIDs      []int `json:"-"`
+	DeletedAttachmentIDs []int `json:"-"`
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// RecipeParent is the recipe another was forked from.
+type RecipeParent struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// The parent's revision when it was forked.
+	Revision int `json:"revision"`
+	// The parent's revision now. If this is higher, the parent has changed
+	// since.
+	CurrentRevision int `json:"current_revision"`
+}
+
+// Kinds of DiffLine.
+const (
+	DiffSame    = "="
+	DiffAdded   = "+"
+	DiffRemoved = "-"
+)
+
+// DiffLine is one line of a list in a RecipeDiff.
+type DiffLine struct {
+	// One of the Diff constants.
+	Op   string `json:"op"`
+	Text string `json:"text"`
+	// Whether this is a section heading rather than an item.
+	Heading bool `json:"heading,omitempty"`
+}
+
+// FieldDiff is a text field that differs between two recipes.
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RecipeDiff describes how a recipe differs from its parent.
+type RecipeDiff struct {
+	Parent RecipeParent `json:"parent"`
+	// Text fields that changed, by their JSON names.
+	Fields      []FieldDiff `json:"fields"`
+	Ingredients []DiffLine  `json:"ingredients"`
+	Directions  []DiffLine  `json:"directions"`
+	TagsAdded   []string    `json:"tags_added"`
+	TagsRemoved []string    `json:"tags_removed"`
+}
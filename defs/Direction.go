@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Direction is one step of a recipe.
+type Direction struct {
+	Text string `json:"text"`
+	// IDs of the recipe's attachments that go with this step.
+	Attachments []int `json:"attachments,omitempty"`
+}
+
+// UnmarshalJSON reads a Direction from either an object or a plain string,
+// which is how directions were written before they could have attachments.
+func (d *Direction) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*d = Direction{Text: text}
+		return nil
+	}
+	// Use a different type to avoid calling this method again.
+	type direction Direction
+	var v direction
+	var err = json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*d = Direction(v)
+	return nil
+}
+
+// Attachment is a file, such as a photo of a step or a scan of the original
+// recipe card. The contents are in blob storage.
+type Attachment struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	// Size in bytes.
+	Size int64 `json:"size"`
+	// Where to download it, filled in by FillURL.
+	URL string `json:"url"`
+}
+
+// FillURL sets the URL the attachment is served from.
+func (a *Attachment) FillURL() {
+	a.URL = "/api/attachments/" + strconv.Itoa(a.ID)
+}
@@ -0,0 +1,28 @@
+package defs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDirectionUnmarshal(t *testing.T) {
+	var got []Direction
+	var err = json.Unmarshal([]byte(`["Preheat oven.",
+		{"text": "Fold until glossy.", "attachments": [3, 4]}]`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []Direction{
+		{Text: "Preheat oven."},
+		{Text: "Fold until glossy.", Attachments: []int{3, 4}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	err = json.Unmarshal([]byte(`[7]`), &got)
+	if err == nil {
+		t.Error("expected an error for a number")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// durationPart matches a number and the word following it, like "1.5 hours".
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-zA-Z]*)`)
+
+// ParseMinutes makes a best effort to read a free-form time like "1 hour 30
+// minutes", "45 min" or "20-30 minutes" as a number of minutes. Ranges take
+// the upper bound, and a trailing number without a unit counts as minutes. It
+// returns 0 if no time could be found.
+func ParseMinutes(s string) int {
+	var total float64
+	// A number we have seen without a unit. It is dropped if the next number
+	// has a unit, as in "20-30 minutes".
+	var pending float64
+	for _, m := range durationPart.FindAllStringSubmatch(s, -1) {
+		var n, err = strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		var unit = strings.ToLower(m[2])
+		switch {
+		case strings.HasPrefix(unit, "d"):
+			total += n * 24 * 60
+		case strings.HasPrefix(unit, "h"):
+			total += n * 60
+		case strings.HasPrefix(unit, "m"):
+			total += n
+		case strings.HasPrefix(unit, "s"):
+			total += n / 60
+		default:
+			pending = n
+			continue
+		}
+		pending = 0
+	}
+	return int(total + pending + 0.5)
+}
+
+// isoDuration matches the ISO 8601 durations used by schema.org, like
+// "PT1H30M" or "P1DT2H".
+var isoDuration = regexp.MustCompile(
+	`^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISODuration reads an ISO 8601 duration like "PT1H30M" as a number of
+// minutes. It returns false if s isn't one. Years, months, and weeks aren't
+// supported, since no recipe takes that long.
+func ParseISODuration(s string) (int, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	var m = isoDuration.FindStringSubmatch(s)
+	// The pattern allows every part to be missing, but there must be one.
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, false
+	}
+	var total float64
+	for i, scale := range []float64{24 * 60, 60, 1, 1.0 / 60} {
+		if m[i+1] == "" {
+			continue
+		}
+		var n, _ = strconv.ParseFloat(m[i+1], 64)
+		total += n * scale
+	}
+	return int(total + 0.5), true
+}
+
+// FormatISODuration writes a number of minutes as an ISO 8601 duration, like
+// "PT1H30M".
+func FormatISODuration(minutes int) string {
+	var s = "PT"
+	if minutes >= 60 {
+		s += strconv.Itoa(minutes/60) + "H"
+	}
+	if minutes%60 != 0 || minutes == 0 {
+		s += strconv.Itoa(minutes%60) + "M"
+	}
+	return s
+}
+
+// FormatMinutes writes a number of minutes the way people write times, like
+// "1 hour 30 minutes", which ParseMinutes reads back.
+func FormatMinutes(minutes int) string {
+	var parts []string
+	var plural = func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return strconv.Itoa(n) + " " + unit + "s"
+	}
+	if minutes >= 60 {
+		parts = append(parts, plural(minutes/60, "hour"))
+	}
+	if minutes%60 != 0 || minutes == 0 {
+		parts = append(parts, plural(minutes%60, "minute"))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,63 @@
+package defs
+
+import "testing"
+
+func TestParseMinutes(t *testing.T) {
+	var cases = map[string]int{
+		"":                  0,
+		"overnight":         0,
+		"45 min":            45,
+		"45 minutes":        45,
+		"1 hour":            60,
+		"1 hour 30 minutes": 90,
+		"1 hr 30":           90,
+		"1.5 hours":         90,
+		"2hrs":              120,
+		"1h30m":             90,
+		"20-30 minutes":     30,
+		"1 to 2 hours":      120,
+		"90":                90,
+		"1 day":             1440,
+	}
+	for in, want := range cases {
+		if got := ParseMinutes(in); got != want {
+			t.Errorf("ParseMinutes(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestISODuration(t *testing.T) {
+	var cases = []struct {
+		in      string
+		minutes int
+		ok      bool
+	}{
+		{"PT1H30M", 90, true},
+		{"PT90M", 90, true},
+		{"P0DT1H0M0S", 60, true},
+		{"P1D", 1440, true},
+		{"PT30S", 1, true},
+		{"pt45m", 45, true},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"pt", 0, false},
+		{"1 hour", 0, false},
+	}
+	for _, c := range cases {
+		var minutes, ok = ParseISODuration(c.in)
+		if minutes != c.minutes || ok != c.ok {
+			t.Errorf("ParseISODuration(%q) = %d, %v, want %d, %v", c.in,
+				minutes, ok, c.minutes, c.ok)
+		}
+	}
+	for _, minutes := range []int{0, 1, 45, 60, 90, 1440} {
+		var back, _ = ParseISODuration(FormatISODuration(minutes))
+		if back != minutes {
+			t.Errorf("FormatISODuration(%d) = %q", minutes,
+				FormatISODuration(minutes))
+		}
+		if got := ParseMinutes(FormatMinutes(minutes)); got != minutes {
+			t.Errorf("FormatMinutes(%d) = %q", minutes, FormatMinutes(minutes))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// Facets summarizes a set of search results so the client can offer ways to
+// narrow it down further.
+type Facets struct {
+	Tags    []TagFacet    `json:"tags"`
+	Authors []AuthorFacet `json:"authors"`
+	Times   []TimeFacet   `json:"times"`
+}
+
+// TagFacet is the number of results with a given tag.
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// AuthorFacet is the number of results written by a given user.
+type AuthorFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TimeFacet is the number of results that would remain with max_time set to
+// MaxMinutes.
+type TimeFacet struct {
+	MaxMinutes int `json:"max_time"`
+	Count      int `json:"count"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// RecipeGraph is every recipe and the links between them.
+type RecipeGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a recipe in a RecipeGraph.
+type GraphNode struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// GraphEdge is a link from the Src recipe to the Dest recipe.
+type GraphEdge struct {
+	Src      int    `json:"src"`
+	Dest     int    `json:"dest"`
+	Role     string `json:"role"`
+	Quantity string `json:"quantity"`
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Ingredient is one line from a recipe's ingredient list, broken into parts.
+// Any part may be empty.
+type Ingredient struct {
+	// Like "1 1/2" or "2-3".
+	Quantity string `json:"quantity"`
+	// Like "cups", as written.
+	Unit string `json:"unit"`
+	// Like "all-purpose flour".
+	Name string `json:"name"`
+	// Anything after a comma or in parentheses, like "sifted".
+	Note string `json:"note"`
+}
+
+// Words we recognize as units, in lower case without a trailing period.
+var units = map[string]bool{
+	"c": true, "cup": true, "cups": true,
+	"t": true, "tsp": true, "teaspoon": true, "teaspoons": true,
+	"tbs": true, "tbsp": true, "tablespoon": true, "tablespoons": true,
+	"oz": true, "ounce": true, "ounces": true,
+	"lb": true, "lbs": true, "pound": true, "pounds": true,
+	"g": true, "gram": true, "grams": true, "kg": true,
+	"ml": true, "l": true, "liter": true, "liters": true,
+	"pt": true, "pint": true, "pints": true,
+	"qt": true, "quart": true, "quarts": true,
+	"gal": true, "gallon": true, "gallons": true,
+	"pinch": true, "dash": true, "handful": true, "bunch": true,
+	"can": true, "cans": true, "jar": true, "jars": true,
+	"package": true, "packages": true, "pkg": true,
+	"stick": true, "sticks": true, "clove": true, "cloves": true,
+	"slice": true, "slices": true, "sprig": true, "sprigs": true,
+	"batch": true, "batches": true,
+}
+
+// isQuantity reports whether a word is part of an amount, like "1", "1/2",
+// "1.5", "½", "2-3", or the "to" in "2 to 3".
+func isQuantity(word string) bool {
+	if word == "to" || word == "-" {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsDigit(r) && !unicode.Is(unicode.No, r) &&
+			!strings.ContainsRune("/.-", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseIngredient makes a best effort to split a line like "2 cups flour,
+// sifted" into its parts. Anything it doesn't understand goes in the name.
+func ParseIngredient(line string) Ingredient {
+	var ing Ingredient
+	var words = strings.Fields(line)
+
+	// Leading numbers, including ranges like "2 to 3".
+	var i = 0
+	for i < len(words) {
+		if isQuantity(words[i]) {
+			i++
+		} else if i > 0 && i+1 < len(words) &&
+			(words[i] == "to" || words[i] == "-") && isQuantity(words[i+1]) {
+			i += 2
+		} else {
+			break
+		}
+	}
+	ing.Quantity = strings.Join(words[:i], " ")
+
+	// A unit may follow, possibly with "of", as in "1 cup of sugar".
+	if i < len(words) {
+		var unit = strings.TrimSuffix(strings.ToLower(words[i]), ".")
+		if units[unit] {
+			ing.Unit = strings.TrimSuffix(words[i], ".")
+			i++
+			if i < len(words) && strings.ToLower(words[i]) == "of" {
+				i++
+			}
+		}
+	}
+
+	// The rest is the name, with notes pulled out.
+	var rest = strings.Join(words[i:], " ")
+	var notes []string
+	for {
+		var open = strings.Index(rest, "(")
+		var end = strings.Index(rest, ")")
+		if open < 0 || end < open {
+			break
+		}
+		notes = append(notes, strings.TrimSpace(rest[open+1:end]))
+		rest = rest[:open] + rest[end+1:]
+	}
+	if comma := strings.Index(rest, ","); comma >= 0 {
+		notes = append(notes, strings.TrimSpace(rest[comma+1:]))
+		rest = rest[:comma]
+	}
+	ing.Name = strings.Join(strings.Fields(rest), " ")
+	ing.Note = strings.Join(notes, ", ")
+	return ing
+}
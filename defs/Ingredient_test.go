@@ -0,0 +1,23 @@
+package defs
+
+import "testing"
+
+func TestParseIngredient(t *testing.T) {
+	var cases = map[string]Ingredient{
+		"2 cups flour, sifted": {"2", "cups", "flour", "sifted"},
+		"1 1/2 tsp. salt":      {"1 1/2", "tsp", "salt", ""},
+		"½ cup of sugar":       {"½", "cup", "sugar", ""},
+		"2 to 3 large eggs":    {"2 to 3", "", "large eggs", ""},
+		"3-4 cloves garlic":    {"3-4", "cloves", "garlic", ""},
+		"1 can (15 oz) beans":  {"1", "can", "beans", "15 oz"},
+		"salt and pepper":      {"", "", "salt and pepper", ""},
+		"1 batch pie crust":    {"1", "batch", "pie crust", ""},
+		"butter, for the pan":  {"", "", "butter", "for the pan"},
+		"":                     {},
+	}
+	for in, want := range cases {
+		if got := ParseIngredient(in); got != want {
+			t.Errorf("ParseIngredient(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+}
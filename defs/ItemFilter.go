@@ -5,6 +5,12 @@
 
 package defs
 
+import (
+	"time"
+
+	null "gopkg.in/guregu/null.v3"
+)
+
 // ItemFilter represents a search query for any records in a collection that
 // match the query string. It also enables server-side pagination.
 type ItemFilter struct {
@@ -14,4 +20,51 @@ type ItemFilter struct {
 	Count int
 	// Skip this many pages of results.
 	Skip int
+	// Start after (or before) the position this opaque cursor marks, instead
+	// of skipping pages. Cursors come from a previous Page.
+	Cursor string
+
+	// The remaining fields only apply to recipes.
+
+	// Only match recipes that have every one of these tags.
+	AllTags []string
+	// Only match recipes that have at least one of these tags.
+	AnyTags []string
+	// Exclude recipes that have any of these tags.
+	NoTags []string
+	// Only match recipes by this author. Zero means any author.
+	AuthorID int
+	// Only match recipes with an ingredient containing each of these.
+	Ingredients []string
+	// Exclude recipes with an ingredient containing any of these.
+	NoIngredients []string
+	// Only match recipes with a known time of at most this many minutes. Zero
+	// means no limit.
+	MaxMinutes int
+	// Only match recipes created or modified in these ranges. The zero time
+	// disables a bound.
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// If set, only match recipes with (or without) photos.
+	HasPhoto null.Bool
+	// Only match recipes rated at least this on average. Zero means any
+	// recipe, rated or not.
+	MinRating float64
+	// Only match recipes this viewer may see. The zero Viewer only sees
+	// public recipes.
+	Viewer Viewer
+	// Sort by this key; one of the SortBy* constants. Empty means by title.
+	Sort string
+	// Reverse the sort order.
+	Descending bool
 }
+
+// Sort keys accepted by ItemFilter.Sort for recipes.
+const (
+	SortByTitle    = "title"
+	SortByCreated  = "created"
+	SortByModified = "modified"
+	SortByTime     = "time"
+)
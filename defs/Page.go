@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// Page describes where one page of results sits in the full result set. Next
+// and Prev are cursors for the neighboring pages, empty if there are none.
+type Page struct {
+	Total int    `json:"total"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// RecipeResults is the response to a recipe search when the client asks for
+// more than the bare list.
+type RecipeResults struct {
+	Page
+	Recipes []Recipe `json:"recipes"`
+	Facets  *Facets  `json:"facets,omitempty"`
+}
+
+// UserResults is the response to a user search when the client asks for more
+// than the bare list.
+type UserResults struct {
+	Page
+	Users []User `json:"users"`
+}
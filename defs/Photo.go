@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import "strconv"
+
+// Photo is a picture of a recipe. The images themselves are in blob storage.
+type Photo struct {
+	ID int `json:"id"`
+	// Whether this is the recipe's main photo.
+	Cover bool `json:"cover"`
+	// Size of the full image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Where to fetch the images, filled in by FillURLs.
+	URL      string `json:"url"`
+	ThumbURL string `json:"thumb_url"`
+}
+
+// FillURLs sets the URLs the images are served from.
+func (p *Photo) FillURLs() {
+	var base = "/api/photos/" + strconv.Itoa(p.ID) + "/"
+	p.URL = base + "full.jpg"
+	p.ThumbURL = base + "thumb.jpg"
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Values of the fraction characters people paste into recipes.
+var vulgarFractions = map[rune]float64{
+	'¼': 1.0 / 4, '½': 1.0 / 2, '¾': 3.0 / 4,
+	'⅓': 1.0 / 3, '⅔': 2.0 / 3,
+	'⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+// Fractions we write amounts with, in the order they're tried.
+var fractionNames = []struct {
+	value float64
+	name  string
+}{
+	{1.0 / 2, "1/2"}, {1.0 / 4, "1/4"}, {3.0 / 4, "3/4"},
+	{1.0 / 3, "1/3"}, {2.0 / 3, "2/3"},
+	{1.0 / 8, "1/8"}, {3.0 / 8, "3/8"}, {5.0 / 8, "5/8"}, {7.0 / 8, "7/8"},
+}
+
+// ParseQuantity reads an amount like "1 1/2", "½", "1.5", or a range like
+// "2-3" or "2 to 3". For a single amount, low and high are the same. It
+// returns false if s isn't an amount.
+func ParseQuantity(s string) (low float64, high float64, ok bool) {
+	s = strings.Replace(strings.TrimSpace(s), "–", "-", -1)
+	var parts = strings.SplitN(s, " to ", 2)
+	if len(parts) == 1 {
+		parts = strings.SplitN(s, "-", 2)
+	}
+	low, ok = parseAmount(parts[0])
+	if !ok {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return low, low, true
+	}
+	high, ok = parseAmount(parts[1])
+	if !ok {
+		return 0, 0, false
+	}
+	return low, high, true
+}
+
+// parseAmount reads a single amount, which may be a whole number followed by
+// a fraction.
+func parseAmount(s string) (float64, bool) {
+	var words = strings.Fields(s)
+	if len(words) == 0 {
+		return 0, false
+	}
+	var total float64
+	for _, w := range words {
+		var v, ok = parseNumber(w)
+		if !ok {
+			return 0, false
+		}
+		total += v
+	}
+	return total, true
+}
+
+// parseNumber reads one word of an amount, like "2", "1.5", "1/2", "½", or
+// "1½".
+func parseNumber(w string) (float64, bool) {
+	var runes = []rune(w)
+	if f, ok := vulgarFractions[runes[len(runes)-1]]; ok {
+		if len(runes) == 1 {
+			return f, true
+		}
+		var whole, err = strconv.Atoi(string(runes[:len(runes)-1]))
+		if err != nil {
+			return 0, false
+		}
+		return float64(whole) + f, true
+	}
+	if slash := strings.Index(w, "/"); slash >= 0 {
+		var num, err1 = strconv.Atoi(w[:slash])
+		var den, err2 = strconv.Atoi(w[slash+1:])
+		if err1 != nil || err2 != nil || den == 0 {
+			return 0, false
+		}
+		return float64(num) / float64(den), true
+	}
+	var v, err = strconv.ParseFloat(w, 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// FormatQuantity writes an amount the way a cook would, like "1 1/2" rather
+// than "1.5" when it's close to a common fraction.
+func FormatQuantity(v float64) string {
+	var whole = math.Floor(v)
+	var frac = v - whole
+	const tolerance = 0.02
+	if frac < tolerance {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	if frac > 1-tolerance {
+		return strconv.FormatFloat(whole+1, 'f', -1, 64)
+	}
+	for _, f := range fractionNames {
+		if math.Abs(frac-f.value) < tolerance {
+			if whole == 0 {
+				return f.name
+			}
+			return strconv.FormatFloat(whole, 'f', -1, 64) + " " + f.name
+		}
+	}
+	var s = strconv.FormatFloat(v, 'f', 2, 64)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// ScaleQuantity multiplies a written amount by factor. Amounts it can't read
+// are returned unchanged.
+func ScaleQuantity(q string, factor float64) string {
+	var low, high, ok = ParseQuantity(q)
+	if !ok {
+		return q
+	}
+	if low == high {
+		return FormatQuantity(low * factor)
+	}
+	return FormatQuantity(low*factor) + "-" + FormatQuantity(high*factor)
+}
+
+// ScaleIngredient multiplies the amount at the start of an ingredient line,
+// so "1 1/2 cups flour" doubled is "3 cups flour". Lines without an amount,
+// like "salt to taste", are returned unchanged.
+func ScaleIngredient(line string, factor float64) string {
+	var ing = ParseIngredient(line)
+	if ing.Quantity == "" || factor == 1 {
+		return line
+	}
+	var n = len(strings.Fields(ing.Quantity))
+	var words = strings.Fields(line)
+	var scaled = ScaleQuantity(ing.Quantity, factor)
+	if n == len(words) {
+		return scaled
+	}
+	return scaled + " " + strings.Join(words[n:], " ")
+}
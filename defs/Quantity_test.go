@@ -0,0 +1,52 @@
+package defs
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	var cases = []struct {
+		in        string
+		low, high float64
+		ok        bool
+	}{
+		{"2", 2, 2, true},
+		{"1 1/2", 1.5, 1.5, true},
+		{"½", 0.5, 0.5, true},
+		{"1½", 1.5, 1.5, true},
+		{"0.25", 0.25, 0.25, true},
+		{"2-3", 2, 3, true},
+		{"2 to 3", 2, 3, true},
+		{"a few", 0, 0, false},
+		{"1/0", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, c := range cases {
+		var low, high, ok = ParseQuantity(c.in)
+		if low != c.low || high != c.high || ok != c.ok {
+			t.Errorf("ParseQuantity(%q) = %v, %v, %v, want %v, %v, %v",
+				c.in, low, high, ok, c.low, c.high, c.ok)
+		}
+	}
+}
+
+func TestScaleIngredient(t *testing.T) {
+	var cases = []struct {
+		in     string
+		factor float64
+		want   string
+	}{
+		{"1 1/2 cups flour", 2, "3 cups flour"},
+		{"1 cup sugar", 0.5, "1/2 cup sugar"},
+		{"2-3 large eggs", 2, "4-6 large eggs"},
+		{"3 eggs", 1.0 / 3, "1 eggs"},
+		{"1 tsp salt", 1.5, "1 1/2 tsp salt"},
+		{"1 can (15 oz) beans", 3, "3 can (15 oz) beans"},
+		{"7 apples", 0.3, "2.1 apples"},
+		{"salt to taste", 2, "salt to taste"},
+	}
+	for _, c := range cases {
+		if got := ScaleIngredient(c.in, c.factor); got != c.want {
+			t.Errorf("ScaleIngredient(%q, %v) = %q, want %q", c.in, c.factor,
+				got, c.want)
+		}
+	}
+}
@@ -5,28 +5,186 @@
 
 package defs
 
+import "time"
+
 // Recipe represents a recipe from the DB.
 type Recipe struct {
-	ID          int      `json:"id"`
-	Revision    int      `json:"revision"`
-	Amount      string   `json:"amount"`
-	AuthorID    int      `json:"author_id"`
-	Directions  []string `json:"directions"`
-	Ingredients []string `json:"ingredients"`
-	Notes       string   `json:"notes"`
-	Oven        string   `json:"oven"`
-	Source      string   `json:"source"`
-	Summary     string   `json:"summary"`
-	Time        string   `json:"time"`
-	Title       string   `json:"title"`
+	ID          int                `json:"id"`
+	Revision    int                `json:"revision"`
+	Amount      string             `json:"amount"`
+	AuthorID    int                `json:"author_id"`
+	Directions  DirectionSections  `json:"directions"`
+	Ingredients IngredientSections `json:"ingredients"`
+	Notes       string             `json:"notes"`
+	Oven        string             `json:"oven"`
+	Source      string             `json:"source"`
+	Summary     string             `json:"summary"`
+	Time        string             `json:"time"`
+	Title       string             `json:"title"`
+	// A readable, unique name made from the title, for URLs.
+	Slug string `json:"slug"`
+	// Time parsed into minutes, or 0 if it couldn't be.
+	Minutes int `json:"minutes"`
+	// Who may see the recipe, VisibilityPublic or VisibilityPrivate. Empty
+	// leaves it as it is when saving.
+	Visibility string `json:"visibility"`
+	// Timestamps maintained by the database.
+	CreationDate time.Time `json:"creation_date"`
+	ModifiedDate time.Time `json:"modified_date"`
 	/* Fields from other tables. */
-	Tags          []string       `json:"tags"`
-	AuthorName    string         `json:"author_name"`
+	Tags       []string `json:"tags"`
+	AuthorName string   `json:"author_name"`
+	// The recipe this is a variant of, or nil.
+	Parent        *RecipeParent  `json:"parent"`
 	LinkedRecipes []LinkedRecipe `json:"linked_recipes"`
+	// Recipes that link to this one. Saving a recipe doesn't change these.
+	UsedIn      []LinkedRecipe `json:"used_in"`
+	Photos      []Photo        `json:"photos"`
+	Attachments []Attachment   `json:"attachments"`
+	// The average of users' ratings from MinRating to MaxRating, or 0 if no
+	// one has rated it, and how many ratings there are.
+	Rating      float64 `json:"rating"`
+	RatingCount int     `json:"rating_count"`
+}
+
+// The range of ratings users can give a recipe.
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Who may see a recipe.
+const (
+	// Anyone.
+	VisibilityPublic = "public"
+	// Only its author, and admins and moderators.
+	VisibilityPrivate = "private"
+)
+
+// ValidVisibility reports whether v is one of the Visibility constants.
+func ValidVisibility(v string) bool {
+	return v == VisibilityPublic || v == VisibilityPrivate
+}
+
+// Viewer is who recipes are being read for, which decides whether private
+// ones are shown. The zero Viewer is someone who isn't logged in.
+type Viewer struct {
+	UserID int
+	// Whether they may see every recipe, as admins and moderators may.
+	All bool
+}
+
+// Sees reports whether the viewer may see a recipe by the given author with
+// the given visibility.
+func (v Viewer) Sees(authorID int, visibility string) bool {
+	return v.All || visibility != VisibilityPrivate ||
+		(v.UserID != 0 && v.UserID == authorID)
 }
 
 // LinkedRecipe is a reference from one recipe to another.
 type LinkedRecipe struct {
 	ID    int    `json:"id"`
 	Title string `json:"title"`
+	// How the linked recipe relates to this one, one of the Link constants,
+	// or empty for a plain link.
+	Role string `json:"role"`
+	// For components, how much of the linked recipe is used, like "1 batch"
+	// or "2". Empty means one batch.
+	Quantity string `json:"quantity"`
+}
+
+// Roles a linked recipe can have.
+const (
+	// The linked recipe is made as part of this one, like a pie crust.
+	LinkComponent = "component"
+	// The linked recipe is another way of making this one.
+	LinkVariation = "variation"
+	// The linked recipe goes well alongside this one.
+	LinkServeWith = "serve-with"
+)
+
+// ShoppingItem is one line of a shopping list, with the amounts needed by
+// every recipe on the list added together where possible.
+type ShoppingItem struct {
+	Quantity string `json:"quantity"`
+	Unit     string `json:"unit"`
+	Name     string `json:"name"`
+	// Titles of the recipes that need it.
+	Recipes []string `json:"recipes"`
+}
+
+// CookableRecipe is a recipe ranked by how much of it can be made from the
+// ingredients on hand.
+type CookableRecipe struct {
+	Recipe Recipe `json:"recipe"`
+	// The share of ingredients on hand, from 0 to 1.
+	Coverage float64 `json:"coverage"`
+	// Ingredient lines that aren't on hand.
+	Missing []string `json:"missing"`
+}
+
+// ImportPreview is a recipe read from another format, before it's saved.
+type ImportPreview struct {
+	Recipe *Recipe `json:"recipe"`
+	// Recipes we have that it's likely the same as.
+	Duplicates []SimilarRecipe `json:"duplicates"`
+	// What the original marked up in its directions, for formats like
+	// Cooklang that do that.
+	Ingredients []Ingredient `json:"ingredients,omitempty"`
+	Cookware    []Cookware   `json:"cookware,omitempty"`
+	Timers      []Timer      `json:"timers,omitempty"`
+}
+
+// What an import does with each recipe in an export.
+const (
+	ImportCreate    = "create"
+	ImportSkip      = "skip"
+	ImportDuplicate = "duplicate"
+)
+
+// ImportResult is what happened, or would happen, to one recipe in an export.
+type ImportResult struct {
+	Title string `json:"title"`
+	// One of ImportCreate, ImportSkip, or ImportDuplicate.
+	Action string `json:"action"`
+	// Why a recipe is skipped.
+	Reason string `json:"reason,omitempty"`
+	// The user who will be the author, and the name the export gave.
+	AuthorID   int    `json:"author_id"`
+	AuthorName string `json:"author_name,omitempty"`
+	// How many photos came with it.
+	Photos int `json:"photos"`
+	// Recipes it's likely the same as. Ones earlier in the same export have
+	// no ID.
+	Duplicates []SimilarRecipe `json:"duplicates,omitempty"`
+	// The new recipe, once it's created.
+	RecipeID int `json:"recipe_id,omitempty"`
+}
+
+// ImportReport is the outcome of importing another app's export.
+type ImportReport struct {
+	// Whether this is only what would happen, with nothing saved.
+	DryRun     bool           `json:"dry_run"`
+	Created    int            `json:"created"`
+	Skipped    int            `json:"skipped"`
+	Duplicates int            `json:"duplicates"`
+	Results    []ImportResult `json:"results"`
+}
+
+// DuplicatePair is two recipes that are likely the same.
+type DuplicatePair struct {
+	FirstID     int     `json:"first_id"`
+	FirstTitle  string  `json:"first_title"`
+	SecondID    int     `json:"second_id"`
+	SecondTitle string  `json:"second_title"`
+	Score       float64 `json:"score"`
+}
+
+// SimilarRecipe is a recipe suggested for being like another one.
+type SimilarRecipe struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// From 0 for nothing in common to 1 for the same tags, ingredients, and
+	// title. For duplicates, only ingredients and title count.
+	Score float64 `json:"score"`
 }
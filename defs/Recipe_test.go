@@ -0,0 +1,26 @@
+package defs
+
+import "testing"
+
+func TestViewerSees(t *testing.T) {
+	var cases = []struct {
+		viewer     Viewer
+		authorID   int
+		visibility string
+		want       bool
+	}{
+		{Viewer{}, 3, VisibilityPublic, true},
+		{Viewer{}, 3, VisibilityPrivate, false},
+		// Recipes with no author aren't anyone's who isn't logged in.
+		{Viewer{}, 0, VisibilityPrivate, false},
+		{Viewer{UserID: 3}, 3, VisibilityPrivate, true},
+		{Viewer{UserID: 4}, 3, VisibilityPrivate, false},
+		{Viewer{UserID: 4, All: true}, 3, VisibilityPrivate, true},
+	}
+	for _, c := range cases {
+		if got := c.viewer.Sees(c.authorID, c.visibility); got != c.want {
+			t.Errorf("%+v sees %s recipe by %d: got %v", c.viewer,
+				c.visibility, c.authorID, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import "encoding/json"
+
+// IngredientSection is a group of ingredients under a heading, like "For the
+// frosting". The heading may be empty.
+type IngredientSection struct {
+	Heading string   `json:"heading"`
+	Items   []string `json:"items"`
+}
+
+// DirectionSection is a group of directions under a heading.
+type DirectionSection struct {
+	Heading string      `json:"heading"`
+	Items   []Direction `json:"items"`
+}
+
+// IngredientSections are the ingredients of a recipe.
+type IngredientSections []IngredientSection
+
+// DirectionSections are the directions of a recipe.
+type DirectionSections []DirectionSection
+
+// Lines returns all ingredient lines in order, without headings.
+func (s IngredientSections) Lines() []string {
+	var lines []string
+	for _, section := range s {
+		lines = append(lines, section.Items...)
+	}
+	return lines
+}
+
+// Steps returns all directions in order, without headings.
+func (s DirectionSections) Steps() []Direction {
+	var steps []Direction
+	for _, section := range s {
+		steps = append(steps, section.Items...)
+	}
+	return steps
+}
+
+// MarshalJSON writes the sections as a list, which is empty rather than null
+// if there are none.
+func (s IngredientSections) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]IngredientSection(s))
+}
+
+// MarshalJSON writes the sections as a list, which is empty rather than null
+// if there are none.
+func (s DirectionSections) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]DirectionSection(s))
+}
+
+// UnmarshalJSON reads a list of sections. Items outside of a section, which
+// is how ingredients were written before they had sections, are gathered
+// into sections without headings.
+func (s *IngredientSections) UnmarshalJSON(data []byte) error {
+	var sections = IngredientSections{}
+	var err = readSections(data, func(heading string) {
+		sections = append(sections, IngredientSection{Heading: heading,
+			Items: []string{}})
+	}, func(item json.RawMessage) error {
+		var line string
+		var err = json.Unmarshal(item, &line)
+		var last = &sections[len(sections)-1]
+		last.Items = append(last.Items, line)
+		return err
+	})
+	*s = sections
+	return err
+}
+
+// UnmarshalJSON reads a list of sections. Directions outside of a section,
+// which is how they were written before they had sections, are gathered into
+// sections without headings.
+func (s *DirectionSections) UnmarshalJSON(data []byte) error {
+	var sections = DirectionSections{}
+	var err = readSections(data, func(heading string) {
+		sections = append(sections, DirectionSection{Heading: heading,
+			Items: []Direction{}})
+	}, func(item json.RawMessage) error {
+		var d Direction
+		var err = json.Unmarshal(item, &d)
+		var last = &sections[len(sections)-1]
+		last.Items = append(last.Items, d)
+		return err
+	})
+	*s = sections
+	return err
+}
+
+// readSections walks a JSON array of sections and loose items. It calls
+// start at the beginning of each section, and add for each item in it. Loose
+// items go in sections with empty headings.
+func readSections(data []byte, start func(heading string),
+	add func(item json.RawMessage) error) error {
+	var elements []json.RawMessage
+	var err = json.Unmarshal(data, &elements)
+	if err != nil {
+		return err
+	}
+	// Whether the current section was started for loose items.
+	var loose bool
+	for _, e := range elements {
+		var section struct {
+			Heading string            `json:"heading"`
+			Items   []json.RawMessage `json:"items"`
+		}
+		if json.Unmarshal(e, &section) == nil && section.Items != nil {
+			start(section.Heading)
+			loose = false
+			for _, item := range section.Items {
+				err = add(item)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !loose {
+			start("")
+			loose = true
+		}
+		err = add(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package defs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIngredientSectionsUnmarshal(t *testing.T) {
+	var cases = []struct {
+		in   string
+		want IngredientSections
+	}{
+		// The old flat format.
+		{`["2 eggs", "1 cup sugar"]`, IngredientSections{
+			{Items: []string{"2 eggs", "1 cup sugar"}}}},
+		{`[{"heading": "For the sponge", "items": ["4 eggs"]},
+			{"heading": "For the frosting", "items": ["1 cup butter"]}]`,
+			IngredientSections{
+				{Heading: "For the sponge", Items: []string{"4 eggs"}},
+				{Heading: "For the frosting", Items: []string{"1 cup butter"}},
+			}},
+		// Loose lines around a section.
+		{`["salt", {"heading": "Sauce", "items": []}, "pepper"]`,
+			IngredientSections{
+				{Items: []string{"salt"}},
+				{Heading: "Sauce", Items: []string{}},
+				{Items: []string{"pepper"}},
+			}},
+		{`[]`, IngredientSections{}},
+		// Older rows may hold null.
+		{`null`, IngredientSections{}},
+	}
+	for _, c := range cases {
+		var got IngredientSections
+		var err = json.Unmarshal([]byte(c.in), &got)
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestDirectionSectionsRoundTrip(t *testing.T) {
+	var got DirectionSections
+	var err = json.Unmarshal([]byte(`["Preheat oven.",
+		{"text": "Fold until glossy.", "attachments": [3]}]`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = DirectionSections{{Items: []Direction{
+		{Text: "Preheat oven."},
+		{Text: "Fold until glossy.", Attachments: []int{3}},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// Once written out, it reads back the same.
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again DirectionSections
+	err = json.Unmarshal(data, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Errorf("round trip got %+v, want %+v", again, want)
+	}
+	if got := want.Steps(); len(got) != 2 {
+		t.Errorf("Steps() = %+v", got)
+	}
+}
+
+func TestEmptySections(t *testing.T) {
+	for _, in := range []string{`{"ingredients": [], "directions": []}`,
+		`{}`} {
+		var r struct {
+			Ingredients IngredientSections `json:"ingredients"`
+			Directions  DirectionSections  `json:"directions"`
+		}
+		var err = json.Unmarshal([]byte(in), &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"ingredients":[],"directions":[]}` {
+			t.Errorf("%s came back as %s", in, data)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import "strings"
+
+// The longest slug Slugify makes, in bytes.
+const maxSlugLength = 80
+
+// Plain letters for accented ones, so "Crème Brûlée" becomes "creme-brulee".
+var slugLetters = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// Slugify makes a URL-safe name from a title, like "grandmas-banana-bread"
+// from "Grandma's Banana Bread!". It never returns an empty slug or one that
+// is only digits, which would look like an ID.
+func Slugify(title string) string {
+	var b strings.Builder
+	// Whether a dash is owed before the next letter.
+	var dash bool
+	for _, r := range strings.ToLower(title) {
+		var s string
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			s = string(r)
+		case r == '\'' || r == '’':
+			// Don't split "grandma's" in two.
+			continue
+		default:
+			s = slugLetters[r]
+		}
+		if s == "" {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(s)
+	}
+
+	var slug = b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	if strings.Trim(slug, "0123456789") == "" {
+		slug = strings.TrimSuffix("recipe-"+slug, "-")
+	}
+	return slug
+}
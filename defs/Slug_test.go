@@ -0,0 +1,28 @@
+package defs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	var cases = map[string]string{
+		"Grandma's Banana Bread!": "grandmas-banana-bread",
+		"  Crème Brûlée  ":        "creme-brulee",
+		"Mac & Cheese (Baked)":    "mac-cheese-baked",
+		"1984":                    "recipe-1984",
+		"???":                     "recipe",
+		"":                        "recipe",
+		"7-Layer Dip":             "7-layer-dip",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	var long = Slugify(strings.Repeat("banana bread ", 20))
+	if len(long) > maxSlugLength || strings.HasSuffix(long, "-") {
+		t.Errorf("long slug not trimmed: %q", long)
+	}
+}
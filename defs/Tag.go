@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import "strings"
+
+// Tag describes a tag and how many recipes use it.
+type Tag struct {
+	Tag         string `json:"tag"`
+	Count       int    `json:"count"`
+	Description string `json:"description"`
+	// A CSS color like "#a0c4ff", or empty for the default.
+	Color string `json:"color"`
+	// Other names that find this tag in a search.
+	Synonyms []string `json:"synonyms"`
+	// Broader tags this one falls under, like "italian" for "pasta".
+	Parents []string `json:"parents"`
+}
+
+// Values for the form argument of NormalizeTag.
+const (
+	TagFormAsWritten = ""
+	TagFormSingular  = "singular"
+	TagFormPlural    = "plural"
+)
+
+// NormalizeTag cleans up a tag so it is always written the same way: lower
+// case with single spaces, and with the last word made singular or plural if
+// form asks for it.
+func NormalizeTag(tag string, form string) string {
+	var words = strings.Fields(strings.ToLower(tag))
+	if len(words) == 0 {
+		return ""
+	}
+	var last = len(words) - 1
+	switch form {
+	case TagFormSingular:
+		words[last] = Singular(words[last])
+	case TagFormPlural:
+		words[last] = Plural(words[last])
+	}
+	return strings.Join(words, " ")
+}
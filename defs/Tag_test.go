@@ -0,0 +1,19 @@
+package defs
+
+import "testing"
+
+func TestNormalizeTag(t *testing.T) {
+	var cases = []struct{ in, form, want string }{
+		{"  Dessert ", TagFormAsWritten, "dessert"},
+		{"Chocolate   Chip\tCookies", TagFormAsWritten, "chocolate chip cookies"},
+		{"Chocolate Chip Cookies", TagFormSingular, "chocolate chip cookie"},
+		{"dessert", TagFormPlural, "desserts"},
+		{"   ", TagFormPlural, ""},
+	}
+	for _, c := range cases {
+		if got := NormalizeTag(c.in, c.form); got != c.want {
+			t.Errorf("NormalizeTag(%q, %q) = %q, want %q", c.in, c.form, got,
+				c.want)
+		}
+	}
+}
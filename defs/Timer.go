@@ -0,0 +1,22 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+// Timer is a length of time called out in a recipe's directions, like
+// "25 minutes" for baking.
+type Timer struct {
+	// What it's timing, if it has a name.
+	Name     string `json:"name"`
+	Quantity string `json:"quantity"`
+	Unit     string `json:"unit"`
+}
+
+// Cookware is a pot, pan, or tool called for in a recipe's directions.
+type Cookware struct {
+	Name string `json:"name"`
+	// How many are needed, if it says.
+	Quantity string `json:"quantity"`
+}
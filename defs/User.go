@@ -22,3 +22,23 @@ type User struct {
 	// Fields from other tables.
 	RecipesAuthored int `json:"recipes_authored"`
 }
+
+// DeletedUser says what went with a deleted user.
+type DeletedUser struct {
+	// How many of their recipes were deleted, or given to another user.
+	Recipes    int `json:"recipes"`
+	Reassigned int `json:"reassigned"`
+	// The photos and attachments of deleted recipes.
+	PhotoIDs      []int `json:"photo_ids"`
+	AttachmentIDs []int `json:"attachment_ids"`
+}
+
+// Account is everything kept about a user, for them to take with them.
+type Account struct {
+	User    User
+	Recipes []Recipe
+	// Where each of their recipes is in its history.
+	Revisions []BackupRevision
+	// The ratings they've given recipes, theirs or anyone's.
+	Ratings []BackupRating
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ */
+
+package defs
+
+import "strings"
+
+// Words ending in "ie" that would otherwise lose it when made singular.
+var ieWords = map[string]bool{
+	"brownie": true, "calorie": true, "cookie": true, "hoagie": true,
+	"pie": true, "pastie": true, "smoothie": true, "veggie": true,
+}
+
+// Singular makes a best effort to strip an English plural ending from a lower
+// case word, so "tomatoes" and "tomato" compare equal.
+func Singular(w string) string {
+	if strings.HasSuffix(w, "ies") && ieWords[w[:len(w)-1]] {
+		return w[:len(w)-1]
+	}
+	switch {
+	case len(w) <= 3:
+		return w
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "oes"), strings.HasSuffix(w, "ches"),
+		strings.HasSuffix(w, "shes"), strings.HasSuffix(w, "sses"),
+		strings.HasSuffix(w, "xes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"),
+		strings.HasSuffix(w, "is"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// Plural makes a best effort to give the plural of a lower case word.
+func Plural(w string) string {
+	w = Singular(w)
+	switch {
+	case w == "":
+		return w
+	case strings.HasSuffix(w, "y") && len(w) > 1 &&
+		!strings.ContainsAny(w[len(w)-2:len(w)-1], "aeiou"):
+		return w[:len(w)-1] + "ies"
+	case strings.HasSuffix(w, "s"), strings.HasSuffix(w, "x"),
+		strings.HasSuffix(w, "ch"), strings.HasSuffix(w, "sh"),
+		strings.HasSuffix(w, "o"):
+		return w + "es"
+	}
+	return w + "s"
+}
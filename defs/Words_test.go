@@ -0,0 +1,37 @@
+package defs
+
+import "testing"
+
+func TestSingularPlural(t *testing.T) {
+	var pairs = map[string]string{
+		"cookie":  "cookies",
+		"berry":   "berries",
+		"tomato":  "tomatoes",
+		"peach":   "peaches",
+		"dessert": "desserts",
+		"pie":     "pies",
+		"box":     "boxes",
+		"key":     "keys",
+		"egg":     "eggs",
+	}
+	for one, many := range pairs {
+		if got := Singular(many); got != one {
+			t.Errorf("Singular(%q) = %q, want %q", many, got, one)
+		}
+		if got := Singular(one); got != one {
+			t.Errorf("Singular(%q) = %q, want %q", one, got, one)
+		}
+		if got := Plural(one); got != many {
+			t.Errorf("Plural(%q) = %q, want %q", one, got, many)
+		}
+		if got := Plural(many); got != many {
+			t.Errorf("Plural(%q) = %q, want %q", many, got, many)
+		}
+	}
+	// Words that only look plural.
+	for _, w := range []string{"hummus", "couscous", "asparagus"} {
+		if got := Singular(w); got != w {
+			t.Errorf("Singular(%q) = %q", w, got)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file compares a recipe to the one it was forked from.
+ */
+
+package diff
+
+import "github.com/rwestlund/recipes/defs"
+
+// Recipes describes what changed from parent to r. The Parent field of the
+// result is left for the caller.
+func Recipes(parent *defs.Recipe, r *defs.Recipe) *defs.RecipeDiff {
+	var d = defs.RecipeDiff{
+		Fields:      []defs.FieldDiff{},
+		TagsAdded:   []string{},
+		TagsRemoved: []string{},
+	}
+	var fields = []struct {
+		name     string
+		old, new string
+	}{
+		{"title", parent.Title, r.Title},
+		{"summary", parent.Summary, r.Summary},
+		{"amount", parent.Amount, r.Amount},
+		{"time", parent.Time, r.Time},
+		{"oven", parent.Oven, r.Oven},
+		{"source", parent.Source, r.Source},
+		{"notes", parent.Notes, r.Notes},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			d.Fields = append(d.Fields,
+				defs.FieldDiff{Field: f.name, Old: f.old, New: f.new})
+		}
+	}
+
+	d.Ingredients = Lines(ingredientLines(parent.Ingredients),
+		ingredientLines(r.Ingredients))
+	d.Directions = Lines(directionLines(parent.Directions),
+		directionLines(r.Directions))
+
+	var had = make(map[string]bool, len(parent.Tags))
+	for _, tag := range parent.Tags {
+		had[tag] = true
+	}
+	var has = make(map[string]bool, len(r.Tags))
+	for _, tag := range r.Tags {
+		has[tag] = true
+		if !had[tag] {
+			d.TagsAdded = append(d.TagsAdded, tag)
+		}
+	}
+	for _, tag := range parent.Tags {
+		if !has[tag] {
+			d.TagsRemoved = append(d.TagsRemoved, tag)
+		}
+	}
+	return &d
+}
+
+// ingredientLines flattens ingredient sections, with headings as lines of
+// their own.
+func ingredientLines(sections defs.IngredientSections) []defs.DiffLine {
+	var lines []defs.DiffLine
+	for _, s := range sections {
+		if s.Heading != "" {
+			lines = append(lines, defs.DiffLine{Text: s.Heading, Heading: true})
+		}
+		for _, item := range s.Items {
+			lines = append(lines, defs.DiffLine{Text: item})
+		}
+	}
+	return lines
+}
+
+// directionLines flattens direction sections, with headings as lines of
+// their own.
+func directionLines(sections defs.DirectionSections) []defs.DiffLine {
+	var lines []defs.DiffLine
+	for _, s := range sections {
+		if s.Heading != "" {
+			lines = append(lines, defs.DiffLine{Text: s.Heading, Heading: true})
+		}
+		for _, item := range s.Items {
+			lines = append(lines, defs.DiffLine{Text: item.Text})
+		}
+	}
+	return lines
+}
+
+// Lines compares two lists of lines, marking each as kept, added, or removed
+// so that as many as possible are kept. The Op fields of the arguments are
+// ignored.
+func Lines(a []defs.DiffLine, b []defs.DiffLine) []defs.DiffLine {
+	var same = func(x, y defs.DiffLine) bool {
+		return x.Text == y.Text && x.Heading == y.Heading
+	}
+	// common[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	var common = make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if same(a[i], b[j]) {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var out = make([]defs.DiffLine, 0, len(a)+len(b))
+	var emit = func(line defs.DiffLine, op string) {
+		line.Op = op
+		out = append(out, line)
+	}
+	var i, j int
+	for i < len(a) && j < len(b) {
+		switch {
+		case same(a[i], b[j]):
+			emit(a[i], defs.DiffSame)
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			emit(a[i], defs.DiffRemoved)
+			i++
+		default:
+			emit(b[j], defs.DiffAdded)
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		emit(a[i], defs.DiffRemoved)
+	}
+	for ; j < len(b); j++ {
+		emit(b[j], defs.DiffAdded)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestRecipes(t *testing.T) {
+	var parent = defs.Recipe{Title: "Cookies", Time: "30 minutes",
+		Tags: []string{"dessert", "baked"},
+		Ingredients: defs.IngredientSections{{Items: []string{
+			"1 cup butter", "1 cup sugar", "2 cups flour"}}}}
+	var child = defs.Recipe{Title: "Cookies", Time: "40 minutes",
+		Tags: []string{"dessert", "chocolate"},
+		Ingredients: defs.IngredientSections{
+			{Items: []string{"1 cup butter", "1/2 cup sugar", "2 cups flour"}},
+			{Heading: "Topping", Items: []string{"chocolate chips"}},
+		}}
+	var d = Recipes(&parent, &child)
+
+	var wantFields = []defs.FieldDiff{
+		{Field: "time", Old: "30 minutes", New: "40 minutes"}}
+	if !reflect.DeepEqual(d.Fields, wantFields) {
+		t.Errorf("fields: got %+v", d.Fields)
+	}
+	var wantIngredients = []defs.DiffLine{
+		{Op: defs.DiffSame, Text: "1 cup butter"},
+		{Op: defs.DiffRemoved, Text: "1 cup sugar"},
+		{Op: defs.DiffAdded, Text: "1/2 cup sugar"},
+		{Op: defs.DiffSame, Text: "2 cups flour"},
+		{Op: defs.DiffAdded, Text: "Topping", Heading: true},
+		{Op: defs.DiffAdded, Text: "chocolate chips"},
+	}
+	if !reflect.DeepEqual(d.Ingredients, wantIngredients) {
+		t.Errorf("ingredients: got %+v", d.Ingredients)
+	}
+	if !reflect.DeepEqual(d.TagsAdded, []string{"chocolate"}) ||
+		!reflect.DeepEqual(d.TagsRemoved, []string{"baked"}) {
+		t.Errorf("tags: got +%v -%v", d.TagsAdded, d.TagsRemoved)
+	}
+	if len(d.Directions) != 0 {
+		t.Errorf("directions: got %+v", d.Directions)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes everything kept about a user as a zip file.
+ */
+
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/markdown"
+)
+
+// Account writes a zip file of everything kept about a user, with the files
+// of their recipes' photos and attachments from files. It has:
+//
+//	profile.json                the user
+//	revisions.json              where each recipe is in its history
+//	ratings.json                the ratings they've given recipes
+//	recipes/<slug>.json         each recipe, as the API returns it
+//	recipes/<slug>.md           and as Markdown
+//	photos/<slug>/<id>.jpg      each photo
+//	attachments/<slug>/<name>   each attachment
+//
+// Files missing from the store are left out.
+func Account(w io.Writer, a *defs.Account, files blob.Store) error {
+	var z = zip.NewWriter(w)
+	var err = writeJSON(z, "profile.json", &a.User)
+	if err != nil {
+		return err
+	}
+	err = writeJSON(z, "revisions.json", &a.Revisions)
+	if err != nil {
+		return err
+	}
+	err = writeJSON(z, "ratings.json", &a.Ratings)
+	if err != nil {
+		return err
+	}
+	for i := range a.Recipes {
+		var r = &a.Recipes[i]
+		var name = r.Slug
+		if name == "" {
+			name = strconv.Itoa(r.ID)
+		}
+		err = writeJSON(z, "recipes/"+name+".json", r)
+		if err != nil {
+			return err
+		}
+		err = writeFile(z, "recipes/"+name+".md", markdown.Write(r))
+		if err != nil {
+			return err
+		}
+		for _, p := range r.Photos {
+			err = copyBlob(z, "photos/"+name+"/"+strconv.Itoa(p.ID)+".jpg",
+				files, blob.PhotoKey(p.ID, "full.jpg"))
+			if err != nil {
+				return err
+			}
+		}
+		for _, at := range r.Attachments {
+			// IDs keep attachments with the same name apart.
+			var file = strconv.Itoa(at.ID) + "-" + path.Base("/"+at.Filename)
+			err = copyBlob(z, "attachments/"+name+"/"+file,
+				files, blob.AttachmentKey(at.ID))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return z.Close()
+}
+
+// writeJSON adds v to a zip file as indented JSON.
+func writeJSON(z *zip.Writer, name string, v interface{}) error {
+	var data, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(z, name, append(data, '\n'))
+}
+
+// writeFile adds a file to a zip file.
+func writeFile(z *zip.Writer, name string, data []byte) error {
+	var f, err = z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// copyBlob adds the blob under key to a zip file, if there is one.
+func copyBlob(z *zip.Writer, name string, files blob.Store, key string) error {
+	var data, err = files.Get(key)
+	if err == blob.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return writeFile(z, name, data)
+}
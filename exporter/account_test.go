@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestAccount(t *testing.T) {
+	var store, err = blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put("photos/3/full.jpg", []byte("jpeg"), "image/jpeg")
+	store.Put("attachments/5", []byte("%PDF"), "application/pdf")
+	var account = defs.Account{
+		User: defs.User{ID: 2, Email: "jo@example.com", Name: "Jo"},
+		Recipes: []defs.Recipe{{
+			ID:    8,
+			Title: "Bread",
+			Slug:  "bread",
+			Ingredients: defs.IngredientSections{{Items: []string{
+				"3 cups flour"}}},
+			// Photo 4's image is missing, which shouldn't stop the export.
+			Photos: []defs.Photo{{ID: 3, Cover: true}, {ID: 4}},
+			Attachments: []defs.Attachment{
+				{ID: 5, Filename: "../shaping.pdf"}},
+		}},
+		Revisions: []defs.BackupRevision{{RecipeID: 8, Revision: 2}},
+		Ratings: []defs.BackupRating{{RecipeID: 8, UserID: 2, Rating: 4},
+			{RecipeID: 11, UserID: 2, Rating: 3}},
+	}
+	var buf bytes.Buffer
+	err = Account(&buf, &account, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files = make(map[string]string)
+	for _, f := range z.File {
+		var r, err = f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	if len(files) != 7 {
+		t.Errorf("got files %v", files)
+	}
+	var user defs.User
+	err = json.Unmarshal([]byte(files["profile.json"]), &user)
+	if err != nil || user.Email != "jo@example.com" {
+		t.Errorf("got profile %q, %v", files["profile.json"], err)
+	}
+	if !strings.Contains(files["revisions.json"], `"revision": 2`) {
+		t.Errorf("got revisions %q", files["revisions.json"])
+	}
+	if !strings.Contains(files["ratings.json"], `"recipe_id": 11`) {
+		t.Errorf("got ratings %q", files["ratings.json"])
+	}
+	if !strings.Contains(files["recipes/bread.json"], `"title": "Bread"`) {
+		t.Errorf("got recipe %q", files["recipes/bread.json"])
+	}
+	if !strings.Contains(files["recipes/bread.md"], "- 3 cups flour") {
+		t.Errorf("got Markdown %q", files["recipes/bread.md"])
+	}
+	if files["photos/bread/3.jpg"] != "jpeg" {
+		t.Errorf("got photo %q", files["photos/bread/3.jpg"])
+	}
+	if files["attachments/bread/5-shaping.pdf"] != "%PDF" {
+		t.Errorf("got attachment %q", files["attachments/bread/5-shaping.pdf"])
+	}
+}
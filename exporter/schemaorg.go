@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes recipes as schema.org JSON-LD.
+ */
+
+// Package exporter writes recipes in formats other tools understand.
+package exporter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// The date format schema.org uses.
+const schemaDate = "2006-01-02"
+
+type schemaPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type schemaStep struct {
+	Type  string `json:"@type"`
+	Text  string `json:"text"`
+	Image string `json:"image,omitempty"`
+}
+
+type schemaSection struct {
+	Type            string       `json:"@type"`
+	Name            string       `json:"name"`
+	ItemListElement []schemaStep `json:"itemListElement"`
+}
+
+type schemaRecipe struct {
+	Context            string        `json:"@context"`
+	Type               string        `json:"@type"`
+	URL                string        `json:"url"`
+	Name               string        `json:"name"`
+	Description        string        `json:"description,omitempty"`
+	Author             *schemaPerson `json:"author,omitempty"`
+	DatePublished      string        `json:"datePublished,omitempty"`
+	DateModified       string        `json:"dateModified,omitempty"`
+	Image              []string      `json:"image,omitempty"`
+	RecipeYield        string        `json:"recipeYield,omitempty"`
+	TotalTime          string        `json:"totalTime,omitempty"`
+	Keywords           string        `json:"keywords,omitempty"`
+	RecipeIngredient   []string      `json:"recipeIngredient"`
+	RecipeInstructions []interface{} `json:"recipeInstructions"`
+	IsBasedOn          string        `json:"isBasedOn,omitempty"`
+}
+
+// RecipePath returns where a recipe's page is on the site: under its slug, or
+// its ID if it has none.
+func RecipePath(r *defs.Recipe) string {
+	if r.Slug == "" {
+		return "/recipes/" + strconv.Itoa(r.ID)
+	}
+	return "/recipes/" + r.Slug
+}
+
+// JSONLD writes a recipe as a schema.org Recipe in JSON-LD. URLs are made
+// absolute with base, like "https://recipes.example.com".
+func JSONLD(r *defs.Recipe, base string) ([]byte, error) {
+	var s = schemaRecipe{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		URL:                base + RecipePath(r),
+		Name:               r.Title,
+		Description:        r.Summary,
+		RecipeYield:        r.Amount,
+		Keywords:           strings.Join(r.Tags, ", "),
+		RecipeIngredient:   r.Ingredients.Lines(),
+		RecipeInstructions: []interface{}{},
+		IsBasedOn:          r.Source,
+	}
+	if s.RecipeIngredient == nil {
+		s.RecipeIngredient = []string{}
+	}
+	if r.AuthorName != "" {
+		s.Author = &schemaPerson{Type: "Person", Name: r.AuthorName}
+	}
+	if !r.CreationDate.IsZero() {
+		s.DatePublished = r.CreationDate.Format(schemaDate)
+	}
+	if !r.ModifiedDate.IsZero() {
+		s.DateModified = r.ModifiedDate.Format(schemaDate)
+	}
+	if r.Minutes > 0 {
+		s.TotalTime = defs.FormatISODuration(r.Minutes)
+	}
+	// The cover photo goes first, since that's the one most tools show.
+	for _, p := range r.Photos {
+		if p.Cover {
+			s.Image = append([]string{base + p.URL}, s.Image...)
+		} else {
+			s.Image = append(s.Image, base+p.URL)
+		}
+	}
+
+	// Attached images can illustrate their steps.
+	var images = make(map[int]string)
+	for _, a := range r.Attachments {
+		if strings.HasPrefix(a.ContentType, "image/") {
+			images[a.ID] = base + a.URL
+		}
+	}
+	var step = func(d defs.Direction) schemaStep {
+		var st = schemaStep{Type: "HowToStep", Text: d.Text}
+		for _, id := range d.Attachments {
+			if images[id] != "" {
+				st.Image = images[id]
+				break
+			}
+		}
+		return st
+	}
+	for _, section := range r.Directions {
+		if section.Heading == "" {
+			for _, d := range section.Items {
+				s.RecipeInstructions = append(s.RecipeInstructions, step(d))
+			}
+			continue
+		}
+		var hs = schemaSection{Type: "HowToSection", Name: section.Heading,
+			ItemListElement: []schemaStep{}}
+		for _, d := range section.Items {
+			hs.ItemListElement = append(hs.ItemListElement, step(d))
+		}
+		s.RecipeInstructions = append(s.RecipeInstructions, hs)
+	}
+	return json.Marshal(s)
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/importer"
+)
+
+func TestJSONLDRoundTrip(t *testing.T) {
+	var r = defs.Recipe{
+		Title:        "Chocolate Layer Cake",
+		Slug:         "chocolate-layer-cake",
+		Summary:      "For birthdays.",
+		Amount:       "12 slices",
+		Time:         "1 hour 30 minutes",
+		Minutes:      90,
+		AuthorName:   "Jane Baker",
+		CreationDate: time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC),
+		Tags:         []string{"cake", "dessert"},
+		Ingredients: defs.IngredientSections{{Items: []string{
+			"2 cups flour", "1 cup cocoa"}}},
+		Directions: defs.DirectionSections{
+			{Items: []defs.Direction{{Text: "Preheat oven.",
+				Attachments: []int{7}}}},
+			{Heading: "For the frosting", Items: []defs.Direction{
+				{Text: "Beat butter and cocoa."}}},
+		},
+		Photos: []defs.Photo{
+			{ID: 1, URL: "/api/photos/1/full.jpg"},
+			{ID: 2, Cover: true, URL: "/api/photos/2/full.jpg"},
+		},
+		Attachments: []defs.Attachment{{ID: 7, ContentType: "image/png",
+			URL: "/api/attachments/7"}},
+	}
+	var data, err = JSONLD(&r, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Our own importer should read back what matters.
+	var page = `<script type="application/ld+json">` + string(data) +
+		`</script>`
+	back, err := importer.FromHTML([]byte(page), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Title != r.Title || back.Summary != r.Summary ||
+		back.Amount != r.Amount || back.Time != r.Time ||
+		back.Source != r.AuthorName ||
+		!reflect.DeepEqual(back.Tags, r.Tags) ||
+		!reflect.DeepEqual(back.Ingredients, r.Ingredients) ||
+		len(back.Directions) != 2 ||
+		back.Directions[1].Heading != "For the frosting" {
+		t.Errorf("round trip lost something:\n%s\n%+v", data, back)
+	}
+
+	var want = `"image":["https://example.com/api/photos/2/full.jpg",` +
+		`"https://example.com/api/photos/1/full.jpg"]`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("cover photo not first: %s", data)
+	}
+	if !strings.Contains(string(data), `"datePublished":"2017-03-04"`) {
+		t.Errorf("missing date: %s", data)
+	}
+}
+
+func TestJSONLDURL(t *testing.T) {
+	// Recipes saved before slugs existed are found by ID.
+	var data, err = JSONLD(&defs.Recipe{ID: 12, Title: "Toast"},
+		"https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"url":"https://example.com/recipes/12"`) {
+		t.Errorf("wrong URL in %s", data)
+	}
+}
@@ -6,7 +6,7 @@ require (
 	cloud.google.com/go v0.85.0 // indirect
 	github.com/gorilla/mux v1.8.0
 	github.com/lib/pq v1.10.2
-	golang.org/x/net v0.0.0-20210614182718-04defd469f4e // indirect
+	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
 	golang.org/x/oauth2 v0.0.0-20210628180205-a41e5a781914
 	gopkg.in/guregu/null.v3 v3.5.0
 )
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file has what the importers for other recipe apps' exports share.
+ */
+
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// MaxArchiveSize is the largest export we'll read, in bytes, both as
+// uploaded and after decompressing each file in it.
+const MaxArchiveSize = 64 << 20
+
+// ErrTooLarge is returned for exports bigger than MaxArchiveSize.
+var ErrTooLarge = errors.New("export is too large")
+
+// Imported is one recipe read from another app's export.
+type Imported struct {
+	// What could be read of the recipe. It's never nil, but may have only a
+	// title if Err is set.
+	Recipe *defs.Recipe
+	// Who the export says wrote it, like a name or email, or empty.
+	Author string
+	// Images of the recipe, cover first.
+	Photos [][]byte
+	// Why the recipe couldn't be read, if it couldn't.
+	Err error
+}
+
+// Apps maps the names of apps we import from to their importers. Each reads
+// an export and returns the recipes in it. An error means the export as a
+// whole couldn't be read; a recipe that couldn't be has its own Err.
+var Apps = map[string]func(data []byte) ([]Imported, error){
+	"paprika":    Paprika,
+	"mealie":     Mealie,
+	"mealmaster": MealMaster,
+}
+
+// newRecipe returns an empty recipe with lists ready to fill.
+func newRecipe() *defs.Recipe {
+	return &defs.Recipe{
+		Tags:        []string{},
+		Ingredients: defs.IngredientSections{},
+		Directions:  defs.DirectionSections{},
+	}
+}
+
+// readAll reads r, failing if it's bigger than MaxArchiveSize.
+func readAll(r io.Reader) ([]byte, error) {
+	var data, err = ioutil.ReadAll(io.LimitReader(r, MaxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxArchiveSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// isZip reports whether data looks like a ZIP archive.
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// zipFiles reads every file in a ZIP archive, by name.
+func zipFiles(data []byte) (map[string][]byte, error) {
+	var archive, err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var files = make(map[string][]byte, len(archive.File))
+	var total = 0
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		var r, err = f.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := readAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		total += len(contents)
+		if total > MaxArchiveSize {
+			return nil, ErrTooLarge
+		}
+		files[f.Name] = contents
+	}
+	return files, nil
+}
+
+// splitLines returns the lines of s that aren't blank, trimmed.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ingredientSections groups ingredient lines into sections. Apps without
+// sections often have lines like "For the sauce:" instead, so those become
+// headings.
+func ingredientSections(lines []string) defs.IngredientSections {
+	var sections = defs.IngredientSections{}
+	for _, line := range lines {
+		if isHeading(line) {
+			sections = append(sections, defs.IngredientSection{
+				Heading: strings.TrimSuffix(line, ":"), Items: []string{}})
+			continue
+		}
+		if len(sections) == 0 {
+			sections = append(sections, defs.IngredientSection{
+				Items: []string{}})
+		}
+		var last = &sections[len(sections)-1]
+		last.Items = append(last.Items, line)
+	}
+	return sections
+}
+
+// directionSection makes a section of directions from lines of text.
+func directionSection(heading string, steps []string) defs.DirectionSection {
+	var section = defs.DirectionSection{Heading: heading,
+		Items: make([]defs.Direction, len(steps))}
+	for i, step := range steps {
+		section.Items[i] = defs.Direction{Text: step}
+	}
+	return section
+}
+
+// isHeading reports whether an ingredient line is a heading, like
+// "For the sauce:". Those end with a colon and have no amounts.
+func isHeading(line string) bool {
+	return strings.HasSuffix(line, ":") &&
+		!strings.ContainsAny(line, "0123456789½⅓⅔¼¾⅛")
+}
+
+// readTime reads a time that may be an ISO 8601 duration, like "PT1H",
+// writing it the way people do.
+func readTime(s string) string {
+	if minutes, ok := defs.ParseISODuration(s); ok {
+		return defs.FormatMinutes(minutes)
+	}
+	return strings.TrimSpace(s)
+}
+
+// joinTime gives the total of a prep time and a cook time, like "1 hour 10
+// minutes". If either can't be read, it returns whichever is set.
+func joinTime(prep, cook string) string {
+	var p, c = defs.ParseMinutes(prep), defs.ParseMinutes(cook)
+	if p != 0 && c != 0 {
+		return defs.FormatMinutes(p + c)
+	}
+	if cook != "" {
+		return cook
+	}
+	return prep
+}
@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// paprikaArchive builds a .paprikarecipes file from recipes.
+func paprikaArchive(t *testing.T, recipes map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	var archive = zip.NewWriter(&buf)
+	for name, recipe := range recipes {
+		var w, err = archive.Create(name + ".paprikarecipe")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var z = gzip.NewWriter(w)
+		err = json.NewEncoder(z).Encode(recipe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z.Close()
+	}
+	var err = archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPaprika(t *testing.T) {
+	var data = paprikaArchive(t, map[string]interface{}{
+		"Banana Bread": map[string]interface{}{
+			"name":             "Banana Bread",
+			"description":      "Moist.",
+			"ingredients":      "3 bananas\n\nFor the topping:\n2 tbsp sugar\n",
+			"directions":       "Mash.\nBake.",
+			"notes":            "Freezes well.",
+			"nutritional_info": "200 calories",
+			"servings":         "1 loaf",
+			"prep_time":        "10 min",
+			"cook_time":        "1 hour",
+			"source":           "Grandma",
+			"categories":       []string{"Bread", "Breakfast"},
+			"photo_data":       base64.StdEncoding.EncodeToString([]byte("cover")),
+			"photos": []map[string]string{
+				{"data": base64.StdEncoding.EncodeToString([]byte("extra"))},
+			},
+		},
+		"Broken": "not a recipe",
+		"Bad Photo": map[string]interface{}{
+			"name":        "Bad Photo",
+			"ingredients": "1 egg",
+			"photo_data":  "not base64!",
+			"photos": []map[string]string{
+				{"data": base64.StdEncoding.EncodeToString([]byte("good"))},
+			},
+		},
+	})
+	var list, err = Paprika(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("got %d recipes, want 3", len(list))
+	}
+
+	var bread = list[1]
+	if bread.Err != nil {
+		t.Fatal(bread.Err)
+	}
+	var want = defs.Recipe{
+		Title:   "Banana Bread",
+		Summary: "Moist.",
+		Amount:  "1 loaf",
+		Time:    "1 hour 10 minutes",
+		Source:  "Grandma",
+		Tags:    []string{"Bread", "Breakfast"},
+		Ingredients: defs.IngredientSections{
+			{Items: []string{"3 bananas"}},
+			{Heading: "For the topping", Items: []string{"2 tbsp sugar"}},
+		},
+		Directions: defs.DirectionSections{{Items: []defs.Direction{
+			{Text: "Mash."}, {Text: "Bake."},
+		}}},
+		Notes: "Freezes well.\n\nNutrition:\n200 calories",
+	}
+	if !reflect.DeepEqual(*bread.Recipe, want) {
+		t.Errorf("got %+v, want %+v", *bread.Recipe, want)
+	}
+	var photos = [][]byte{[]byte("cover"), []byte("extra")}
+	if !reflect.DeepEqual(bread.Photos, photos) {
+		t.Errorf("got photos %q, want %q", bread.Photos, photos)
+	}
+
+	// A broken entry is reported by its file name.
+	if list[2].Err == nil || list[2].Recipe.Title != "Broken" {
+		t.Errorf("got %q, %v for a broken entry", list[2].Recipe.Title,
+			list[2].Err)
+	}
+
+	// A photo that can't be decoded is dropped, but the recipe is kept.
+	var bad = list[0]
+	if bad.Err != nil || bad.Recipe.Title != "Bad Photo" ||
+		len(bad.Recipe.Ingredients.Lines()) != 1 {
+		t.Errorf("got %+v, %v for a recipe with a bad photo", *bad.Recipe,
+			bad.Err)
+	}
+	if !reflect.DeepEqual(bad.Photos, [][]byte{[]byte("good")}) {
+		t.Errorf("got photos %q, want only the good one", bad.Photos)
+	}
+}
+
+func TestMealie(t *testing.T) {
+	var data, err = ioutil.ReadFile("testdata/mealie.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := Mealie(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d recipes, want 2", len(list))
+	}
+	var want = []defs.Recipe{{
+		Title:   "Tomato Soup",
+		Summary: "Smooth and bright.",
+		Amount:  "4 servings",
+		Time:    "40 minutes",
+		Source:  "https://example.com/soup",
+		Tags:    []string{"Soup", "Dinner"},
+		Ingredients: defs.IngredientSections{
+			{Items: []string{"800 g tomatoes", "1 tbsp olive oil, extra virgin"}},
+			{Heading: "Garnish", Items: []string{"basil leaves"}},
+		},
+		Directions: defs.DirectionSections{
+			{Items: []defs.Direction{{Text: "Simmer the tomatoes."}}},
+			{Heading: "To serve", Items: []defs.Direction{
+				{Text: "Blend and garnish."}}},
+		},
+		Notes: "Storage\nKeeps three days.",
+	}, {
+		Title:       "Old Toast",
+		Amount:      "2",
+		Tags:        []string{},
+		Ingredients: defs.IngredientSections{{Items: []string{"2 slices bread"}}},
+		Directions: defs.DirectionSections{{Items: []defs.Direction{
+			{Text: "Toast it."}}}},
+	}}
+	for i := range want {
+		if list[i].Err != nil {
+			t.Errorf("%s: %v", want[i].Title, list[i].Err)
+		}
+		if !reflect.DeepEqual(*list[i].Recipe, want[i]) {
+			t.Errorf("got %+v, want %+v", *list[i].Recipe, want[i])
+		}
+	}
+}
+
+func TestMealMaster(t *testing.T) {
+	var data, err = ioutil.ReadFile("testdata/mealmaster.mmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := MealMaster(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("got %d recipes, want 3", len(list))
+	}
+
+	var want = defs.Recipe{
+		Title:  "Simple Crepes",
+		Amount: "12 crepes",
+		Tags:   []string{"Breakfast", "Desserts"},
+		Ingredients: defs.IngredientSections{
+			{Items: []string{"1 1/2 cup Flour", "1 tsp Salt", "2 large Eggs",
+				"1/2 cup Sugar", "1 cup Milk"}},
+			{Heading: "FILLING", Items: []string{
+				"1 can Cherry pie filling drained"}},
+		},
+		Directions: defs.DirectionSections{{Items: []defs.Direction{
+			{Text: "Whisk everything together until smooth, then rest the " +
+				"batter for an hour."},
+			{Text: "Cook thin crepes in a buttered pan."},
+		}}},
+	}
+	if list[0].Err != nil {
+		t.Error(list[0].Err)
+	}
+	if !reflect.DeepEqual(*list[0].Recipe, want) {
+		t.Errorf("got %+v, want %+v", *list[0].Recipe, want)
+	}
+	if list[0].Author != "Grandma Jo" {
+		t.Errorf("got author %q, want Grandma Jo", list[0].Author)
+	}
+
+	var toast = list[1].Recipe
+	if toast.Title != "Plain Toast" || toast.Amount != "1" ||
+		len(toast.Tags) != 0 ||
+		!reflect.DeepEqual(toast.Ingredients.Lines(), []string{"1 slice Bread"}) {
+		t.Errorf("got %+v", *toast)
+	}
+
+	if list[2].Err == nil {
+		t.Error("expected an error for a recipe without a title")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file fetches pages to import recipes from.
+ */
+
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// MaxPageSize is the largest page Fetch will read, in bytes.
+const MaxPageSize = 4 << 20
+
+// AllowPrivateAddresses lets Fetch connect to loopback and private network
+// addresses. It's off so users can't make the server probe its own network,
+// and meant to be turned on only in tests.
+var AllowPrivateAddresses = false
+
+// ErrPrivateAddress is returned when a URL leads to an address Fetch won't
+// connect to.
+var ErrPrivateAddress = errors.New("refusing to fetch from a private address")
+
+// checkAddress refuses connections to private addresses. It runs after DNS
+// lookup, so names that resolve to them are caught too.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	if AllowPrivateAddresses {
+		return nil
+	}
+	var host, _, err = net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	var ip = net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return ErrPrivateAddress
+	}
+	for _, block := range privateBlocks {
+		if block.Contains(ip) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// Private network ranges, from RFC 1918 and RFC 4193.
+var privateBlocks = func() []*net.IPNet {
+	var blocks []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12",
+		"192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		var _, block, _ = net.ParseCIDR(cidr)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}()
+
+// The client for fetching pages. It never uses a proxy, since checkAddress
+// would only see the proxy's address, and the proxy would connect anywhere.
+var client = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: checkAddress,
+		}).DialContext,
+	},
+}
+
+// Fetch downloads a web page to import a recipe from. Only http and https
+// URLs are accepted.
+func Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	var u, err = url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("unsupported URL scheme: " + u.Scheme)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, errors.New("fetching " + u.String() + ": status " +
+			strconv.Itoa(res.StatusCode))
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxPageSize {
+		return nil, errors.New("page is too large")
+	}
+	return data, nil
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file imports Mealie exports.
+ */
+
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// mealieName is a tag, category, unit, or food, which Mealie writes as an
+// object.
+type mealieName struct {
+	Name string `json:"name"`
+}
+
+// mealieIngredient is an ingredient as newer versions of Mealie write it.
+// Older versions write plain strings.
+type mealieIngredient struct {
+	// Starts a new section.
+	Title        string      `json:"title"`
+	Display      string      `json:"display"`
+	OriginalText string      `json:"originalText"`
+	Note         string      `json:"note"`
+	Quantity     float64     `json:"quantity"`
+	Unit         *mealieName `json:"unit"`
+	Food         *mealieName `json:"food"`
+}
+
+// mealieInstruction is a step. Older versions write plain strings.
+type mealieInstruction struct {
+	// Starts a new section.
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// mealieRecipe is a recipe as Mealie exports it.
+type mealieRecipe struct {
+	Name               string              `json:"name"`
+	Slug               string              `json:"slug"`
+	Description        string              `json:"description"`
+	RecipeYield        json.RawMessage     `json:"recipeYield"`
+	TotalTime          string              `json:"totalTime"`
+	PrepTime           string              `json:"prepTime"`
+	PerformTime        string              `json:"performTime"`
+	CookTime           string              `json:"cookTime"`
+	OrgURL             string              `json:"orgURL"`
+	Tags               []mealieName        `json:"tags"`
+	RecipeCategory     []mealieName        `json:"recipeCategory"`
+	RecipeIngredient   []json.RawMessage   `json:"recipeIngredient"`
+	RecipeInstructions []json.RawMessage   `json:"recipeInstructions"`
+	Notes              []mealieInstruction `json:"notes"`
+}
+
+// Mealie reads recipes exported from Mealie. That may be a JSON recipe, a
+// JSON list of them, or a ZIP archive of JSON files, in which each recipe's
+// photo is "images/original" beside it.
+func Mealie(data []byte) ([]Imported, error) {
+	if !isZip(data) {
+		var list = mealieJSON(data, nil)
+		if len(list) == 0 {
+			return nil, errors.New("no Mealie recipes found")
+		}
+		return list, nil
+	}
+	var files, err = zipFiles(data)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range files {
+		if strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var list []Imported
+	for _, name := range names {
+		// Look for a photo beside the recipe.
+		var dir = path.Dir(name)
+		var photo = func() []byte {
+			for _, ext := range []string{".jpg", ".jpeg", ".png", ".webp"} {
+				if p, ok := files[path.Join(dir, "images", "original"+ext)]; ok {
+					return p
+				}
+			}
+			return nil
+		}
+		list = append(list, mealieJSON(files[name], photo)...)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no Mealie recipes found in archive")
+	}
+	return list, nil
+}
+
+// mealieJSON reads one recipe or a list of them. Anything without a name
+// isn't a recipe, and is left out. If photo isn't nil, it gives the photo of
+// a single recipe.
+func mealieJSON(data []byte, photo func() []byte) []Imported {
+	var recipes []mealieRecipe
+	if json.Unmarshal(data, &recipes) != nil {
+		var one mealieRecipe
+		if err := json.Unmarshal(data, &one); err != nil {
+			return nil
+		}
+		recipes = []mealieRecipe{one}
+	}
+	var list []Imported
+	for _, m := range recipes {
+		if m.Name == "" {
+			continue
+		}
+		var item = mealieRecipeItem(m)
+		if photo != nil && len(recipes) == 1 {
+			if p := photo(); p != nil {
+				item.Photos = [][]byte{p}
+			}
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// mealieRecipeItem maps a Mealie recipe onto ours.
+func mealieRecipeItem(m mealieRecipe) Imported {
+	var item = Imported{Recipe: newRecipe()}
+	var r = item.Recipe
+	r.Title = strings.TrimSpace(m.Name)
+	r.Summary = strings.TrimSpace(m.Description)
+	r.Amount = yieldText(m.RecipeYield)
+	r.Time = readTime(m.TotalTime)
+	if r.Time == "" {
+		var cook = m.PerformTime
+		if cook == "" {
+			cook = m.CookTime
+		}
+		r.Time = joinTime(readTime(m.PrepTime), readTime(cook))
+	}
+	r.Source = m.OrgURL
+	for _, list := range [][]mealieName{m.Tags, m.RecipeCategory} {
+		for _, tag := range list {
+			r.Tags = append(r.Tags, tag.Name)
+		}
+	}
+
+	for _, raw := range m.RecipeIngredient {
+		var line string
+		var ing mealieIngredient
+		if json.Unmarshal(raw, &line) != nil {
+			if err := json.Unmarshal(raw, &ing); err != nil {
+				item.Err = err
+				return item
+			}
+			line = ing.line()
+		}
+		line = strings.TrimSpace(line)
+		if ing.Title != "" || len(r.Ingredients) == 0 {
+			r.Ingredients = append(r.Ingredients, defs.IngredientSection{
+				Heading: strings.TrimSpace(ing.Title), Items: []string{}})
+		}
+		if line != "" {
+			var last = &r.Ingredients[len(r.Ingredients)-1]
+			last.Items = append(last.Items, line)
+		}
+	}
+
+	for _, raw := range m.RecipeInstructions {
+		var step mealieInstruction
+		if json.Unmarshal(raw, &step.Text) != nil {
+			if err := json.Unmarshal(raw, &step); err != nil {
+				item.Err = err
+				return item
+			}
+		}
+		if step.Title != "" || len(r.Directions) == 0 {
+			r.Directions = append(r.Directions,
+				directionSection(strings.TrimSpace(step.Title), nil))
+		}
+		if text := strings.TrimSpace(step.Text); text != "" {
+			var last = &r.Directions[len(r.Directions)-1]
+			last.Items = append(last.Items, defs.Direction{Text: text})
+		}
+	}
+
+	var notes []string
+	for _, note := range m.Notes {
+		var text = strings.TrimSpace(note.Text)
+		if note.Title != "" {
+			text = strings.TrimSpace(note.Title + "\n" + text)
+		}
+		notes = append(notes, text)
+	}
+	r.Notes = strings.Join(notes, "\n\n")
+	return item
+}
+
+// line writes an ingredient as one line, as Mealie shows it.
+func (ing mealieIngredient) line() string {
+	if ing.Display != "" {
+		return ing.Display
+	}
+	if ing.OriginalText != "" {
+		return ing.OriginalText
+	}
+	var parts []string
+	if ing.Quantity != 0 {
+		parts = append(parts, defs.FormatQuantity(ing.Quantity))
+	}
+	if ing.Unit != nil {
+		parts = append(parts, ing.Unit.Name)
+	}
+	if ing.Food != nil {
+		parts = append(parts, ing.Food.Name)
+	}
+	var line = strings.Join(parts, " ")
+	if ing.Note != "" {
+		if line != "" {
+			line += ", "
+		}
+		line += ing.Note
+	}
+	return line
+}
+
+// yieldText reads a yield, which may be a string or a number.
+func yieldText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var n float64
+	if json.Unmarshal(raw, &n) == nil && n != 0 {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return ""
+}
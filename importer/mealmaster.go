@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file imports MealMaster text files.
+ */
+
+package importer
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+var (
+	// The first line of a recipe, like
+	// "MMMMM----- Recipe via Meal-Master (tm) v8.05".
+	mmStart = regexp.MustCompile(`(?i)^(MMMMM|-----).*meal-?master`)
+	// The last line of a recipe.
+	mmEnd = regexp.MustCompile(`^(MMMMM|-----)\s*$`)
+	// A heading within a recipe, like "MMMMM--------FILLING--------".
+	mmHeading = regexp.MustCompile(`^(?:MMMMM|-----)-*\s*([^-].*?)\s*-*$`)
+	// A header line, like "Title: Apple Pie".
+	mmField = regexp.MustCompile(
+		`(?i)^\s*(title|categories|yield|servings)\s*:\s*(.*?)\s*$`)
+	// A line at the end naming where the recipe came from.
+	mmSource = regexp.MustCompile(`(?i)^\s*(source|from)\s*:\s*(.*?)\s*$`)
+	// What can be in the quantity column.
+	mmQuantity = regexp.MustCompile(`^ *[0-9][0-9/.\- ]*$|^ *$`)
+)
+
+// MealMaster unit codes, with how we write them.
+var mmUnits = map[string]string{
+	"":   "",
+	"x":  "",
+	"ea": "",
+	"sm": "small",
+	"md": "medium",
+	"lg": "large",
+	"cn": "can",
+	"pk": "package",
+	"pn": "pinch",
+	"dr": "drop",
+	"ds": "dash",
+	"ct": "carton",
+	"bn": "bunch",
+	"sl": "slice",
+	"t":  "tsp",
+	"ts": "tsp",
+	"T":  "tbsp",
+	"tb": "tbsp",
+	"fl": "fl oz",
+	"c":  "cup",
+	"pt": "pint",
+	"qt": "quart",
+	"ga": "gallon",
+	"oz": "oz",
+	"lb": "lb",
+	"ml": "ml",
+	"cb": "cubic cm",
+	"cl": "cl",
+	"dl": "dl",
+	"l":  "l",
+	"mg": "mg",
+	"cg": "cg",
+	"dg": "dg",
+	"g":  "g",
+	"kg": "kg",
+}
+
+// The column where the second ingredient starts in two-column layouts.
+const mmSecondColumn = 41
+
+// MealMaster reads the recipes in a MealMaster text file, usually named .mmf
+// or .mm. Recipes name where they're from with a "From:" line, which is
+// taken as the author.
+func MealMaster(data []byte) ([]Imported, error) {
+	var text = strings.Replace(string(data), "\r\n", "\n", -1)
+	var list []Imported
+	var lines []string
+	var inRecipe = false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r\x1a")
+		switch {
+		case mmStart.MatchString(line):
+			if inRecipe {
+				list = append(list, mmRecipe(lines))
+			}
+			inRecipe = true
+			lines = nil
+		case inRecipe && mmEnd.MatchString(line):
+			list = append(list, mmRecipe(lines))
+			inRecipe = false
+		case inRecipe:
+			lines = append(lines, line)
+		}
+	}
+	if inRecipe {
+		list = append(list, mmRecipe(lines))
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no MealMaster recipes found")
+	}
+	return list, nil
+}
+
+// mmColumn reads one ingredient column: the quantity in 7 characters, a
+// space, a unit code in 2, a space, then the ingredient. It returns false if
+// s isn't laid out like that.
+func mmColumn(s string) (quantity, unit, text string, ok bool) {
+	if len(s) < 12 || s[7] != ' ' || s[10] != ' ' ||
+		!mmQuantity.MatchString(s[:7]) {
+		return "", "", "", false
+	}
+	unit, ok = mmUnits[strings.TrimSpace(s[8:10])]
+	if !ok {
+		return "", "", "", false
+	}
+	text = strings.TrimSpace(s[11:])
+	return strings.Join(strings.Fields(s[:7]), " "), unit, text, text != ""
+}
+
+// mmRecipe reads the lines between the start and end of a recipe.
+func mmRecipe(lines []string) Imported {
+	var item = Imported{Recipe: newRecipe()}
+	var r = item.Recipe
+
+	// The header, up to the first ingredient.
+	var i = 0
+	for ; i < len(lines); i++ {
+		var m = mmField.FindStringSubmatch(lines[i])
+		if m == nil {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			break
+		}
+		switch strings.ToLower(m[1]) {
+		case "title":
+			r.Title = m[2]
+		case "categories":
+			for _, tag := range strings.Split(m[2], ",") {
+				if tag = strings.TrimSpace(tag); tag != "" &&
+					!strings.EqualFold(tag, "none") {
+					r.Tags = append(r.Tags, tag)
+				}
+			}
+		case "yield", "servings":
+			r.Amount = m[2]
+		}
+	}
+
+	// Ingredients, until a line that isn't one. Two-column layouts list the
+	// left column first.
+	var left, right []string
+	var heading = ""
+	var started = false
+	var endSection = func() {
+		var items = append(left, right...)
+		if len(items) != 0 || heading != "" {
+			if items == nil {
+				items = []string{}
+			}
+			r.Ingredients = append(r.Ingredients, defs.IngredientSection{
+				Heading: heading, Items: items})
+		}
+		left, right = nil, nil
+	}
+	var add = func(column *[]string, quantity, unit, text string) {
+		// A continuation of the previous ingredient.
+		if quantity == "" && unit == "" && strings.HasPrefix(text, "-") &&
+			len(*column) != 0 {
+			(*column)[len(*column)-1] += " " + strings.TrimSpace(text[1:])
+			return
+		}
+		var line = strings.Join(strings.Fields(quantity+" "+unit+" "+text), " ")
+		*column = append(*column, line)
+	}
+	for ; i < len(lines); i++ {
+		var line = lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := mmHeading.FindStringSubmatch(line); m != nil {
+			if started {
+				endSection()
+			}
+			heading = strings.TrimSpace(m[1])
+			started = true
+			continue
+		}
+		var quantity, unit, text, ok = mmColumn(line)
+		if !ok {
+			break
+		}
+		started = true
+		if len(line) > mmSecondColumn {
+			if q, u, t, ok := mmColumn(line[mmSecondColumn:]); ok {
+				quantity, unit, text, ok = mmColumn(line[:mmSecondColumn])
+				if ok {
+					add(&left, quantity, unit, text)
+				}
+				add(&right, q, u, t)
+				continue
+			}
+		}
+		add(&left, quantity, unit, text)
+	}
+	endSection()
+
+	// Directions, in paragraphs.
+	var section = directionSection("", nil)
+	var paragraph []string
+	var endParagraph = func() {
+		if len(paragraph) != 0 {
+			section.Items = append(section.Items,
+				defs.Direction{Text: strings.Join(paragraph, " ")})
+			paragraph = nil
+		}
+	}
+	for ; i < len(lines); i++ {
+		var line = strings.TrimSpace(lines[i])
+		if m := mmHeading.FindStringSubmatch(lines[i]); m != nil {
+			endParagraph()
+			if len(section.Items) != 0 || section.Heading != "" {
+				r.Directions = append(r.Directions, section)
+			}
+			section = directionSection(strings.TrimSpace(m[1]), nil)
+			continue
+		}
+		if m := mmSource.FindStringSubmatch(line); m != nil {
+			endParagraph()
+			if strings.EqualFold(m[1], "from") {
+				item.Author = m[2]
+			} else {
+				r.Source = m[2]
+			}
+			continue
+		}
+		if line == "" {
+			endParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	endParagraph()
+	if len(section.Items) != 0 || section.Heading != "" {
+		r.Directions = append(r.Directions, section)
+	}
+
+	if r.Title == "" {
+		item.Err = errors.New("recipe has no title")
+	}
+	return item
+}
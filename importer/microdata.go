@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file reads HTML Microdata into the same shape as JSON-LD.
+ */
+
+package importer
+
+import (
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// microdata returns the top-level items in a document.
+func microdata(doc *xhtml.Node) []interface{} {
+	var items []interface{}
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode && hasAttr(n, "itemscope") &&
+			!hasAttr(n, "itemprop") {
+			items = append(items, map[string]interface{}(item(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return items
+}
+
+// item reads the properties of an element with itemscope.
+func item(scope *xhtml.Node) node {
+	var n = node{}
+	if t := attr(scope, "itemtype"); t != "" {
+		n["@type"] = strings.Fields(t)[0]
+	}
+	var walk func(e *xhtml.Node)
+	walk = func(e *xhtml.Node) {
+		for c := e.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != xhtml.ElementNode {
+				continue
+			}
+			var props = strings.Fields(attr(c, "itemprop"))
+			if len(props) > 0 {
+				var value = propValue(c)
+				for _, p := range props {
+					n[p] = append(list(n[p]), value)
+				}
+			}
+			// Properties inside a nested item belong to it.
+			if !hasAttr(c, "itemscope") {
+				walk(c)
+			}
+		}
+	}
+	walk(scope)
+	// Single values aren't lists in JSON-LD either.
+	for k, v := range n {
+		if l, ok := v.([]interface{}); ok && len(l) == 1 {
+			n[k] = l[0]
+		}
+	}
+	return n
+}
+
+// propValue reads the value of an element with itemprop, following the rules
+// in the HTML spec.
+func propValue(e *xhtml.Node) interface{} {
+	if hasAttr(e, "itemscope") {
+		return map[string]interface{}(item(e))
+	}
+	switch e.DataAtom {
+	case atom.Meta:
+		return attr(e, "content")
+	case atom.A, atom.Link, atom.Area:
+		return attr(e, "href")
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Embed,
+		atom.Iframe, atom.Track:
+		return attr(e, "src")
+	case atom.Object:
+		return attr(e, "data")
+	case atom.Data, atom.Meter:
+		return attr(e, "value")
+	case atom.Time:
+		if hasAttr(e, "datetime") {
+			return attr(e, "datetime")
+		}
+	}
+	if hasAttr(e, "content") {
+		return attr(e, "content")
+	}
+	return textContent(e)
+}
+
+// textContent returns all the text inside an element, keeping line breaks
+// between block elements so lists of steps can be split.
+func textContent(e *xhtml.Node) string {
+	var b strings.Builder
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		switch n.Type {
+		case xhtml.TextNode:
+			b.WriteString(n.Data)
+		case xhtml.ElementNode:
+			if n.DataAtom == atom.Br {
+				b.WriteString("\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.DataAtom == atom.P || n.DataAtom == atom.Li ||
+			n.DataAtom == atom.Div {
+			b.WriteString("\n")
+		}
+	}
+	walk(e)
+	return b.String()
+}
+
+// hasAttr reports whether an element has an attribute, even an empty one.
+func hasAttr(n *xhtml.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file imports Paprika exports.
+ */
+
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+)
+
+// paprikaRecipe is a recipe as Paprika exports it.
+type paprikaRecipe struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	Ingredients     string   `json:"ingredients"`
+	Directions      string   `json:"directions"`
+	Notes           string   `json:"notes"`
+	NutritionalInfo string   `json:"nutritional_info"`
+	Servings        string   `json:"servings"`
+	PrepTime        string   `json:"prep_time"`
+	CookTime        string   `json:"cook_time"`
+	TotalTime       string   `json:"total_time"`
+	Source          string   `json:"source"`
+	SourceURL       string   `json:"source_url"`
+	Categories      []string `json:"categories"`
+	// The main photo, as base64.
+	PhotoData string `json:"photo_data"`
+	Photos    []struct {
+		Data string `json:"data"`
+	} `json:"photos"`
+}
+
+// Paprika reads a .paprikarecipes export, which is a ZIP archive of
+// .paprikarecipe files, each a gzipped JSON recipe. A single .paprikarecipe
+// file works too.
+func Paprika(data []byte) ([]Imported, error) {
+	if !isZip(data) {
+		return []Imported{paprikaFile(data)}, nil
+	}
+	var files, err = zipFiles(data)
+	if err != nil {
+		return nil, err
+	}
+	// Archives don't have a meaningful order, so use the file names.
+	var names []string
+	for name := range files {
+		if strings.HasSuffix(name, ".paprikarecipe") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no .paprikarecipe files in archive")
+	}
+	sort.Strings(names)
+	var list []Imported
+	for _, name := range names {
+		var item = paprikaFile(files[name])
+		if item.Err != nil && item.Recipe.Title == "" {
+			item.Recipe.Title = strings.TrimSuffix(name, ".paprikarecipe")
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// paprikaFile reads one gzipped recipe.
+func paprikaFile(data []byte) Imported {
+	var item = Imported{Recipe: newRecipe()}
+	var z, err = gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		item.Err = err
+		return item
+	}
+	data, err = readAll(z)
+	if err != nil {
+		item.Err = err
+		return item
+	}
+	var p paprikaRecipe
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		item.Err = err
+		return item
+	}
+
+	var r = item.Recipe
+	r.Title = strings.TrimSpace(p.Name)
+	r.Summary = strings.TrimSpace(p.Description)
+	r.Amount = strings.TrimSpace(p.Servings)
+	r.Time = p.TotalTime
+	if r.Time == "" {
+		r.Time = joinTime(p.PrepTime, p.CookTime)
+	}
+	r.Source = p.Source
+	if p.SourceURL != "" && p.SourceURL != p.Source {
+		r.Source = strings.TrimSpace(r.Source + " " + p.SourceURL)
+	}
+	r.Tags = append(r.Tags, p.Categories...)
+	r.Ingredients = ingredientSections(splitLines(p.Ingredients))
+	var steps = splitLines(p.Directions)
+	if len(steps) != 0 {
+		r.Directions = append(r.Directions, directionSection("", steps))
+	}
+	r.Notes = strings.TrimSpace(p.Notes)
+	if info := strings.TrimSpace(p.NutritionalInfo); info != "" {
+		r.Notes = strings.TrimSpace(r.Notes + "\n\nNutrition:\n" + info)
+	}
+
+	for _, encoded := range append([]string{p.PhotoData}, photoData(p)...) {
+		if encoded == "" {
+			continue
+		}
+		// A bad photo shouldn't cost us the recipe.
+		var photo, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Println("dropping a photo of", r.Title+":", err)
+			continue
+		}
+		item.Photos = append(item.Photos, photo)
+	}
+	if r.Title == "" {
+		item.Err = errors.New("recipe has no name")
+	}
+	return item
+}
+
+// photoData lists the recipe's extra photos.
+func photoData(p paprikaRecipe) []string {
+	var list []string
+	for _, photo := range p.Photos {
+		list = append(list, photo.Data)
+	}
+	return list
+}
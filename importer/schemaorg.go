@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file reads schema.org Recipe metadata from web pages, as either JSON-LD
+ * or Microdata.
+ */
+
+// Package importer reads recipes written in other formats.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// ErrNoRecipe is returned when a document has no recipe in it.
+var ErrNoRecipe = errors.New("no recipe found")
+
+// node is a schema.org item, from JSON-LD or converted from Microdata. Keys
+// are property names, plus "@type". Values are strings, numbers, nodes, or
+// lists of them.
+type node map[string]interface{}
+
+// FromHTML finds a schema.org Recipe in a web page and converts it. JSON-LD
+// is preferred over Microdata. The source is noted in the recipe, and may be
+// empty for an uploaded page.
+func FromHTML(data []byte, source string) (*defs.Recipe, error) {
+	var doc, err = xhtml.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var recipe = findRecipe(jsonLD(doc))
+	if recipe == nil {
+		recipe = findRecipe(microdata(doc))
+	}
+	if recipe == nil {
+		return nil, ErrNoRecipe
+	}
+	return fromSchema(recipe, source), nil
+}
+
+// jsonLD returns the contents of every JSON-LD script in a document. Scripts
+// that don't parse are skipped.
+func jsonLD(doc *xhtml.Node) []interface{} {
+	var items []interface{}
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode && n.DataAtom == atom.Script &&
+			strings.EqualFold(attr(n, "type"), "application/ld+json") &&
+			n.FirstChild != nil {
+			var v interface{}
+			if json.Unmarshal([]byte(n.FirstChild.Data), &v) == nil {
+				items = append(items, v)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return items
+}
+
+// findRecipe searches JSON-LD values for the first node with a type of
+// Recipe, looking inside lists and @graph.
+func findRecipe(items []interface{}) node {
+	for _, item := range items {
+		switch v := item.(type) {
+		case []interface{}:
+			if r := findRecipe(v); r != nil {
+				return r
+			}
+		case map[string]interface{}:
+			if hasType(v, "Recipe") {
+				return node(v)
+			}
+			if r := findRecipe(list(v["@graph"])); r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a node's @type is, or includes, t.
+func hasType(n map[string]interface{}, t string) bool {
+	for _, v := range list(n["@type"]) {
+		var s, _ = v.(string)
+		if s == t || strings.HasSuffix(s, "/"+t) {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns a property's values, whether it has none, one, or several.
+func list(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	return []interface{}{v}
+}
+
+// htmlTag matches the markup some sites leave in their metadata.
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// text returns the text of a value: the string itself, a number written out,
+// or the name or text of a node. Markup and extra spaces are removed.
+func text(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "@value"} {
+			if t := text(v[key]); t != "" {
+				return t
+			}
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return text(v[0])
+		}
+	}
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, " "))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// lines returns the text of each value, splitting strings on line breaks and
+// dropping blanks.
+func lines(v interface{}) []string {
+	var out []string
+	for _, item := range list(v) {
+		if s, ok := item.(string); ok {
+			s = strings.NewReplacer("<br>", "\n", "<br/>", "\n",
+				"<br />", "\n", "</p>", "\n", "</li>", "\n").Replace(s)
+			for _, line := range strings.Split(s, "\n") {
+				if t := text(line); t != "" {
+					out = append(out, t)
+				}
+			}
+		} else if t := text(item); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// fromSchema converts a schema.org Recipe node.
+func fromSchema(n node, source string) *defs.Recipe {
+	var r = defs.Recipe{
+		Title:         text(n["name"]),
+		Summary:       text(n["description"]),
+		Tags:          keywords(n),
+		LinkedRecipes: []defs.LinkedRecipe{},
+	}
+
+	var ingredients = n["recipeIngredient"]
+	if ingredients == nil {
+		// The old name for it.
+		ingredients = n["ingredients"]
+	}
+	if items := lines(ingredients); len(items) > 0 {
+		r.Ingredients = defs.IngredientSections{{Items: items}}
+	}
+	r.Directions = instructions(n["recipeInstructions"])
+
+	// Yield may be given both as a number and as text; prefer the text.
+	for _, y := range list(n["recipeYield"]) {
+		var t = text(y)
+		if _, isNumber := y.(float64); r.Amount == "" || !isNumber {
+			r.Amount = t
+		}
+	}
+
+	if total, ok := defs.ParseISODuration(text(n["totalTime"])); ok {
+		r.Time = defs.FormatMinutes(total)
+	} else {
+		var prep, prepOK = defs.ParseISODuration(text(n["prepTime"]))
+		var cook, cookOK = defs.ParseISODuration(text(n["cookTime"]))
+		if prepOK || cookOK {
+			r.Time = defs.FormatMinutes(prep + cook)
+		}
+	}
+
+	var credits []string
+	for _, a := range list(n["author"]) {
+		if name := text(a); name != "" {
+			credits = append(credits, name)
+		}
+	}
+	if source != "" {
+		credits = append(credits, source)
+	}
+	r.Source = strings.Join(credits, ", ")
+	return &r
+}
+
+// keywords collects tags from a recipe's keywords, category, and cuisine.
+// Keywords may be one comma-separated string or a list.
+func keywords(n node) []string {
+	var tags = []string{}
+	for _, key := range []string{"keywords", "recipeCategory",
+		"recipeCuisine"} {
+		for _, v := range list(n[key]) {
+			for _, tag := range strings.Split(text(v), ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	return tags
+}
+
+// instructions converts recipeInstructions, which may be text, a list of
+// strings or HowToSteps, or HowToSections holding steps.
+func instructions(v interface{}) defs.DirectionSections {
+	var sections defs.DirectionSections
+	// Whether the last section holds steps outside of any HowToSection.
+	var loose bool
+	var add = func(steps []string) {
+		if len(steps) == 0 {
+			return
+		}
+		if !loose {
+			sections = append(sections, defs.DirectionSection{})
+			loose = true
+		}
+		var last = &sections[len(sections)-1]
+		for _, s := range steps {
+			last.Items = append(last.Items, defs.Direction{Text: s})
+		}
+	}
+	for _, item := range list(v) {
+		var n, ok = item.(map[string]interface{})
+		if !ok {
+			add(lines(item))
+			continue
+		}
+		if hasType(n, "HowToSection") {
+			var section = defs.DirectionSection{Heading: text(n["name"]),
+				Items: []defs.Direction{}}
+			for _, s := range steps(n["itemListElement"]) {
+				section.Items = append(section.Items, defs.Direction{Text: s})
+			}
+			sections = append(sections, section)
+			loose = false
+			continue
+		}
+		add(steps(n))
+	}
+	return sections
+}
+
+// steps returns the text of HowToSteps. A step may be plain text, or hold
+// HowToDirections of its own.
+func steps(v interface{}) []string {
+	var out []string
+	for _, item := range list(v) {
+		var n, ok = item.(map[string]interface{})
+		if !ok {
+			out = append(out, lines(item)...)
+			continue
+		}
+		if n["text"] == nil && n["itemListElement"] != nil {
+			out = append(out, steps(n["itemListElement"])...)
+			continue
+		}
+		if t := text(n); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// attr returns the value of an element's attribute, or "".
+func attr(n *xhtml.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestFromHTMLJSONLD(t *testing.T) {
+	var data, err = ioutil.ReadFile("testdata/jsonld.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := FromHTML(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = defs.Recipe{
+		Title:   "Chocolate Layer Cake",
+		Summary: "A rich cake for birthdays & holidays.",
+		Amount:  "12 slices",
+		Time:    "1 hour 30 minutes",
+		Source:  "Jane Baker",
+		Tags:    []string{"cake", "chocolate", "birthday", "Dessert"},
+		Ingredients: defs.IngredientSections{{Items: []string{
+			"2 cups flour", "1 cup cocoa", "1 cup butter"}}},
+		Directions: defs.DirectionSections{
+			{Heading: "For the sponge", Items: []defs.Direction{
+				{Text: "Preheat oven to 350F."},
+				{Text: "Mix and bake for 30 minutes."},
+			}},
+			{Heading: "For the frosting", Items: []defs.Direction{
+				{Text: "Beat butter and cocoa."},
+			}},
+		},
+		LinkedRecipes: []defs.LinkedRecipe{},
+	}
+	if !reflect.DeepEqual(*r, want) {
+		t.Errorf("got %+v\nwant %+v", *r, want)
+	}
+}
+
+func TestFetchMicrodata(t *testing.T) {
+	// Stand in for a recipe website.
+	var server = httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	var _, err = Fetch(context.Background(), server.URL+"/microdata.html")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected a private address error, got %v", err)
+	}
+	// A proxy would connect to private addresses for us.
+	if client.Transport.(*http.Transport).Proxy != nil {
+		t.Error("the client uses a proxy")
+	}
+
+	AllowPrivateAddresses = true
+	defer func() { AllowPrivateAddresses = false }()
+	var url = server.URL + "/microdata.html"
+	data, err := Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := FromHTML(data, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = defs.Recipe{
+		Title:   "Banana Bread",
+		Summary: "Uses up brown bananas.",
+		Amount:  "1 loaf",
+		Time:    "1 hour 15 minutes",
+		Source:  "Sam Cook, " + url,
+		Tags:    []string{"bread", "banana"},
+		Ingredients: defs.IngredientSections{{Items: []string{
+			"3 ripe bananas", "2 cups flour", "1/2 cup sugar"}}},
+		Directions: defs.DirectionSections{{Items: []defs.Direction{
+			{Text: "Mash the bananas."},
+			{Text: "Stir in everything else."},
+			{Text: "Bake for an hour."},
+		}}},
+		LinkedRecipes: []defs.LinkedRecipe{},
+	}
+	if !reflect.DeepEqual(*r, want) {
+		t.Errorf("got %+v\nwant %+v", *r, want)
+	}
+
+	_, err = FromHTML([]byte("<html><body>Nothing here</body></html>"), "")
+	if err != ErrNoRecipe {
+		t.Errorf("expected ErrNoRecipe, got %v", err)
+	}
+}
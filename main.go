@@ -3,14 +3,26 @@
  * This code is under the BSD-2-Clause license.
  *
  * This is the main file. Run it to launch the application.
+ *
+ * Usage:
+ *   recipes                             serve the application
+ *   recipes backup [-o file]            write a backup archive
+ *   recipes restore [-replace] [-check] file
+ *                                       restore a backup archive
  */
 
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/rwestlund/recipes/backup"
+	"github.com/rwestlund/recipes/blob"
 	"github.com/rwestlund/recipes/config"
 	"github.com/rwestlund/recipes/db"
 	"github.com/rwestlund/recipes/router"
@@ -21,9 +33,112 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			runBackup(os.Args[2:])
+			return
+		case "restore":
+			runRestore(os.Args[2:])
+			return
+		default:
+			fmt.Fprintln(os.Stderr, "usage: recipes [backup | restore]")
+			os.Exit(2)
+		}
+	}
 	// Create router from routes.go.
-	myRouter := router.NewRouter()
+	myRouter := router.NewRouter(openStore())
 	log.Println("starting server on " + config.ListenAddress)
 	err = http.ListenAndServe(config.ListenAddress, myRouter)
 	log.Fatal(err)
 }
+
+// openStore returns the blob store the config names.
+func openStore() blob.Store {
+	switch config.BlobStore {
+	case "s3":
+		return blob.NewS3Store(config.S3Endpoint, config.S3Region,
+			config.S3Bucket, config.S3AccessKey, config.S3SecretKey)
+	default:
+		var store, err = blob.NewFileStore(config.BlobDirectory)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return store
+	}
+}
+
+// runBackup writes a backup archive of the database and blobs.
+func runBackup(args []string) {
+	var flags = flag.NewFlagSet("backup", flag.ExitOnError)
+	var out = flags.String("o", "", "file to write; without it, standard output")
+	flags.Parse(args)
+
+	var b, err = db.FetchBackup()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		var f, err = os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	err = backup.Write(w, b, openStore())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("backed up %d users, %d recipes, %d photos, %d attachments",
+		len(b.Users), len(b.Recipes), len(b.Photos), len(b.Attachments))
+}
+
+// runRestore checks a backup archive and restores it.
+func runRestore(args []string) {
+	var flags = flag.NewFlagSet("restore", flag.ExitOnError)
+	var replace = flags.Bool("replace", false,
+		"delete everything in the database first, instead of adding to it")
+	var check = flags.Bool("check", false, "only check the archive")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr,
+			"usage: recipes restore [-replace] [-check] file")
+		os.Exit(2)
+	}
+
+	var f, err = os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := backup.Read(f, stat.Size())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *check {
+		err = a.Check()
+		if errs, ok := err.(backup.Errors); ok {
+			for _, e := range errs {
+				fmt.Println(e)
+			}
+			os.Exit(1)
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("backup from %s is fine", a.Manifest.Created)
+		return
+	}
+	report, err := backup.Restore(a, openStore(), *replace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("restored %d recipes, %d photos, %d attachments; "+
+		"created %d users and matched %d", report.Recipes, report.Photos,
+		report.Attachments, report.Users, report.MatchedUsers)
+}
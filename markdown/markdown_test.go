@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var recipes = []defs.Recipe{
+		{
+			Title:   "Banana Bread",
+			Tags:    []string{"bread", "quick, easy"},
+			Time:    "1 hour",
+			Oven:    "350 F",
+			Source:  " Grandma",
+			Amount:  "1 loaf",
+			Summary: "Moist.\n\n# Not a heading\n\\ backslash",
+			Ingredients: defs.IngredientSections{
+				{Heading: "", Items: []string{"3 bananas", "", "1 cup\nflour"}},
+				{Heading: "Topping", Items: []string{}},
+				{Heading: "", Items: []string{"2 tbsp sugar"}},
+			},
+			Directions: defs.DirectionSections{
+				{Heading: "Batter", Items: []defs.Direction{
+					{Text: "Mash.\n\n  Then stir."},
+					{Text: "Pour."},
+				}},
+			},
+			Notes: "## Not a section\n\nStill notes.",
+		},
+		{
+			Title:       "Toast",
+			Tags:        []string{},
+			Ingredients: defs.IngredientSections{{Items: []string{}}},
+			Directions:  defs.DirectionSections{},
+		},
+	}
+	for _, want := range recipes {
+		var data = Write(&want)
+		var got, err = Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v\n%s", want.Title, err, data)
+			continue
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("%s: got %+v, want %+v\n%s", want.Title, *got, want, data)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var data = "---\n" +
+		"title: Pie\n" +
+		"color: red\n" +
+		"oven \"hot\"\n" +
+		"---\n" +
+		"\n" +
+		"## Ingredients\n" +
+		"- crust\n" +
+		"1. filling\n" +
+		"## Steps\n"
+	var _, err = Parse([]byte(data))
+	var want = Errors{
+		{Line: 3, Message: `unknown key "color"`},
+		{Line: 4, Message: `expected "key: value"`},
+		{Line: 9, Message: `expected a list item starting with "-" or a "###" heading`},
+		{Line: 10, Message: `unknown section "steps"; expected Ingredients, Directions, or Notes`},
+	}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got %v, want %v", err, want)
+	}
+
+	_, err = Parse([]byte("---\ntags: a\n"))
+	want = Errors{{Line: 1, Message: `front matter has no closing "---"`}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
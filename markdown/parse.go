@@ -0,0 +1,333 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file reads recipes from Markdown.
+ */
+
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Error is a problem with a Markdown recipe, found at a line of the file.
+type Error struct {
+	// Line numbers start at 1.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Message
+}
+
+// Errors are all the problems found in a Markdown recipe, in order.
+type Errors []Error
+
+func (e Errors) Error() string {
+	var messages = make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Markers that start list items, followed by the item's first line.
+var (
+	bulletPattern = regexp.MustCompile(`^[-*+](?: (.*))?$`)
+	numberPattern = regexp.MustCompile(`^[0-9]+[.)](?: (.*))?$`)
+)
+
+// parser holds the state of reading one file.
+type parser struct {
+	lines []string
+	// Index of the next line to read.
+	pos  int
+	errs Errors
+}
+
+// fail records a problem with the line at index i.
+func (p *parser) fail(i int, message string) {
+	p.errs = append(p.errs, Error{Line: i + 1, Message: message})
+}
+
+// atSection reports whether the next line is a "##" heading, or there are no
+// more lines.
+func (p *parser) atSection() bool {
+	if p.pos >= len(p.lines) {
+		return true
+	}
+	var line = p.lines[p.pos]
+	return line == "##" || strings.HasPrefix(line, "## ")
+}
+
+// Parse reads a recipe written in the format described in the package
+// documentation. If the file has problems, the error is Errors listing all of
+// them.
+func Parse(data []byte) (*defs.Recipe, error) {
+	var text = strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	var p = parser{lines: strings.Split(strings.TrimSuffix(text, "\n"), "\n")}
+	var r = defs.Recipe{
+		Tags:        []string{},
+		Ingredients: defs.IngredientSections{},
+		Directions:  defs.DirectionSections{},
+	}
+
+	if !p.frontMatter(&r) {
+		// Without the front matter, the rest can't be found reliably.
+		return nil, p.errs
+	}
+
+	// Everything up to the first section is the summary.
+	var summary []string
+	for !p.atSection() {
+		var line = p.lines[p.pos]
+		if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		summary = append(summary, line)
+		p.pos++
+	}
+	r.Summary = strings.Trim(strings.Join(summary, "\n"), "\n")
+
+	var seen = map[string]bool{}
+	for p.pos < len(p.lines) {
+		var start = p.pos
+		var name = strings.ToLower(strings.TrimSpace(
+			strings.TrimPrefix(p.lines[p.pos], "##")))
+		p.pos++
+		if seen[name] {
+			p.fail(start, "repeated section "+strconv.Quote(name))
+		}
+		seen[name] = true
+		switch name {
+		case "ingredients":
+			for _, s := range p.list(bulletPattern, `"-"`) {
+				r.Ingredients = append(r.Ingredients, defs.IngredientSection{
+					Heading: s.heading, Items: s.items})
+			}
+		case "directions":
+			for _, s := range p.list(numberPattern, `"1."`) {
+				var section = defs.DirectionSection{Heading: s.heading,
+					Items: make([]defs.Direction, len(s.items))}
+				for i, item := range s.items {
+					section.Items[i] = defs.Direction{Text: item}
+				}
+				r.Directions = append(r.Directions, section)
+			}
+		case "notes":
+			r.Notes = strings.Trim(strings.Join(p.lines[p.pos:], "\n"), "\n")
+			p.pos = len(p.lines)
+		default:
+			p.fail(start, "unknown section "+strconv.Quote(name)+
+				"; expected Ingredients, Directions, or Notes")
+			for !p.atSection() {
+				p.pos++
+			}
+		}
+	}
+
+	if p.errs != nil {
+		return nil, p.errs
+	}
+	return &r, nil
+}
+
+// frontMatter reads the front matter into r. It returns false if there isn't
+// any, or it doesn't end.
+func (p *parser) frontMatter(r *defs.Recipe) bool {
+	// Allow blank lines before it.
+	for p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos]) == "" {
+		p.pos++
+	}
+	if p.pos >= len(p.lines) || p.lines[p.pos] != "---" {
+		p.fail(p.pos, `expected front matter starting with "---"`)
+		return false
+	}
+	var start = p.pos
+	p.pos++
+
+	var seen = map[string]bool{}
+	for ; p.pos < len(p.lines) && p.lines[p.pos] != "---"; p.pos++ {
+		var line = p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var colon = strings.Index(line, ":")
+		if colon < 0 {
+			p.fail(p.pos, `expected "key: value"`)
+			continue
+		}
+		var key = strings.ToLower(strings.TrimSpace(line[:colon]))
+		var raw = strings.TrimSpace(line[colon+1:])
+		if seen[key] {
+			p.fail(p.pos, "repeated key "+strconv.Quote(key))
+			continue
+		}
+		seen[key] = true
+
+		if key == "tags" {
+			var tags, ok = splitTags(raw)
+			if !ok {
+				p.fail(p.pos, "bad quoted tag")
+				continue
+			}
+			r.Tags = tags
+			continue
+		}
+		var value, ok = unquote(raw)
+		if !ok {
+			p.fail(p.pos, "bad quoted value")
+			continue
+		}
+		switch key {
+		case "title":
+			r.Title = value
+		case "time":
+			r.Time = value
+		case "oven":
+			r.Oven = value
+		case "source":
+			r.Source = value
+		case "amount":
+			r.Amount = value
+		default:
+			p.fail(p.pos, "unknown key "+strconv.Quote(key))
+		}
+	}
+	if p.pos >= len(p.lines) {
+		p.fail(start, `front matter has no closing "---"`)
+		return false
+	}
+	p.pos++
+	if r.Title == "" {
+		p.fail(start, "missing title")
+	}
+	return true
+}
+
+// listSection is a group of list items under a heading.
+type listSection struct {
+	heading string
+	items   []string
+}
+
+// list reads list items up to the next section. Items start with a marker
+// matching pattern, which is described to the user as marker.
+func (p *parser) list(pattern *regexp.Regexp, marker string) []listSection {
+	var sections []listSection
+	// The item being read, or nil if there isn't one.
+	var item *string
+	// Blank lines seen since the last line of the item.
+	var blanks = 0
+	for ; !p.atSection(); p.pos++ {
+		var line = p.lines[p.pos]
+		if strings.TrimSpace(line) == "" && (line == "" || item == nil) {
+			blanks++
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if item == nil {
+				p.fail(p.pos, "indented line isn't part of an item")
+				continue
+			}
+			*item += strings.Repeat("\n", blanks+1) + unindent(line)
+			blanks = 0
+			continue
+		}
+		item = nil
+		blanks = 0
+		if line == "###" || strings.HasPrefix(line, "### ") {
+			sections = append(sections, listSection{
+				heading: strings.TrimSpace(line[3:]),
+				items:   []string{},
+			})
+			continue
+		}
+		var match = pattern.FindStringSubmatch(line)
+		if match == nil {
+			p.fail(p.pos, "expected a list item starting with "+marker+
+				` or a "###" heading`)
+			continue
+		}
+		if sections == nil {
+			sections = append(sections, listSection{items: []string{}})
+		}
+		var last = &sections[len(sections)-1]
+		last.items = append(last.items, match[1])
+		item = &last.items[len(last.items)-1]
+	}
+	return sections
+}
+
+// unindent removes the indent from a line that continues a list item.
+func unindent(line string) string {
+	if line[0] == '\t' {
+		return line[1:]
+	}
+	for i := 0; i < len(indent); i++ {
+		if i == len(line) || line[i] != ' ' {
+			return line[i:]
+		}
+	}
+	return line[len(indent):]
+}
+
+// unquote reads a front matter value, which may be a quoted string.
+func unquote(value string) (string, bool) {
+	if !strings.HasPrefix(value, `"`) {
+		return value, true
+	}
+	var s, err = strconv.Unquote(value)
+	return s, err == nil
+}
+
+// splitTags reads a list of tags separated by commas. A tag with a comma in
+// it must be quoted.
+func splitTags(value string) ([]string, bool) {
+	var tags = []string{}
+	for value != "" {
+		var tag string
+		if strings.HasPrefix(value, `"`) {
+			// Find the closing quote, skipping escaped characters.
+			var end = 1
+			for end < len(value) && value[end] != '"' {
+				if value[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(value) {
+				return nil, false
+			}
+			var err error
+			tag, err = strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = strings.TrimSpace(value[end+1:])
+			if value != "" && value[0] != ',' {
+				return nil, false
+			}
+			value = strings.TrimPrefix(value, ",")
+		} else {
+			var comma = strings.Index(value, ",")
+			if comma < 0 {
+				comma = len(value) - 1
+			}
+			tag = strings.TrimSpace(strings.TrimSuffix(value[:comma+1], ","))
+			value = value[comma+1:]
+		}
+		value = strings.TrimSpace(value)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, true
+}
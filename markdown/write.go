@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes recipes as Markdown.
+ */
+
+/*
+Package markdown reads and writes recipes as Markdown, for people who keep
+recipes in their notes. A recipe looks like this:
+
+	---
+	title: Banana Bread
+	tags: bread, breakfast
+	time: 1 hour 10 minutes
+	oven: 350 F
+	source: Grandma
+	amount: 1 loaf
+	---
+
+	A summary, which may be several paragraphs.
+
+	## Ingredients
+
+	- 3 ripe bananas
+	- 1/3 cup melted butter
+
+	### For the topping
+
+	- 2 tbsp sugar
+
+	## Directions
+
+	1. Mash the bananas.
+	2. Stir in the butter.
+
+	## Notes
+
+	Anything at all, up to the end of the file.
+
+The front matter is between two lines of "---". Each line in it is a key, a
+colon, and a value. Only title is required; the keys above are the only ones
+allowed. A value that has line breaks or surrounding spaces is written as a
+double-quoted Go string, like "two\nlines". Tags are separated by commas.
+
+Any text after the front matter and before the first "##" heading is the
+summary. A summary line starting with "#" or "\" has a backslash added in
+front of it, which is removed when reading.
+
+The Ingredients and Directions sections are lists: ingredients with "-" or
+"*", directions numbered like "1." or "1)". The numbers themselves are
+ignored. A "###" heading starts a group of items; a bare "###" starts a group
+without a heading. An item continues onto following lines indented by a tab
+or up to four spaces, including blank lines between them.
+
+Notes must come last, since everything after its heading belongs to it.
+
+Photos, attachments, and linked recipes are stored by reference, so they
+aren't part of the format.
+*/
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// The indent of lines that continue a list item.
+const indent = "    "
+
+// Write writes a recipe as Markdown. Parse reads it back as the same recipe.
+func Write(r *defs.Recipe) []byte {
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	var field = func(key, value string) {
+		if value != "" {
+			b.WriteString(key + ": " + quote(value) + "\n")
+		}
+	}
+	field("title", r.Title)
+	if len(r.Tags) != 0 {
+		var tags = make([]string, len(r.Tags))
+		for i, tag := range r.Tags {
+			tags[i] = quoteTag(tag)
+		}
+		b.WriteString("tags: " + strings.Join(tags, ", ") + "\n")
+	}
+	field("time", r.Time)
+	field("oven", r.Oven)
+	field("source", r.Source)
+	field("amount", r.Amount)
+	b.WriteString("---\n")
+
+	if r.Summary != "" {
+		b.WriteString("\n")
+		for _, line := range strings.Split(r.Summary, "\n") {
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, `\`) {
+				line = `\` + line
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	b.WriteString("\n## Ingredients\n")
+	for i, section := range r.Ingredients {
+		writeHeading(&b, i, section.Heading, len(section.Items))
+		if len(section.Items) != 0 {
+			b.WriteString("\n")
+		}
+		for _, item := range section.Items {
+			writeItem(&b, "- ", item)
+		}
+	}
+
+	b.WriteString("\n## Directions\n")
+	for i, section := range r.Directions {
+		writeHeading(&b, i, section.Heading, len(section.Items))
+		if len(section.Items) != 0 {
+			b.WriteString("\n")
+		}
+		for n, item := range section.Items {
+			writeItem(&b, strconv.Itoa(n+1)+". ", item.Text)
+		}
+	}
+
+	if r.Notes != "" {
+		b.WriteString("\n## Notes\n\n" + r.Notes + "\n")
+	}
+	return b.Bytes()
+}
+
+// writeHeading starts the i'th section of a list. The first section needs no
+// heading if it doesn't have one, unless it's empty.
+func writeHeading(b *bytes.Buffer, i int, heading string, items int) {
+	if i == 0 && heading == "" && items != 0 {
+		return
+	}
+	b.WriteString("\n###")
+	if heading != "" {
+		b.WriteString(" " + heading)
+	}
+	b.WriteString("\n")
+}
+
+// writeItem writes a list item, indenting any lines after the first.
+func writeItem(b *bytes.Buffer, marker, text string) {
+	var lines = strings.Split(text, "\n")
+	if lines[0] == "" {
+		marker = strings.TrimSpace(marker)
+	}
+	b.WriteString(marker + lines[0] + "\n")
+	for _, line := range lines[1:] {
+		if line != "" {
+			line = indent + line
+		}
+		b.WriteString(line + "\n")
+	}
+}
+
+// quote returns a front matter value, quoted if it wouldn't read back the
+// same otherwise.
+func quote(value string) string {
+	if value != strings.TrimSpace(value) || strings.HasPrefix(value, `"`) ||
+		strings.ContainsAny(value, "\n\r\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// quoteTag is like quote, but also quotes tags that have commas.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, ",") {
+		return strconv.Quote(tag)
+	}
+	return quote(tag)
+}
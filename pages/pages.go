@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file renders recipes as HTML pages.
+ */
+
+// Package pages renders public HTML pages of recipes, for readers without
+// JavaScript, link previews, and printing. Each recipe has a full page and a
+// compact kitchen card. Pages carry Open Graph and Twitter meta tags and the
+// recipe as schema.org JSON-LD, which also go in the client app's page when
+// it shows a recipe.
+package pages
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/exporter"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"paragraphs": paragraphs,
+	"join":       strings.Join,
+}).ParseFS(files, "templates/*.html"))
+
+// The longest description we give link previews, in characters.
+const maxDescription = 200
+
+// page is what the templates are given.
+type page struct {
+	Recipe *defs.Recipe
+	// Where the recipe's page is, absolute and relative to the site.
+	URL  string
+	Path string
+	// The cover photo, or the first, absolute. Empty if there are none.
+	Image       string
+	Description string
+	JSONLD      template.JS
+}
+
+// Recipe writes the full page of a recipe. URLs in meta tags are made
+// absolute with base, like "https://recipes.example.com".
+func Recipe(w io.Writer, r *defs.Recipe, base string) error {
+	return render(w, "recipe.html", r, base)
+}
+
+// Card writes a recipe's kitchen card: only the ingredients and directions,
+// small enough to print on an index card.
+func Card(w io.Writer, r *defs.Recipe, base string) error {
+	return render(w, "card.html", r, base)
+}
+
+// The app's head, and the tags in it that a recipe's replace.
+var (
+	headStart = regexp.MustCompile(`(?i)<head[^>]*>`)
+	headEnd   = regexp.MustCompile(`(?i)</head>`)
+	appTags   = regexp.MustCompile(
+		`(?is)\s*(<title>.*?</title>|<meta\s+name="description"[^>]*>)`)
+)
+
+// App writes the client app's page, index, with a recipe's title, link
+// preview tags, and JSON-LD in its head. The app shows the recipe, and links
+// to it preview like links to its full page.
+func App(w io.Writer, index []byte, r *defs.Recipe, base string) error {
+	var meta bytes.Buffer
+	var err = render(&meta, "meta", r, base)
+	if err != nil {
+		return err
+	}
+	var start, end = 0, 0
+	if loc := headStart.FindIndex(index); loc != nil {
+		start = loc[1]
+		end = start
+		if loc := headEnd.FindIndex(index[start:]); loc != nil {
+			end += loc[0]
+		}
+	}
+	var head = appTags.ReplaceAll(index[start:end], nil)
+	for _, part := range [][]byte{index[:start], meta.Bytes(), head,
+		index[end:]} {
+		_, err = w.Write(part)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func render(w io.Writer, name string, r *defs.Recipe, base string) error {
+	var ld, err = exporter.JSONLD(r, base)
+	if err != nil {
+		return err
+	}
+	var p = page{
+		Recipe:      r,
+		Path:        exporter.RecipePath(r),
+		URL:         base + exporter.RecipePath(r),
+		Description: description(r),
+		// JSON-LD escapes <, >, and &, so it can't end the script early.
+		JSONLD: template.JS(ld),
+	}
+	for _, photo := range r.Photos {
+		if photo.Cover || p.Image == "" {
+			p.Image = base + photo.URL
+		}
+	}
+	return templates.ExecuteTemplate(w, name, &p)
+}
+
+// description summarizes a recipe for link previews: its summary, or else
+// what's in it.
+func description(r *defs.Recipe) string {
+	var text = strings.Join(strings.Fields(r.Summary), " ")
+	if text == "" {
+		text = strings.Join(r.Ingredients.Lines(), ", ")
+	}
+	if utf8.RuneCountInString(text) <= maxDescription {
+		return text
+	}
+	var runes = []rune(text)[:maxDescription]
+	// Break between words if there's one near the end.
+	var cut = strings.LastIndex(string(runes), " ")
+	if cut < maxDescription/2 {
+		cut = len(string(runes))
+	}
+	return strings.TrimRight(string(runes)[:cut], " ,.;:") + "…"
+}
+
+// paragraphs splits text into its non-blank lines.
+func paragraphs(text string) []string {
+	var list []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			list = append(list, line)
+		}
+	}
+	return list
+}
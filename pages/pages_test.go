@@ -0,0 +1,133 @@
+package pages
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+var pie = defs.Recipe{
+	ID:         3,
+	Title:      "Apple <Pie>",
+	Slug:       "apple-pie",
+	Summary:    "Tart and\nflaky.",
+	Amount:     "8 slices",
+	AuthorName: "Jo",
+	Tags:       []string{"dessert", "fall"},
+	Ingredients: defs.IngredientSections{
+		{Items: []string{"6 apples"}},
+		{Heading: "Crust", Items: []string{"2 cups flour"}},
+	},
+	Directions: defs.DirectionSections{{Items: []defs.Direction{
+		{Text: "Slice the apples."}, {Text: "Bake </script> an hour."}}}},
+	Notes: "Best warm.",
+	Photos: []defs.Photo{
+		{ID: 1, URL: "/api/photos/1/full.jpg", ThumbURL: "/api/photos/1/thumb.jpg"},
+		{ID: 2, Cover: true, URL: "/api/photos/2/full.jpg"},
+	},
+}
+
+func TestRecipe(t *testing.T) {
+	var buf bytes.Buffer
+	var err = Recipe(&buf, &pie, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = buf.String()
+	for _, want := range []string{
+		`<title>Apple &lt;Pie&gt;</title>`,
+		`<link rel="canonical" href="https://example.com/recipes/apple-pie">`,
+		`<meta property="og:title" content="Apple &lt;Pie&gt;">`,
+		`<meta property="og:description" content="Tart and flaky.">`,
+		`<meta property="og:image" content="https://example.com/api/photos/2/full.jpg">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		`<script type="application/ld+json">{"@context":"https://schema.org"`,
+		`<a href="/recipes/apple-pie/card">`,
+		`<h3>Crust</h3>`,
+		`<li><p>Bake &lt;/script&gt; an hour.</p></li>`,
+		`<p>Best warm.</p>`,
+		`<img src="/api/photos/1/thumb.jpg"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+	// Only the end of the JSON-LD script may close it.
+	if strings.Count(out, "</script>") != 1 {
+		t.Errorf("JSON-LD isn't escaped:\n%s", out)
+	}
+}
+
+func TestCard(t *testing.T) {
+	var plain = pie
+	plain.Photos = nil
+	plain.Summary = ""
+	var buf bytes.Buffer
+	var err = Card(&buf, &plain, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = buf.String()
+	for _, want := range []string{
+		`<a href="/recipes/apple-pie/page">Full recipe</a>`,
+		`<meta name="twitter:card" content="summary">`,
+		// Without a summary, previews list the ingredients.
+		`<meta name="description" content="6 apples, 2 cups flour">`,
+		`<li>6 apples</li>`,
+		`size: 7in 5in;`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("card is missing %s", want)
+		}
+	}
+	if strings.Contains(out, "Best warm.") {
+		t.Error("the card shouldn't have notes")
+	}
+}
+
+func TestApp(t *testing.T) {
+	var index = []byte(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Recipes</title>
+    <meta name="description" content="A recipe app">
+    <script src="/app.js"></script>
+</head>
+<body><div id="root"></div></body>
+</html>
+`)
+	var buf bytes.Buffer
+	var err = App(&buf, index, &pie, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = buf.String()
+	for _, want := range []string{
+		"<head>\n    <title>Apple &lt;Pie&gt;</title>",
+		`<meta property="og:url" content="https://example.com/recipes/apple-pie">`,
+		`<script type="application/ld+json">{"@context":"https://schema.org"`,
+		`<meta charset="utf-8">`,
+		`<script src="/app.js"></script>`,
+		`<body><div id="root"></div></body>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+	for _, gone := range []string{"<title>Recipes</title>", "A recipe app"} {
+		if strings.Contains(out, gone) {
+			t.Errorf("the app's %s is still there", gone)
+		}
+	}
+}
+
+func TestDescription(t *testing.T) {
+	var r = defs.Recipe{Summary: strings.Repeat("word ", 100)}
+	var got = description(&r)
+	if len([]rune(got)) > maxDescription+1 || !strings.HasSuffix(got, "word…") {
+		t.Errorf("got %q", got)
+	}
+}
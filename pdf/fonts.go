@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file has the metrics of the fonts we use and how text is encoded.
+ */
+
+package pdf
+
+import "unicode/utf8"
+
+// Font is one of the standard fonts every PDF reader has, so nothing needs to
+// be embedded.
+type Font int
+
+// The fonts we use.
+const (
+	Regular Font = iota
+	Bold
+	Italic
+)
+
+// The names of the fonts in the PDF, in the order of the Font constants.
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique"}
+
+// Widths of the printable ASCII characters, from space to tilde, in
+// thousandths of the font size. These are from Adobe's font metrics.
+// Helvetica-Oblique is as wide as Helvetica.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333,
+		278, 278, 556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278,
+		584, 584, 584, 556, 1015, 667, 667, 722, 722, 667, 611, 778, 722, 278,
+		500, 667, 556, 833, 722, 778, 667, 778, 722, 667, 611, 722, 667, 944,
+		667, 667, 611, 278, 278, 278, 469, 556, 333, 556, 556, 500, 556, 556,
+		278, 556, 556, 222, 222, 500, 222, 833, 556, 556, 556, 556, 333, 500,
+		278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333,
+		278, 278, 556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333,
+		584, 584, 584, 611, 975, 722, 722, 722, 722, 667, 611, 778, 722, 278,
+		556, 722, 611, 833, 722, 778, 667, 778, 722, 667, 611, 722, 667, 944,
+		667, 667, 611, 333, 278, 333, 584, 556, 333, 556, 611, 556, 611, 556,
+		333, 611, 611, 278, 278, 556, 278, 889, 611, 611, 611, 611, 389, 556,
+		333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// Characters WinAnsiEncoding puts from 0x80 to 0x9F, with their codes and
+// widths. Latin-1 characters from 0xA0 up keep their own codes.
+var winAnsi = map[rune]struct {
+	code  byte
+	width int
+}{
+	'€': {0x80, 556}, '‚': {0x82, 222}, 'ƒ': {0x83, 556}, '„': {0x84, 333},
+	'…': {0x85, 1000}, '†': {0x86, 556}, '‡': {0x87, 556}, 'ˆ': {0x88, 333},
+	'‰': {0x89, 1000}, 'Š': {0x8A, 667}, '‹': {0x8B, 333}, 'Œ': {0x8C, 1000},
+	'Ž': {0x8E, 611}, '‘': {0x91, 222}, '’': {0x92, 222}, '“': {0x93, 333},
+	'”': {0x94, 333}, '•': {0x95, 350}, '–': {0x96, 556}, '—': {0x97, 1000},
+	'˜': {0x98, 333}, '™': {0x99, 1000}, 'š': {0x9A, 500}, '›': {0x9B, 333},
+	'œ': {0x9C, 944}, 'ž': {0x9E, 500}, 'Ÿ': {0x9F, 667},
+}
+
+// Text that WinAnsiEncoding can't write, with what to write instead.
+var replacements = map[rune]string{
+	'⅓': "1/3", '⅔': "2/3", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+	'⅕': "1/5", '⅙': "1/6", '\u2009': " ", '\u202f': " ", '\u2010': "-",
+	'\u2011': "-", '\u2212': "-", '\t': " ",
+}
+
+// encode writes text in WinAnsiEncoding, with a ? for anything it can't.
+func encode(s string) []byte {
+	var out = make([]byte, 0, len(s))
+	for _, r := range s {
+		if text, ok := replacements[r]; ok {
+			out = append(out, text...)
+			continue
+		}
+		switch {
+		case r >= ' ' && r <= '~', r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case winAnsi[r].code != 0:
+			out = append(out, winAnsi[r].code)
+		case r == utf8.RuneError, r < ' ':
+			// Leave out control characters and invalid UTF-8.
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// charWidth returns the width of a character as encoded, in thousandths of
+// the font size.
+func charWidth(f Font, c byte) int {
+	var widths = &helveticaWidths
+	if f == Bold {
+		widths = &helveticaBoldWidths
+	}
+	switch {
+	case c >= ' ' && c <= '~':
+		return widths[c-' ']
+	case c < 0xA0:
+		for _, w := range winAnsi {
+			if w.code == c {
+				return w.width
+			}
+		}
+		return 0
+	default:
+		// Accented letters are about as wide as the letters they're made
+		// from, which is close enough for laying out text.
+		return 556
+	}
+}
+
+// Width returns how wide text is in a font, in points.
+func Width(f Font, size float64, s string) float64 {
+	var total = 0
+	for _, c := range encode(s) {
+		total += charWidth(f, c)
+	}
+	return float64(total) * size / 1000
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file writes PDF documents.
+ */
+
+// Package pdf writes simple PDF documents: pages of text in the standard
+// Helvetica fonts, lines, JPEG images, and links between pages. That's all
+// the cookbook needs, so we don't need a full PDF library.
+//
+// Coordinates are in points, 72 to the inch, from the bottom left corner of
+// the page, as PDF has them.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strconv"
+)
+
+// Page sizes, in points.
+const (
+	LetterWidth  = 612
+	LetterHeight = 792
+)
+
+// ErrNotJPEG is returned by AddJPEG for images it can't read.
+var ErrNotJPEG = errors.New("not a JPEG image")
+
+// Document is a PDF being built.
+type Document struct {
+	// Shown by PDF readers as the document's title.
+	Title  string
+	width  float64
+	height float64
+	pages  []*Page
+	images []*Image
+}
+
+// Page is one page of a Document.
+type Page struct {
+	// The page's number, counting from 1.
+	Number  int
+	content bytes.Buffer
+	images  map[*Image]bool
+	links   []link
+}
+
+// link is an area of a page that goes to another page when clicked.
+type link struct {
+	x, y, w, h float64
+	target     *Page
+}
+
+// Image is a JPEG added to a Document, which can be drawn on any of its
+// pages.
+type Image struct {
+	// Size in pixels.
+	Width  int
+	Height int
+	data   []byte
+	space  string
+	// The image's name in page resources.
+	name string
+}
+
+// New returns an empty document with pages of the given size.
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+// AddPage adds a blank page to the end of the document.
+func (d *Document) AddPage() *Page {
+	var p = &Page{Number: len(d.pages) + 1, images: make(map[*Image]bool)}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the pages added so far.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// AddJPEG adds an image to the document. JPEGs can go in a PDF as they are,
+// without decoding them.
+func (d *Document) AddJPEG(data []byte) (*Image, error) {
+	var config, err = jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNotJPEG
+	}
+	var img = &Image{
+		Width:  config.Width,
+		Height: config.Height,
+		data:   data,
+		name:   "Im" + strconv.Itoa(len(d.images)+1),
+	}
+	switch config.ColorModel {
+	case color.GrayModel:
+		img.space = "/DeviceGray"
+	case color.CMYKModel:
+		// Adobe writes CMYK JPEGs inverted.
+		img.space = "/DeviceCMYK /Decode [1 0 1 0 1 0 1 0]"
+	default:
+		img.space = "/DeviceRGB"
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// Text draws text with its baseline starting at x, y.
+func (p *Page) Text(x, y float64, f Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td ", int(f)+1, num(size),
+		num(x), num(y))
+	p.content.Write(literal(encode(s)))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// Gray sets the shade of what's drawn after, from 0 for black to 1 for
+// white.
+func (p *Page) Gray(level float64) {
+	fmt.Fprintf(&p.content, "%s g %s G\n", num(level), num(level))
+}
+
+// Line draws a line from x1, y1 to x2, y2.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width),
+		num(x1), num(y1), num(x2), num(y2))
+}
+
+// Image draws an image with its bottom left corner at x, y, stretched to w
+// by h.
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	p.images[img] = true
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(w), num(h),
+		num(x), num(y), img.name)
+}
+
+// Link makes the area with its bottom left corner at x, y and of size w by h
+// go to the top of another page of the same document.
+func (p *Page) Link(x, y, w, h float64, target *Page) {
+	p.links = append(p.links, link{x, y, w, h, target})
+}
+
+// num writes a number the way PDF reads it.
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// literal writes a PDF string.
+func literal(s []byte) []byte {
+	var out = make([]byte, 0, len(s)+2)
+	out = append(out, '(')
+	for _, c := range s {
+		if c == '(' || c == ')' || c == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, c)
+	}
+	return append(out, ')')
+}
+
+// writer numbers and writes a document's objects, keeping track of where
+// each starts.
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// reserve returns the number of an object that will be written later.
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+// object writes an object that was reserved.
+func (w *writer) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes a stream object that was reserved.
+func (w *writer) stream(id int, dict string, data []byte) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict,
+		len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// WriteTo writes the document as a PDF file. Content streams are left
+// uncompressed; they're small next to the images.
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	var w writer
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	var catalog, info, pages = w.reserve(), w.reserve(), w.reserve()
+
+	var fonts = make([]int, len(fontNames))
+	for i := range fontNames {
+		fonts[i] = w.reserve()
+	}
+	var images = make(map[*Image]int, len(d.images))
+	for _, img := range d.images {
+		images[img] = w.reserve()
+	}
+	var pageIDs = make(map[*Page]int, len(d.pages))
+	var contentIDs = make([]int, len(d.pages))
+	for i, p := range d.pages {
+		pageIDs[p] = w.reserve()
+		contentIDs[i] = w.reserve()
+	}
+
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.object(info, fmt.Sprintf("<< /Title %s /Producer (recipes) >>",
+		literal(encode(d.Title))))
+	var kids bytes.Buffer
+	for _, p := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", pageIDs[p])
+	}
+	w.object(pages, fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		bytes.TrimSpace(kids.Bytes()), len(d.pages), num(d.width),
+		num(d.height)))
+	for i, name := range fontNames {
+		w.object(fonts[i], fmt.Sprintf("<< /Type /Font /Subtype /Type1 "+
+			"/BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for _, img := range d.images {
+		w.stream(images[img], fmt.Sprintf("/Type /XObject /Subtype /Image "+
+			"/Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 "+
+			"/Filter /DCTDecode", img.Width, img.Height, img.space), img.data)
+	}
+
+	var fontRefs bytes.Buffer
+	for i := range fontNames {
+		fmt.Fprintf(&fontRefs, "/F%d %d 0 R ", i+1, fonts[i])
+	}
+	for i, p := range d.pages {
+		var resources bytes.Buffer
+		fmt.Fprintf(&resources, "/Font << %s>>", fontRefs.Bytes())
+		if len(p.images) != 0 {
+			resources.WriteString(" /XObject << ")
+			// Keep the order stable.
+			for _, img := range d.images {
+				if p.images[img] {
+					fmt.Fprintf(&resources, "/%s %d 0 R ", img.name, images[img])
+				}
+			}
+			resources.WriteString(">>")
+		}
+		var annots bytes.Buffer
+		if len(p.links) != 0 {
+			annots.WriteString(" /Annots [")
+			for _, l := range p.links {
+				fmt.Fprintf(&annots, "<< /Type /Annot /Subtype /Link "+
+					"/Rect [%s %s %s %s] /Border [0 0 0] "+
+					"/Dest [%d 0 R /Fit] >> ", num(l.x), num(l.y),
+					num(l.x+l.w), num(l.y+l.h), pageIDs[l.target])
+			}
+			annots.WriteString("]")
+		}
+		w.object(pageIDs[p], fmt.Sprintf("<< /Type /Page /Parent %d 0 R "+
+			"/Resources << %s >> /Contents %d 0 R%s >>", pages,
+			resources.Bytes(), contentIDs[i], annots.Bytes()))
+		w.stream(contentIDs[i], "", p.content.Bytes())
+	}
+
+	var xref = w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\n"+
+		"startxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalog, info, xref)
+	return w.buf.WriteTo(out)
+}
+
+// Fit returns the size to draw an image at to fit within w by h without
+// changing its shape.
+func Fit(img *Image, w, h float64) (float64, float64) {
+	var aspect = float64(img.Width) / float64(img.Height)
+	if w/h > aspect {
+		return h * aspect, h
+	}
+	return w, w / aspect
+}
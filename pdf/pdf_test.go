@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	var doc = New(LetterWidth, LetterHeight)
+	doc.Title = "Pies (and tarts)"
+	var first = doc.AddPage()
+	var second = doc.AddPage()
+	first.Text(72, 700, Bold, 12, `Crème brûlée (½ batch) \ more`)
+	first.Link(72, 690, 100, 20, second)
+
+	var buf bytes.Buffer
+	var err = jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := doc.AddJPEG(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 4 || img.Height != 3 {
+		t.Errorf("got image size %dx%d, want 4x3", img.Width, img.Height)
+	}
+	second.Image(img, 72, 72, 40, 30)
+	_, err = doc.AddJPEG([]byte("not an image"))
+	if err != ErrNotJPEG {
+		t.Errorf("got %v for a bad image, want ErrNotJPEG", err)
+	}
+
+	buf.Reset()
+	_, err = doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = buf.Bytes()
+
+	for _, want := range []string{
+		"(Cr\xe8me br\xfbl\xe9e \\(\xbd batch\\) \\\\ more) Tj",
+		"/Title (Pies \\(and tarts\\))",
+		"/Count 2",
+		"/Subtype /Link",
+		"/Im1 Do",
+		"/ColorSpace /DeviceRGB",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+
+	// Every object must be where the cross-reference table says.
+	var m = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref at the end")
+	}
+	var xref, _ = strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d doesn't point at the table", xref)
+	}
+	var entries = regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(
+		out[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("no objects in the table")
+	}
+	for i, e := range entries {
+		var offset, _ = strconv.Atoi(string(e[1]))
+		var want = strconv.Itoa(i+1) + " 0 obj\n"
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("object %d isn't at offset %d", i+1, offset)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	var cases = []struct {
+		font Font
+		text string
+		want float64
+	}{
+		{Regular, "Hi", (722 + 222) * 10 / 1000.0},
+		{Bold, "Hi", (722 + 278) * 10 / 1000.0},
+		{Italic, "Hi", (722 + 222) * 10 / 1000.0},
+		// Thirds aren't in the encoding, so they're written out.
+		{Regular, "⅓", (556 + 278 + 556) * 10 / 1000.0},
+	}
+	for _, c := range cases {
+		if got := Width(c.font, 10, c.text); got != c.want {
+			t.Errorf("Width(%d, %q) = %g, want %g", c.font, c.text, got, c.want)
+		}
+	}
+}
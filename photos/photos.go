@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file turns uploaded photos into the sizes we serve.
+ */
+
+// Package photos processes uploaded recipe photos into the images we store
+// and serve.
+package photos
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	// Register the other formats we accept for uploads.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ErrUnsupported is returned for uploads we can't decode as an image.
+var ErrUnsupported = errors.New("unsupported image format")
+
+// ErrTooLarge is returned for images with more than MaxPixels.
+var ErrTooLarge = errors.New("image has too many pixels")
+
+// The most pixels we'll decode. Decoding takes memory in proportion to them,
+// and a small file can claim to be enormous.
+const MaxPixels = 50000000
+
+// The largest dimensions we keep, in pixels. Larger uploads are scaled down.
+const (
+	FullSize  = 1600
+	ThumbSize = 400
+)
+
+// JPEG quality for the images we make.
+const jpegQuality = 85
+
+// Processed holds the images made from one upload. Re-encoding leaves out any
+// EXIF or other metadata from the original.
+type Processed struct {
+	// Width and height of the full size image.
+	Width  int
+	Height int
+	// Full size JPEG.
+	Full []byte
+	// Thumbnail JPEG.
+	ThumbJPEG []byte
+}
+
+// Process decodes an uploaded JPEG, PNG, or GIF, turns it upright according to
+// its EXIF orientation, and encodes the sizes we serve. Images larger than
+// MaxPixels are ErrTooLarge, and aren't decoded.
+func Process(data []byte) (*Processed, error) {
+	var config, _, err = image.DecodeConfig(bytes.NewReader(data))
+	if err == image.ErrFormat {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	// Flatten onto white, since JPEG has no transparency.
+	var b = img.Bounds()
+	var rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Over)
+	rgba = orient(rgba, jpegOrientation(data))
+
+	var p Processed
+	var full = fit(rgba, FullSize)
+	p.Width, p.Height = full.Rect.Dx(), full.Rect.Dy()
+	p.Full, err = encodeJPEG(full)
+	if err != nil {
+		return nil, err
+	}
+	var thumb = fit(full, ThumbSize)
+	p.ThumbJPEG, err = encodeJPEG(thumb)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	var err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	return buf.Bytes(), err
+}
+
+// fit scales an image down to fit in a square of the given size, keeping its
+// shape. Smaller images are returned as they are.
+func fit(src *image.RGBA, size int) *image.RGBA {
+	var w, h = src.Rect.Dx(), src.Rect.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	var dw, dh = size, h * size / w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	return resize(src, dw, dh)
+}
+
+// resize scales an image down by averaging the block of source pixels under
+// each destination pixel.
+func resize(src *image.RGBA, dw, dh int) *image.RGBA {
+	var sw, sh = src.Rect.Dx(), src.Rect.Dy()
+	var dst = image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		var y0, y1 = y * sh / dh, (y + 1) * sh / dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			var x0, x1 = x * sw / dw, (x + 1) * sw / dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				var row = src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					var i = row + 4*sx
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+				}
+			}
+			var n = (y1 - y0) * (x1 - x0)
+			var d = dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[d+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,88 @@
+package photos
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation inserts an EXIF segment with the orientation into a JPEG.
+func withOrientation(data []byte, orientation byte) []byte {
+	var tiff = []byte("MM\x00\x2a\x00\x00\x00\x08" +
+		"\x00\x01" + // One entry.
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string(orientation) +
+		"\x00\x00" +
+		"\x00\x00\x00\x00") // No next IFD.
+	var payload = append([]byte("Exif\x00\x00"), tiff...)
+	var length = len(payload) + 2
+	var segment = append([]byte{0xff, 0xe1, byte(length >> 8), byte(length)},
+		payload...)
+	var out = append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	// A wide image, with the left half red.
+	var img = image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 2000; x++ {
+			if x < 1000 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 means the camera was turned; the result should be tall.
+	var p, err = Process(withOrientation(buf.Bytes(), 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 800 || p.Height != FullSize {
+		t.Errorf("full size is %dx%d", p.Width, p.Height)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(p.ThumbJPEG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 200 || b.Dy() != ThumbSize {
+		t.Errorf("thumbnail is %dx%d", b.Dx(), b.Dy())
+	}
+	// The left half of the original is now on top.
+	var r, _, b, _ = thumb.At(100, 50).RGBA()
+	if r < b {
+		t.Errorf("thumbnail wasn't rotated")
+	}
+	// The EXIF data shouldn't survive.
+	if bytes.Contains(p.Full, []byte("Exif")) {
+		t.Errorf("EXIF data wasn't stripped")
+	}
+
+	if _, err = Process([]byte("not an image")); err != ErrUnsupported {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestProcessTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	var img = image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{
+		color.White})
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Claim to be 65535 pixels square, though there's only one pixel.
+	var data = buf.Bytes()
+	copy(data[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := Process(data); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains access checks shared by several handlers.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// checkRecipeAccess reads the recipe ID from the URL and makes sure the user
+// may change that recipe. If child names another URL parameter, such as the
+// ID of one of the recipe's photos, that is read too. If anything is wrong,
+// it writes the error response and returns false.
+func checkRecipeAccess(res http.ResponseWriter, req *http.Request,
+	child string) (*defs.User, int, int, bool) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return nil, 0, 0, false
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return nil, 0, 0, false
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return nil, 0, 0, false
+	}
+
+	// Get id parameters.
+	var params = mux.Vars(req)
+	recipeID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		res.WriteHeader(400)
+		return nil, 0, 0, false
+	}
+	var childID int
+	if child != "" {
+		childID, err = strconv.Atoi(params[child])
+		if err != nil {
+			res.WriteHeader(400)
+			return nil, 0, 0, false
+		}
+	}
+
+	// Only the author may change the recipe, unless the user has sufficient
+	// privileges.
+	recipe, err := db.FetchRecipe(recipeID, viewerFor(usr))
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return nil, 0, 0, false
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return nil, 0, 0, false
+	}
+	var force = usr.Role == "Admin" || usr.Role == "Moderator"
+	if !force && recipe.AuthorID != usr.ID {
+		res.WriteHeader(403)
+		return nil, 0, 0, false
+	}
+	return usr, recipeID, childID, true
+}
+
+// viewerFor is who a user reads recipes as. A nil user isn't logged in.
+func viewerFor(usr *defs.User) defs.Viewer {
+	if usr == nil {
+		return defs.Viewer{}
+	}
+	return defs.Viewer{UserID: usr.ID,
+		All: usr.Role == "Admin" || usr.Role == "Moderator"}
+}
+
+// checkViewer finds who is reading recipes, whether or not they're logged in,
+// so private recipes are only shown to those who may see them. If that fails,
+// it writes the error response and returns false.
+func checkViewer(res http.ResponseWriter, req *http.Request) (defs.Viewer,
+	bool) {
+	var usr, err = checkAuth(res, req)
+	if err != nil && err != http.ErrNoCookie {
+		log.Println(err)
+		res.WriteHeader(500)
+		return defs.Viewer{}, false
+	}
+	return viewerFor(usr), true
+}
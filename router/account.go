@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for exporting a user's data.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/exporter"
+)
+
+// handleUserExport sends a zip file of everything kept about a user: their
+// profile and their recipes, with photos and attachments. Users may export
+// their own data, and admins anyone's.
+// GET /users/4/export
+func handleUserExport(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+
+	// Get id parameter.
+	var params = mux.Vars(req)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		res.WriteHeader(400)
+		return
+	}
+	if usr.Role != "Admin" && usr.ID != id {
+		res.WriteHeader(403)
+		return
+	}
+
+	var account defs.Account
+	user, err := db.FetchUser(id)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	account.User = *user
+	account.Recipes, err = db.FetchRecipes(defs.ItemFilter{AuthorID: id,
+		Viewer: viewerFor(usr)})
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	account.Revisions, err = db.FetchRevisions(id)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	account.Ratings, err = db.FetchUserRatings(id)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/zip")
+	res.Header().Set("Content-Disposition",
+		`attachment; filename="recipes-user-`+strconv.Itoa(id)+`.zip"`)
+	// The zip file is streamed, so it's too late to send an error status if
+	// this fails.
+	err = exporter.Account(res, &account, blobs)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for importing other apps' exports.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/importer"
+	"github.com/rwestlund/recipes/search"
+)
+
+// findAuthor returns the user named by an export, by name or email.
+func findAuthor(users []defs.User, name string) *defs.User {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	for i := range users {
+		if strings.EqualFold(users[i].Name, name) ||
+			strings.EqualFold(users[i].Email, name) {
+			return &users[i]
+		}
+	}
+	return nil
+}
+
+// handleImportApp imports every recipe in an export from another app,
+// uploaded as with the other imports. By default nothing is saved, and the
+// report says what would be created, skipped, or left out as a duplicate of a
+// recipe we have or one earlier in the export. With save=true, the recipes
+// are created along with their photos, and allow_duplicate=true creates
+// duplicates too.
+//
+// Admins and moderators can import recipes for others: one whose author in
+// the export matches a user's name or email is credited to them. Everything
+// else is credited to whoever is importing.
+// POST /recipes/import/paprika?save=true
+func handleImportApp(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var read, ok = importer.Apps[mux.Vars(req)["format"]]
+	if !ok {
+		res.WriteHeader(404)
+		return
+	}
+	data, ok := readImport(res, req, importer.MaxArchiveSize)
+	if !ok {
+		return
+	}
+	list, err := read(data)
+	if err == importer.ErrTooLarge {
+		res.WriteHeader(413)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+
+	var users []defs.User
+	if usr.Role == "Admin" || usr.Role == "Moderator" {
+		users, err = db.FetchUsers(defs.ItemFilter{})
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+	}
+	candidates, err := db.FetchRecipes(
+		defs.ItemFilter{Viewer: viewerFor(usr)})
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
+	var save, _ = strconv.ParseBool(req.URL.Query().Get("save"))
+	var allow = allowDuplicates(req)
+	var report = defs.ImportReport{DryRun: !save,
+		Results: make([]defs.ImportResult, 0, len(list))}
+	for _, item := range list {
+		var result = defs.ImportResult{
+			Title:      item.Recipe.Title,
+			Action:     defs.ImportCreate,
+			AuthorID:   usr.ID,
+			AuthorName: item.Author,
+			Photos:     len(item.Photos),
+		}
+		if author := findAuthor(users, item.Author); author != nil {
+			result.AuthorID = author.ID
+		}
+		switch {
+		case item.Err != nil:
+			result.Action = defs.ImportSkip
+			result.Reason = item.Err.Error()
+		case item.Recipe.Title == "":
+			result.Action = defs.ImportSkip
+			result.Reason = "recipe has no title"
+		default:
+			result.Duplicates = search.Duplicates(item.Recipe, candidates)
+			if len(result.Duplicates) != 0 && !allow {
+				result.Action = defs.ImportDuplicate
+			}
+		}
+
+		if result.Action == defs.ImportCreate && save {
+			var saved, err = db.ImportRecipe(item.Recipe, result.AuthorID)
+			if err != nil {
+				log.Println(err)
+				result.Action = defs.ImportSkip
+				result.Reason = "could not be saved"
+			} else {
+				result.RecipeID = saved.ID
+				*item.Recipe = *saved
+				for _, data := range item.Photos {
+					if _, err := storePhoto(saved.ID, data); err != nil {
+						log.Println("photo for recipe", saved.ID, err)
+					}
+				}
+			}
+		}
+		// Later recipes in the export are checked against this one too.
+		if result.Action == defs.ImportCreate {
+			candidates = append(candidates, *item.Recipe)
+		}
+
+		switch result.Action {
+		case defs.ImportCreate:
+			report.Created++
+		case defs.ImportSkip:
+			report.Skipped++
+		case defs.ImportDuplicate:
+			report.Duplicates++
+		}
+		report.Results = append(report.Results, result)
+	}
+	if save {
+		log.Println("user", usr.ID, "imported", report.Created, "recipes from",
+			mux.Vars(req)["format"])
+	}
+
+	j, e := json.Marshal(report)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
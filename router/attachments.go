@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for recipe attachments, such as photos of
+ * individual steps or a scan of the original recipe card.
+ */
+
+package router
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// The largest attachment we'll accept, in bytes.
+const maxAttachmentSize = 20 << 20
+
+// The kinds of files that may be attached, by sniffed content type.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// errAttachmentType is returned for files of a kind we don't accept.
+var errAttachmentType = errors.New("unsupported attachment type")
+
+// deleteAttachmentBlob removes an attachment from blob storage. Failures are
+// only logged, since the database no longer refers to it.
+func deleteAttachmentBlob(id int) {
+	var err = blobs.Delete(blob.AttachmentKey(id))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// attachmentName cleans up the name of an uploaded file for use in a
+// Content-Disposition header.
+func attachmentName(name string) string {
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	return name
+}
+
+// saveAttachment checks one uploaded file and stores it for a recipe.
+func saveAttachment(recipeID int,
+	fh *multipart.FileHeader) (*defs.Attachment, error) {
+	var f, err = fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	// Trust the contents, not whatever type the client claimed.
+	var contentType = http.DetectContentType(data)
+	if !attachmentTypes[contentType] {
+		return nil, errAttachmentType
+	}
+
+	attachment, err := db.CreateAttachment(recipeID, attachmentName(fh.Filename),
+		contentType, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	err = blobs.Put(blob.AttachmentKey(attachment.ID), data, contentType)
+	if err != nil {
+		// Don't leave a record of a file we can't serve.
+		if e := db.DeleteAttachment(recipeID, attachment.ID); e != nil {
+			log.Println(e)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// handlePostAttachments adds attachments to a recipe from a multipart upload,
+// with each file in a "files" field. It responds with the new attachments,
+// whose IDs can then be added to the recipe's directions.
+// POST /recipes/4/attachments
+func handlePostAttachments(res http.ResponseWriter, req *http.Request) {
+	var _, id, _, ok = checkRecipeAccess(res, req, "")
+	if !ok {
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Leave room for the multipart framing around the file.
+	var limit int64 = maxAttachmentSize + 1<<20
+	if req.ContentLength > limit {
+		res.WriteHeader(413)
+		return
+	}
+	req.Body = http.MaxBytesReader(res, req.Body, limit)
+	var err = req.ParseMultipartForm(limit)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	defer req.MultipartForm.RemoveAll()
+	var files = req.MultipartForm.File["files"]
+	if len(files) == 0 {
+		res.WriteHeader(400)
+		return
+	}
+	for _, fh := range files {
+		if fh.Size > maxAttachmentSize {
+			res.WriteHeader(413)
+			return
+		}
+	}
+
+	var saved = make([]defs.Attachment, 0, len(files))
+	for _, fh := range files {
+		attachment, err := saveAttachment(id, fh)
+		if err == errAttachmentType {
+			res.WriteHeader(415)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+		saved = append(saved, *attachment)
+	}
+
+	j, e := json.Marshal(saved)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleGetAttachment downloads an attachment. Only logged-in users may
+// download attachments. Range requests are supported.
+// GET /attachments/7
+func handleGetAttachment(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+
+	// Get id parameter.
+	var params = mux.Vars(req)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	attachment, err := db.FetchAttachment(id, viewerFor(usr))
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	data, err := blobs.Get(blob.AttachmentKey(id))
+	if err == blob.ErrNotFound {
+		res.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
+	res.Header().Set("Content-Type", attachment.ContentType)
+	// Browsers must not second-guess the type we sniffed.
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	res.Header().Set("Content-Disposition", mime.FormatMediaType("inline",
+		map[string]string{"filename": attachment.Filename}))
+	res.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(data))
+}
+
+// handleDeleteAttachment removes an attachment from a recipe and from any
+// directions that show it.
+// DELETE /recipes/4/attachments/7
+func handleDeleteAttachment(res http.ResponseWriter, req *http.Request) {
+	var usr, recipeID, attachmentID, ok = checkRecipeAccess(res, req,
+		"attachment")
+	if !ok {
+		return
+	}
+	var err = db.DeleteAttachment(recipeID, attachmentID)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	deleteAttachmentBlob(attachmentID)
+	log.Println("user", usr.ID, "deleted attachment", attachmentID)
+	res.WriteHeader(200)
+}
@@ -0,0 +1,19 @@
+package router
+
+import "testing"
+
+func TestAttachmentName(t *testing.T) {
+	var cases = []struct{ in, want string }{
+		{"card.pdf", "card.pdf"},
+		{"C:\\Users\\me\\card.pdf", "card.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{"line\nbreak.png", "linebreak.png"},
+		{"", "attachment"},
+		{"/", "attachment"},
+	}
+	for _, c := range cases {
+		if got := attachmentName(c.in); got != c.want {
+			t.Errorf("attachmentName(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
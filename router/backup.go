@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for backups.
+ */
+
+package router
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rwestlund/recipes/backup"
+	"github.com/rwestlund/recipes/db"
+)
+
+// handleBackup sends a backup archive of everything in the cookbook. Only
+// admins may make one, since it has every user's email.
+// GET /backup
+func handleBackup(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" {
+		res.WriteHeader(403)
+		return
+	}
+
+	b, err := db.FetchBackup()
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	var name = "recipes-backup-" + time.Now().Format("2006-01-02") + ".zip"
+	res.Header().Set("Content-Type", "application/zip")
+	res.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	// The archive is streamed, so it's too late to send an error status if
+	// this fails.
+	err = backup.Write(res, b, blobs)
+	if err != nil {
+		log.Println(err)
+	}
+}
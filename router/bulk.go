@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for changing many recipes at once.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// The most a bulk change request body may be, in bytes.
+const maxBulkSize = 1 << 20
+
+// handleBulkRecipes makes a change to many recipes at once: adding and
+// removing tags, giving them to another author, making them public or
+// private, or deleting them. The body is
+// a defs.BulkChange. Its ids choose the recipes; without any, the change is
+// made to every recipe matching the same filter parameters as GET /recipes,
+// of which there must be at least one.
+//
+// By default nothing is saved, and the report says what would happen to each
+// recipe. With save=true, the change is made to all of them at once or, if
+// anything fails, to none. Recipes the user may not change are skipped, as in
+// PUT /recipes/3. Only admins and moderators may change authors.
+// POST /recipes/bulk?tags=cake&save=true
+func handleBulkRecipes(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	var force = usr.Role == "Admin" || usr.Role == "Moderator"
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var change defs.BulkChange
+	err = json.NewDecoder(io.LimitReader(req.Body, maxBulkSize)).Decode(&change)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	if code := checkBulkChange(&change, force); code != 0 {
+		res.WriteHeader(code)
+		return
+	}
+
+	var ids = change.IDs
+	if len(ids) == 0 {
+		var filter, err = buildItemFilter(req.URL)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(400)
+			return
+		}
+		// Without ids or a filter, this would change every recipe, which is
+		// far more likely a mistake than what's meant.
+		if !narrowsRecipes(filter) {
+			res.WriteHeader(400)
+			return
+		}
+		// Every match the user may see, not a page of them.
+		filter.Count, filter.Skip, filter.Cursor = 0, 0, ""
+		filter.Viewer = viewerFor(usr)
+		recipes, err := db.FetchRecipes(filter)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+		for _, r := range recipes {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	var save, _ = strconv.ParseBool(req.URL.Query().Get("save"))
+	report, err := db.BulkChangeRecipes(&change, ids, usr.ID, force, !save)
+	if err == db.ErrNoSuchUser {
+		res.WriteHeader(400)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	// The database no longer refers to the files of deleted recipes.
+	for _, id := range report.DeletedPhotoIDs {
+		deletePhotoBlobs(id)
+	}
+	for _, id := range report.DeletedAttachmentIDs {
+		deleteAttachmentBlob(id)
+	}
+	if save {
+		log.Printf("user %d changed %d recipes and deleted %d in bulk",
+			usr.ID, report.Changed, report.Deleted)
+	}
+
+	j, e := json.Marshal(report)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// checkBulkChange checks that a bulk change makes sense and that the user may
+// make it, returning the status to respond with if not, or zero if so. Force
+// is whether the user may change anyone's recipes.
+func checkBulkChange(change *defs.BulkChange, force bool) int {
+	var edits = len(change.AddTags) > 0 || len(change.RemoveTags) > 0 ||
+		change.AuthorID != 0 || change.Visibility != ""
+	if change.Visibility != "" && !defs.ValidVisibility(change.Visibility) {
+		return 400
+	}
+	if change.Delete == edits {
+		// Either nothing to do, or deleting along with something else.
+		return 400
+	}
+	if change.AuthorID != 0 && !force {
+		return 403
+	}
+	return 0
+}
+
+// narrowsRecipes reports whether a filter matches fewer than all recipes, or
+// at least might. Paging and sorting don't count.
+func narrowsRecipes(filter defs.ItemFilter) bool {
+	return strings.TrimSpace(filter.Query) != "" ||
+		len(filter.AllTags) > 0 || len(filter.AnyTags) > 0 ||
+		len(filter.NoTags) > 0 || filter.AuthorID != 0 ||
+		len(filter.Ingredients) > 0 || len(filter.NoIngredients) > 0 ||
+		filter.MaxMinutes != 0 || filter.HasPhoto.Valid ||
+		filter.MinRating != 0 ||
+		!filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() ||
+		!filter.ModifiedAfter.IsZero() || !filter.ModifiedBefore.IsZero()
+}
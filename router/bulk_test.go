@@ -0,0 +1,67 @@
+package router
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestCheckBulkChange(t *testing.T) {
+	var cases = []struct {
+		change defs.BulkChange
+		force  bool
+		want   int
+	}{
+		{defs.BulkChange{AddTags: []string{"cake"}}, false, 0},
+		{defs.BulkChange{RemoveTags: []string{"cake"}}, false, 0},
+		{defs.BulkChange{Delete: true}, false, 0},
+		{defs.BulkChange{AuthorID: 2}, true, 0},
+		{defs.BulkChange{Visibility: defs.VisibilityPrivate}, false, 0},
+		{defs.BulkChange{Visibility: "secret"}, true, 400},
+		{defs.BulkChange{Delete: true, Visibility: "public"}, true, 400},
+		// Nothing to do.
+		{defs.BulkChange{IDs: []int{1, 2}}, true, 400},
+		// Deleting along with something else.
+		{defs.BulkChange{Delete: true, AddTags: []string{"cake"}}, true, 400},
+		{defs.BulkChange{Delete: true, AuthorID: 2}, true, 400},
+		// Only admins and moderators may change authors.
+		{defs.BulkChange{AuthorID: 2}, false, 403},
+		{defs.BulkChange{AuthorID: 2, AddTags: []string{"cake"}}, false, 403},
+	}
+	for _, c := range cases {
+		if got := checkBulkChange(&c.change, c.force); got != c.want {
+			t.Errorf("%+v, force %v: got %d, want %d", c.change, c.force,
+				got, c.want)
+		}
+	}
+}
+
+func TestNarrowsRecipes(t *testing.T) {
+	var cases = map[string]bool{
+		"":                         false,
+		"save=true":                false,
+		"sort=time&order=desc":     false,
+		"count=10&skip=2":          false,
+		"query=%20":                false,
+		"tags=":                    false,
+		"tags=cake":                true,
+		"query=pie":                true,
+		"author=3":                 true,
+		"has_photo=false":          true,
+		"min_rating=4":             true,
+		"created_after=2017-01-02": true,
+		"no_ingredients=nuts":      true,
+	}
+	for query, want := range cases {
+		var u, _ = url.Parse("/recipes/bulk?" + query)
+		var filter, err = buildItemFilter(u)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		if got := narrowsRecipes(filter); got != want {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for printable cookbooks.
+ */
+
+package router
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/cookbook"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// handleCookbook makes a printable PDF cookbook. The recipes are chosen by
+// the ids parameter, a comma-separated list printed in that order, or else
+// by the same filter parameters as GET /recipes. The title and subtitle
+// parameters go on the title page.
+// GET /cookbook.pdf?tags=holiday&title=Family%20Cookbook
+// GET /cookbook.pdf?ids=4,12,7
+func handleCookbook(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	var query = req.URL.Query()
+	var recipes []defs.Recipe
+	if ids := listParam(query, "ids"); len(ids) != 0 {
+		for _, param := range ids {
+			var id, err = strconv.Atoi(param)
+			if err != nil {
+				log.Println(err)
+				res.WriteHeader(400)
+				return
+			}
+			recipe, err := db.FetchRecipe(id, viewer)
+			if err == sql.ErrNoRows {
+				res.WriteHeader(404)
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				res.WriteHeader(500)
+				return
+			}
+			recipes = append(recipes, *recipe)
+		}
+	} else {
+		var filter, err = buildItemFilter(req.URL)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(400)
+			return
+		}
+		filter.Viewer = viewer
+		recipes, err = db.FetchRecipes(filter)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+	}
+
+	var book = cookbook.Book{
+		Title:    query.Get("title"),
+		Subtitle: query.Get("subtitle"),
+		Recipes:  recipes,
+		Photo: func(id int) ([]byte, error) {
+			var data, err = blobs.Get(blob.PhotoKey(id, "full.jpg"))
+			if err != nil {
+				log.Println("photo", id, err)
+			}
+			return data, err
+		},
+	}
+	if book.Title == "" {
+		book.Title = "Recipes"
+	}
+	var buf bytes.Buffer
+	var err = cookbook.Write(&buf, &book)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Header().Set("Content-Type", "application/pdf")
+	res.Header().Set("Content-Disposition", `inline; filename="cookbook.pdf"`)
+	res.Write(buf.Bytes())
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for reading and writing recipes as
+ * Cooklang.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/rwestlund/recipes/cooklang"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/importer"
+)
+
+// handleRecipeCooklang returns a recipe as Cooklang.
+// GET /recipes/3.cook, GET /recipes/banana-bread.cook
+func handleRecipeCooklang(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	res.Write(cooklang.FromRecipe(recipe).Bytes())
+}
+
+// handleImportCooklang imports a recipe written in Cooklang, either uploaded
+// or sent as the request body. Cooklang files are often named for the recipe
+// instead of having a title, so the title parameter gives one. A file that
+// can't be read is refused with 422 and a list of problems by line number.
+// Otherwise, see respondImport for what comes back; the preview includes the
+// ingredients, cookware, and timers marked up in the file.
+// POST /recipes/import/cooklang?title=Pancakes
+func handleImportCooklang(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var data, ok = readImport(res, req, importer.MaxPageSize)
+	if !ok {
+		return
+	}
+	file, err := cooklang.Parse(data)
+	if errs, ok := err.(cooklang.Errors); ok {
+		writeImportErrors(res, errs)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	var recipe = file.ToRecipe()
+	if recipe.Title == "" {
+		recipe.Title = req.URL.Query().Get("title")
+	}
+	if recipe.Title == "" {
+		writeImportErrors(res, cooklang.Errors{{Line: 1,
+			Message: "no title in the metadata or the title parameter"}})
+		return
+	}
+	respondImport(res, req, usr, &defs.ImportPreview{
+		Recipe:      recipe,
+		Ingredients: file.Ingredients(),
+		Cookware:    file.Cookware(),
+		Timers:      file.Timers(),
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains helpers and HTTP handlers for finding duplicate recipes.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/search"
+)
+
+// allowDuplicates reports whether the request asks to create a recipe even if
+// it looks like one we have, with allow_duplicate=true.
+func allowDuplicates(req *http.Request) bool {
+	var allow, _ = strconv.ParseBool(req.URL.Query().Get("allow_duplicate"))
+	return allow
+}
+
+// rejectDuplicates looks for recipes the viewer may see that are likely the
+// same as a new one. If there are any, it responds with 409 and the list of
+// them, and returns true. It also returns true if it wrote an error response.
+func rejectDuplicates(res http.ResponseWriter, recipe *defs.Recipe,
+	viewer defs.Viewer) bool {
+	var candidates, err = db.FetchRecipes(defs.ItemFilter{Viewer: viewer})
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return true
+	}
+	var duplicates = search.Duplicates(recipe, candidates)
+	if len(duplicates) == 0 {
+		return false
+	}
+	j, e := json.Marshal(duplicates)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return true
+	}
+	res.WriteHeader(409)
+	res.Write(j)
+	return true
+}
+
+// handleDuplicates lists pairs of recipes that are likely the same, so they
+// can be cleaned up.
+// GET /recipes/duplicates
+func handleDuplicates(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	recipes, err := db.FetchRecipes(defs.ItemFilter{Viewer: viewerFor(usr)})
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	j, e := json.Marshal(search.DuplicatePairs(recipes))
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
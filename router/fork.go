@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for forking recipes and comparing forks
+ * to their parents.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/diff"
+)
+
+// handleForkRecipe copies a recipe into a new one owned by the current user,
+// which remembers the original as its parent. It responds with the new
+// recipe.
+// POST /recipes/3/fork
+func handleForkRecipe(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Get id parameter.
+	var params = mux.Vars(req)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+
+	var force = usr.Role == "Admin" || usr.Role == "Moderator"
+	recipe, err := db.ForkRecipe(id, usr.ID, force)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	j, e := json.Marshal(recipe)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleRecipeDiff compares a forked recipe with the current version of its
+// parent.
+// GET /recipes/4/diff
+func handleRecipeDiff(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	// Only forks have something to compare with.
+	if recipe.Parent == nil {
+		res.WriteHeader(404)
+		return
+	}
+	parent, err := db.FetchRecipe(recipe.Parent.ID, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
+	var d = diff.Recipes(parent, recipe)
+	d.Parent = *recipe.Parent
+	j, e := json.Marshal(d)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for the graph of linked recipes.
+ */
+
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// How each kind of link is drawn in DOT.
+var dotEdgeStyles = map[string]string{
+	"":                 "solid",
+	defs.LinkComponent: "bold",
+	defs.LinkVariation: "dashed",
+	defs.LinkServeWith: "dotted",
+}
+
+// dotQuote makes a DOT string literal.
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// writeDOT writes a recipe graph in Graphviz's DOT language.
+func writeDOT(buf *bytes.Buffer, graph *defs.RecipeGraph) {
+	buf.WriteString("digraph recipes {\n")
+	buf.WriteString("\tnode [shape=box];\n")
+	for _, n := range graph.Nodes {
+		fmt.Fprintf(buf, "\tr%d [label=%s];\n", n.ID, dotQuote(n.Title))
+	}
+	for _, e := range graph.Edges {
+		var label = e.Role
+		if e.Quantity != "" {
+			label += " (" + e.Quantity + ")"
+		}
+		fmt.Fprintf(buf, "\tr%d -> r%d [label=%s, style=%s];\n", e.Src,
+			e.Dest, dotQuote(label), dotEdgeStyles[e.Role])
+	}
+	buf.WriteString("}\n")
+}
+
+// handleRecipeGraph returns every recipe the user may see and the links between them, as JSON
+// or, with format=dot or an Accept header asking for it, as Graphviz DOT.
+// Set linked=true to leave out recipes without links.
+// GET /recipes/graph?format=dot
+func handleRecipeGraph(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	var query = req.URL.Query()
+	var linkedOnly, _ = strconv.ParseBool(query.Get("linked"))
+	var graph, err = db.FetchRecipeGraph(linkedOnly, viewer)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
+	if query.Get("format") == "dot" ||
+		strings.Contains(req.Header.Get("Accept"), "text/vnd.graphviz") {
+		var buf bytes.Buffer
+		writeDOT(&buf, graph)
+		res.Header().Set("Content-Type", "text/vnd.graphviz; charset=UTF-8")
+		res.Write(buf.Bytes())
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	j, e := json.Marshal(graph)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
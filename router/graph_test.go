@@ -0,0 +1,32 @@
+package router
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestWriteDOT(t *testing.T) {
+	var graph = defs.RecipeGraph{
+		Nodes: []defs.GraphNode{
+			{ID: 1, Title: `Grandma's "Best" Pie`},
+			{ID: 2, Title: "Crust"},
+		},
+		Edges: []defs.GraphEdge{
+			{Src: 1, Dest: 2, Role: defs.LinkComponent, Quantity: "1 batch"},
+		},
+	}
+	var buf bytes.Buffer
+	writeDOT(&buf, &graph)
+	var want = `digraph recipes {
+	node [shape=box];
+	r1 [label="Grandma's \"Best\" Pie"];
+	r2 [label="Crust"];
+	r1 -> r2 [label="component (1 batch)", style=bold];
+}
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
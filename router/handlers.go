@@ -10,42 +10,193 @@ package router
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/config"
 	"github.com/rwestlund/recipes/db"
 	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/exporter"
+	"github.com/rwestlund/recipes/search"
+	null "gopkg.in/guregu/null.v3"
 )
 
 // buildItemFilter takes a url.URL object (from req.URL) and fills an ItemFilter.
-func buildItemFilter(url *url.URL) defs.ItemFilter {
+// It returns an error if a structured filter parameter is malformed.
+//
+// A cursor parameter, taken from a previous response, replaces skip for
+// paging. Recipe listings also understand these parameters, where lists are
+// comma separated and dates are either 2006-01-02 or RFC 3339:
+//
+//	tags, any_tags, no_tags        tags that must all, any, or none be present
+//	author                         ID of the author
+//	ingredients, no_ingredients    text that must or must not be in ingredients
+//	max_time                       upper bound on time in minutes
+//	has_photo                      true or false
+//	min_rating                     lower bound on the average rating, 1 to 5
+//	created_after, created_before, modified_after, modified_before
+//	sort                           title, created, modified, or time
+//	order                          asc or desc
+func buildItemFilter(url *url.URL) (defs.ItemFilter, error) {
+	var query = url.Query()
 	// We can ignore the error because count=0 means disabled.
-	var count, _ = strconv.Atoi(url.Query().Get("count"))
-	var skip, _ = strconv.Atoi(url.Query().Get("skip"))
+	var count, _ = strconv.Atoi(query.Get("count"))
+	var skip, _ = strconv.Atoi(query.Get("skip"))
 	// Build ItemFilter from query params.
 	var filter = defs.ItemFilter{
-		Query: url.Query().Get("query"),
-		Count: count,
-		Skip:  skip,
+		Query:         query.Get("query"),
+		Count:         count,
+		Skip:          skip,
+		Cursor:        query.Get("cursor"),
+		AllTags:       listParam(query, "tags"),
+		AnyTags:       listParam(query, "any_tags"),
+		NoTags:        listParam(query, "no_tags"),
+		Ingredients:   listParam(query, "ingredients"),
+		NoIngredients: listParam(query, "no_ingredients"),
+		Sort:          query.Get("sort"),
 	}
-	return filter
+	var err error
+	if query.Get("author") != "" {
+		filter.AuthorID, err = strconv.Atoi(query.Get("author"))
+		if err != nil {
+			return filter, err
+		}
+	}
+	if query.Get("has_photo") != "" {
+		var hasPhoto bool
+		hasPhoto, err = strconv.ParseBool(query.Get("has_photo"))
+		if err != nil {
+			return filter, err
+		}
+		filter.HasPhoto = null.BoolFrom(hasPhoto)
+	}
+	if query.Get("min_rating") != "" {
+		filter.MinRating, err = strconv.ParseFloat(query.Get("min_rating"), 64)
+		if err != nil {
+			return filter, err
+		}
+		if filter.MinRating < defs.MinRating ||
+			filter.MinRating > defs.MaxRating {
+			return filter, errors.New("invalid min_rating: " +
+				query.Get("min_rating"))
+		}
+	}
+	if query.Get("max_time") != "" {
+		filter.MaxMinutes, err = strconv.Atoi(query.Get("max_time"))
+		if err != nil {
+			return filter, err
+		}
+	}
+	var dates = map[string]*time.Time{
+		"created_after":   &filter.CreatedAfter,
+		"created_before":  &filter.CreatedBefore,
+		"modified_after":  &filter.ModifiedAfter,
+		"modified_before": &filter.ModifiedBefore,
+	}
+	for name, dest := range dates {
+		*dest, err = parseDateParam(query.Get(name))
+		if err != nil {
+			return filter, err
+		}
+	}
+	switch filter.Sort {
+	case "", defs.SortByTitle, defs.SortByCreated, defs.SortByModified,
+		defs.SortByTime:
+	default:
+		return filter, errors.New("invalid sort key: " + filter.Sort)
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, errors.New("invalid order: " + query.Get("order"))
+	}
+	return filter, nil
+}
+
+// listParam reads a comma-separated list from a query parameter, which may also
+// be repeated. Blank entries are dropped.
+func listParam(query url.Values, name string) []string {
+	var list []string
+	for _, value := range query[name] {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// parseDateParam reads a date or a full timestamp. An empty string gives the
+// zero time.
+func parseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	var t, err = time.Parse("2006-01-02", value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
-// handleRecipes handles a request for a list of recipes.
+// handleRecipes handles a request for a list of recipes. The response is a
+// bare list unless envelope=true, which wraps it in an object with the total
+// count and cursors for neighboring pages. With facets=true, the object also
+// has counts by tag, author, and time. Paging information is also sent in the
+// X-Total-Count and Link headers either way.
 // GET /recipes
 func handleRecipes(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
 	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	var filter = buildItemFilter(req.URL)
-	var recipes, err = db.FetchRecipes(filter)
+	var filter, err = buildItemFilter(req.URL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	filter.Viewer = viewer
+	results, err := db.FetchRecipePage(filter)
+	if err == db.ErrBadCursor {
+		res.WriteHeader(400)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		res.WriteHeader(500)
 		return
 	}
-	j, e := json.Marshal(recipes)
+	setPageHeaders(res, req.URL, &results.Page)
+
+	var wantFacets, _ = strconv.ParseBool(req.URL.Query().Get("facets"))
+	var wantEnvelope, _ = strconv.ParseBool(req.URL.Query().Get("envelope"))
+	// The bare list is the default.
+	var body interface{} = results.Recipes
+	if wantFacets {
+		results.Facets, err = db.FetchRecipeFacets(filter)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+	}
+	if wantFacets || wantEnvelope {
+		body = results
+	}
+	j, e := json.Marshal(body)
 	if e != nil {
 		log.Println(e)
 		res.WriteHeader(500)
@@ -54,7 +205,79 @@ func handleRecipes(res http.ResponseWriter, req *http.Request) {
 	res.Write(j)
 }
 
-// handlePutOrPostRecipe creates a new recipe or updates an existing one.
+// setPageHeaders describes a page of results in the response headers, with the
+// total count in X-Total-Count and links to neighboring pages in Link.
+func setPageHeaders(res http.ResponseWriter, u *url.URL, page *defs.Page) {
+	res.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	var links []string
+	var addLink = func(c string, rel string) {
+		if c == "" {
+			return
+		}
+		// Keep the rest of the query, but swap any paging for the cursor.
+		var query = u.Query()
+		query.Del("skip")
+		query.Set("cursor", c)
+		links = append(links,
+			"<"+u.Path+"?"+query.Encode()+`>; rel="`+rel+`"`)
+	}
+	addLink(page.Next, "next")
+	addLink(page.Prev, "prev")
+	if len(links) != 0 {
+		res.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// handleCookable ranks recipes by how many of their ingredients are on hand.
+// The have parameter lists ingredients on hand, and staples lists ones to
+// ignore, like salt. Other filter parameters narrow the recipes considered,
+// and count limits the number of results.
+// GET /recipes/cookable?have=eggs,milk&staples=salt
+func handleCookable(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	var filter, err = buildItemFilter(req.URL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	filter.Viewer = viewer
+	var query = req.URL.Query()
+	var have = listParam(query, "have")
+	if len(have) == 0 {
+		res.WriteHeader(400)
+		return
+	}
+	// Rank every candidate, then page through the ranking.
+	var count = filter.Count
+	filter.Count, filter.Skip = 0, 0
+	recipes, err := db.FetchRecipes(filter)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	var results = search.Cookable(recipes, have, listParam(query, "staples"))
+	if count != 0 && len(results) > count {
+		results = results[:count]
+	}
+	j, e := json.Marshal(results)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handlePutOrPostRecipe creates a new recipe or updates an existing one. A new
+// recipe that looks like one we have is refused with 409 and a list of the
+// likely duplicates, unless allow_duplicate=true is set.
 // POST /recipes, PUT /recipes/4
 func handlePutOrPostRecipe(res http.ResponseWriter, req *http.Request) {
 	// Access control.
@@ -85,6 +308,10 @@ func handlePutOrPostRecipe(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(400)
 		return
 	}
+	if recipe.Visibility != "" && !defs.ValidVisibility(recipe.Visibility) {
+		res.WriteHeader(400)
+		return
+	}
 
 	var newRecipe *defs.Recipe
 
@@ -99,6 +326,10 @@ func handlePutOrPostRecipe(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 	} else {
+		// Make sure we don't already have it, unless told to go ahead.
+		if !allowDuplicates(req) && rejectDuplicates(res, &recipe, viewerFor(usr)) {
+			return
+		}
 		// Create it with the currently logged-in user as the author.
 		recipe.AuthorID = usr.ID
 		newRecipe, err = db.CreateRecipe(&recipe)
@@ -119,22 +350,26 @@ func handlePutOrPostRecipe(res http.ResponseWriter, req *http.Request) {
 	res.Write(j)
 }
 
-// handleRecipe handles a request for a specific recipe.
-// GET /recipes/3
+// handleRecipe handles a request for a specific recipe, by ID or slug. A
+// client asking for application/ld+json gets a schema.org Recipe.
+// GET /recipes/3, GET /recipes/banana-bread
 func handleRecipe(res http.ResponseWriter, req *http.Request) {
-	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	var format = negotiate(req, "application/json", "application/ld+json")
+	res.Header().Set("Vary", "Accept")
+	res.Header().Set("Content-Type", format+"; charset=UTF-8")
 
-	// Get id parameter.
-	var params = mux.Vars(req)
-	var id, err = strconv.Atoi(params["id"])
-	if err != nil {
-		log.Println(err)
-		res.WriteHeader(400)
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
 		return
 	}
 
-	var recipe *defs.Recipe
-	recipe, err = db.FetchRecipe(id)
+	var recipe, err = db.FetchRecipe(id, viewer)
 	if err == sql.ErrNoRows {
 		res.WriteHeader(404)
 		return
@@ -143,12 +378,68 @@ func handleRecipe(res http.ResponseWriter, req *http.Request) {
 		log.Println(err)
 		return
 	}
-	j, e := json.Marshal(recipe)
+	var j []byte
+	var e error
+	if format == "application/ld+json" {
+		j, e = exporter.JSONLD(recipe, config.PublicBaseURL)
+	} else {
+		j, e = json.Marshal(recipe)
+	}
 	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleSimilarRecipes suggests recipes like the given one that it doesn't
+// already link to. The count parameter limits the number of suggestions.
+// GET /recipes/3/similar?count=5
+func handleSimilarRecipes(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return
+	}
+	var count = 10
+	var err error
+	if c := req.URL.Query().Get("count"); c != "" {
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 {
+			res.WriteHeader(400)
+			return
+		}
+	}
+
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	candidates, err := db.FetchRecipes(defs.ItemFilter{Viewer: viewer})
+	if err != nil {
 		log.Println(err)
 		res.WriteHeader(500)
 		return
 	}
+	j, e := json.Marshal(search.Similar(recipe, candidates, count))
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
 	res.Write(j)
 }
 
@@ -182,6 +473,21 @@ func handleDeleteRecipe(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Note the photos and attachments first, since their rows go with the
+	// recipe.
+	photoIDs, err := db.FetchPhotoIDs(id)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	attachmentIDs, err := db.FetchAttachmentIDs(id)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+
 	// Bypass the author check if the user has sufficient privileges.
 	var force = usr.Role == "Admin" || usr.Role == "Moderator"
 	err = db.DeleteRecipe(id, usr.ID, force)
@@ -194,10 +500,17 @@ func handleDeleteRecipe(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(400)
 		return
 	}
+	for _, photoID := range photoIDs {
+		deletePhotoBlobs(photoID)
+	}
+	for _, attachmentID := range attachmentIDs {
+		deleteAttachmentBlob(attachmentID)
+	}
 	res.WriteHeader(200)
 }
 
-// handleUsers handles a request for a list of users.
+// handleUsers handles a request for a list of users. Paging works the same as
+// for handleRecipes.
 // GET /users
 func handleUsers(res http.ResponseWriter, req *http.Request) {
 	// Access control.
@@ -217,15 +530,31 @@ func handleUsers(res http.ResponseWriter, req *http.Request) {
 	}
 
 	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	var filter = buildItemFilter(req.URL)
+	filter, err := buildItemFilter(req.URL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
 
-	users, err := db.FetchUsers(filter)
+	results, err := db.FetchUserPage(filter)
+	if err == db.ErrBadCursor {
+		res.WriteHeader(400)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		res.WriteHeader(500)
 		return
 	}
-	j, e := json.Marshal(users)
+	setPageHeaders(res, req.URL, &results.Page)
+
+	// The bare list is the default.
+	var body interface{} = results.Users
+	if wantEnvelope, _ := strconv.ParseBool(req.URL.Query().Get("envelope")); wantEnvelope {
+		body = results
+	}
+	j, e := json.Marshal(body)
 	if e != nil {
 		log.Println(e)
 		res.WriteHeader(500)
@@ -296,8 +625,11 @@ func handlePutOrPostUser(res http.ResponseWriter, req *http.Request) {
 	res.Write(j)
 }
 
-// handleDeleteUser deletes a user by id.
-// DELETE /users/4
+// handleDeleteUser deletes a user by id. Admins may delete anyone, and users
+// may delete themselves. The request must say what becomes of the user's
+// recipes: recipes=delete deletes them too, or an admin may give them to
+// another user with reassign.
+// DELETE /users/4?recipes=delete, DELETE /users/4?reassign=1
 func handleDeleteUser(res http.ResponseWriter, req *http.Request) {
 	// Access control.
 	var usr, err = checkAuth(res, req)
@@ -310,11 +642,6 @@ func handleDeleteUser(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(401)
 		return
 	}
-	if usr.Role != "Admin" {
-		res.WriteHeader(403)
-		return
-	}
-	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
 	// Get id parameter.
 	var params = mux.Vars(req)
@@ -324,27 +651,69 @@ func handleDeleteUser(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(400)
 		return
 	}
-	err = db.DeleteUser(id)
-	if err != nil {
-		log.Println(err)
+	if usr.Role != "Admin" && usr.ID != id {
+		res.WriteHeader(403)
+		return
+	}
+	var query = req.URL.Query()
+	var reassignTo int
+	if value := query.Get("reassign"); value != "" {
+		reassignTo, err = strconv.Atoi(value)
+		if err != nil || reassignTo <= 0 {
+			res.WriteHeader(400)
+			return
+		}
+		// Only admins may give recipes to someone else.
+		if usr.Role != "Admin" {
+			res.WriteHeader(403)
+			return
+		}
+	}
+	// Exactly one of these says what becomes of the recipes.
+	if (query.Get("recipes") == "delete") == (reassignTo != 0) {
 		res.WriteHeader(400)
 		return
 	}
-	res.WriteHeader(200)
-}
 
-func handleGetTags(res http.ResponseWriter, req *http.Request) {
-	var tags, err = db.FetchTags()
+	deleted, err := db.DeleteUser(id, reassignTo)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err == db.ErrNoSuchUser {
+		res.WriteHeader(400)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	// The database no longer refers to the files of deleted recipes.
+	for _, id := range deleted.PhotoIDs {
+		deletePhotoBlobs(id)
+	}
+	for _, id := range deleted.AttachmentIDs {
+		deleteAttachmentBlob(id)
+	}
+	log.Printf("user %d deleted user %d: %d recipes deleted, %d reassigned",
+		usr.ID, id, deleted.Recipes, deleted.Reassigned)
+
+	j, err := json.Marshal(deleted)
 	if err != nil {
 		log.Println(err)
 		res.WriteHeader(500)
 		return
 	}
-	res.Write(tags)
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	res.Write(j)
 }
 
 func handleGetRecipeTitles(res http.ResponseWriter, req *http.Request) {
-	var titles, err = db.FetchRecipeTitles()
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	var titles, err = db.FetchRecipeTitles(viewer)
 	if err != nil {
 		log.Println(err)
 		res.WriteHeader(500)
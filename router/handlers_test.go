@@ -0,0 +1,43 @@
+package router
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestBuildItemFilter(t *testing.T) {
+	var u, _ = url.Parse("/recipes?query=pie&tags=dessert,%20baked&tags=fruit" +
+		"&no_tags=nuts&author=3&max_time=45&created_after=2017-01-02" +
+		"&sort=time&order=desc")
+	var filter, err = buildItemFilter(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(filter.AllTags, []string{"dessert", "baked", "fruit"}) {
+		t.Errorf("wrong tags: %v", filter.AllTags)
+	}
+	if filter.Query != "pie" || filter.AuthorID != 3 || filter.MaxMinutes != 45 {
+		t.Errorf("wrong filter: %+v", filter)
+	}
+	if filter.CreatedAfter.Format("2006-01-02") != "2017-01-02" {
+		t.Errorf("wrong created_after: %v", filter.CreatedAfter)
+	}
+	if filter.Sort != "time" || !filter.Descending {
+		t.Errorf("wrong sort: %q %v", filter.Sort, filter.Descending)
+	}
+
+	u, _ = url.Parse("/recipes?min_rating=3.5")
+	filter, err = buildItemFilter(u)
+	if err != nil || filter.MinRating != 3.5 {
+		t.Errorf("wrong min_rating: %v, %v", filter.MinRating, err)
+	}
+
+	for _, bad := range []string{"author=bob", "sort=rating", "order=up",
+		"created_before=yesterday", "min_rating=6", "min_rating=good"} {
+		u, _ = url.Parse("/recipes?" + bad)
+		if _, err = buildItemFilter(u); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
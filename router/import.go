@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for importing recipes from other formats.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/importer"
+	"github.com/rwestlund/recipes/search"
+)
+
+// readImport reads the document to import, either uploaded as the "file"
+// field of a multipart form or sent as the request body, up to limit bytes.
+// It writes an error response and returns false if that fails.
+func readImport(res http.ResponseWriter, req *http.Request,
+	limit int64) ([]byte, bool) {
+	if req.ContentLength > limit {
+		res.WriteHeader(413)
+		return nil, false
+	}
+	req.Body = http.MaxBytesReader(res, req.Body, limit)
+	var body io.Reader = req.Body
+	if file, _, err := req.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+	} else if err != http.ErrNotMultipart {
+		log.Println(err)
+		res.WriteHeader(400)
+		return nil, false
+	}
+	var data, err = ioutil.ReadAll(body)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return nil, false
+	}
+	return data, true
+}
+
+// writeImportErrors refuses an import with 422 and the problems found in it.
+func writeImportErrors(res http.ResponseWriter, errs interface{}) {
+	j, e := json.Marshal(errs)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.WriteHeader(422)
+	res.Write(j)
+}
+
+// respondImport finishes an import. By default it responds with the preview,
+// with any likely duplicates filled in, without saving anything. With
+// save=true, it creates the recipe and responds with it, unless it looks like
+// one we have; that is refused with 409 as in handlePutOrPostRecipe.
+func respondImport(res http.ResponseWriter, req *http.Request,
+	usr *defs.User, preview *defs.ImportPreview) {
+	var recipe = preview.Recipe
+	var save, _ = strconv.ParseBool(req.URL.Query().Get("save"))
+	var result interface{}
+	if save {
+		if !allowDuplicates(req) && rejectDuplicates(res, recipe, viewerFor(usr)) {
+			return
+		}
+		var saved, err = db.ImportRecipe(recipe, usr.ID)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(400)
+			return
+		}
+		result = saved
+	} else {
+		var candidates, err = db.FetchRecipes(
+			defs.ItemFilter{Viewer: viewerFor(usr)})
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+		preview.Duplicates = search.Duplicates(recipe, candidates)
+		result = preview
+	}
+	j, e := json.Marshal(result)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleImportHTML imports a recipe from a web page with schema.org metadata.
+// The page is fetched from the url parameter, or else uploaded. See
+// respondImport for what comes back.
+// POST /recipes/import?url=https://example.com/banana-bread
+func handleImportHTML(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var data []byte
+	var source = req.URL.Query().Get("url")
+	if source != "" {
+		data, err = importer.Fetch(req.Context(), source)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(502)
+			return
+		}
+	} else {
+		var ok bool
+		data, ok = readImport(res, req, importer.MaxPageSize)
+		if !ok {
+			return
+		}
+	}
+
+	recipe, err := importer.FromHTML(data, source)
+	if err == importer.ErrNoRecipe {
+		res.WriteHeader(422)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	respondImport(res, req, usr, &defs.ImportPreview{Recipe: recipe})
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for reading and writing recipes as
+ * Markdown.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/importer"
+	"github.com/rwestlund/recipes/markdown"
+)
+
+// handleRecipeMarkdown returns a recipe as Markdown.
+// GET /recipes/3.md, GET /recipes/banana-bread.md
+func handleRecipeMarkdown(res http.ResponseWriter, req *http.Request) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return
+	}
+
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Header().Set("Content-Type", "text/markdown; charset=UTF-8")
+	res.Write(markdown.Write(recipe))
+}
+
+// handleImportMarkdown imports a recipe written in Markdown, either uploaded
+// or sent as the request body. A file with problems is refused with 422 and
+// a list of them by line number. Otherwise, see respondImport for what comes
+// back.
+// POST /recipes/import/markdown
+func handleImportMarkdown(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var data, ok = readImport(res, req, importer.MaxPageSize)
+	if !ok {
+		return
+	}
+	recipe, err := markdown.Parse(data)
+	if errs, ok := err.(markdown.Errors); ok {
+		writeImportErrors(res, errs)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	respondImport(res, req, usr, &defs.ImportPreview{Recipe: recipe})
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file picks response formats from the Accept header.
+ */
+
+package router
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the media type from offers that the client prefers,
+// going by the Accept header. Offers are in our order of preference, which
+// breaks ties, so the first is used when the client doesn't say.
+func negotiate(req *http.Request, offers ...string) string {
+	var accept = req.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+	var best = offers[0]
+	var bestQ = -1.0
+	for _, offer := range offers {
+		// The q of the most specific range matching this offer.
+		var q, specificity = 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			var mediaType, params, err = mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			var s int
+			switch {
+			case mediaType == offer:
+				s = 2
+			case mediaType == "*/*":
+				s = 0
+			case strings.HasSuffix(mediaType, "/*") &&
+				strings.HasPrefix(offer, strings.TrimSuffix(mediaType, "*")):
+				s = 1
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+			specificity = s
+			q = 1
+			if v, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(v, 64)
+				if err != nil {
+					q = 0
+				}
+			}
+		}
+		if q > bestQ && q > 0 {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	var cases = map[string]string{
+		"":                                     "application/json",
+		"application/ld+json":                  "application/ld+json",
+		"application/json":                     "application/json",
+		"text/html,*/*;q=0.8":                  "application/json",
+		"application/ld+json;q=0.9, */*;q=0.1": "application/ld+json",
+		"application/*":                        "application/json",
+		"application/json;q=0.5, application/ld+json": "application/ld+json",
+		"image/png": "application/json",
+	}
+	for accept, want := range cases {
+		var req = httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		var got = negotiate(req, "application/json", "application/ld+json")
+		if got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for the HTML pages served outside the API,
+ * and for the client app itself.
+ */
+
+package router
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/config"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/pages"
+)
+
+// renderRecipePage serves a recipe as a page rendered by render.
+func renderRecipePage(res http.ResponseWriter, req *http.Request,
+	render func(io.Writer, *defs.Recipe, string) error) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	// Get id parameter, which may be a slug.
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return
+	}
+	var recipe, err = db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	var buf bytes.Buffer
+	err = render(&buf, recipe, config.PublicBaseURL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	res.Write(buf.Bytes())
+}
+
+// handleRecipePage serves the full page of a recipe, for readers without
+// JavaScript.
+// GET /recipes/3/page, GET /recipes/banana-bread/page
+func handleRecipePage(res http.ResponseWriter, req *http.Request) {
+	renderRecipePage(res, req, pages.Recipe)
+}
+
+// handleRecipeCard serves a recipe's kitchen card, a compact page for
+// printing.
+// GET /recipes/banana-bread/card
+func handleRecipeCard(res http.ResponseWriter, req *http.Request) {
+	renderRecipePage(res, req, pages.Card)
+}
+
+// handleAppRecipe serves the client app's page for a recipe, with the
+// recipe's title, link preview tags, and JSON-LD in its head, so shared links
+// preview well. The app shows the recipe itself, or says it's missing.
+// GET /recipes/3, GET /recipes/banana-bread
+func handleAppRecipe(res http.ResponseWriter, req *http.Request) {
+	var index, err = ioutil.ReadFile(
+		filepath.Join(config.StaticDirectory, "index.html"))
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(404)
+		return
+	}
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return
+	}
+	var ref = mux.Vars(req)["id"]
+	id, current, err := lookupRecipeRef(ref)
+	if err == nil && current != ref {
+		redirectRecipeRef(res, req, ref, current)
+		return
+	}
+	var recipe *defs.Recipe
+	if err == nil {
+		recipe, err = db.FetchRecipe(id, viewer)
+	}
+	res.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		res.Write(index)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	var buf bytes.Buffer
+	err = pages.App(&buf, index, recipe, config.PublicBaseURL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(buf.Bytes())
+}
+
+// spaHandler serves the client app's files from a directory. Any other path
+// is one of the app's own pages, so it gets index.html and the app shows the
+// page.
+type spaHandler string
+
+func (dir spaHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	var files = http.Dir(dir)
+	var f, err = files.Open(path.Clean("/" + req.URL.Path))
+	if err == nil {
+		if stat, e := f.Stat(); e != nil || stat.IsDir() {
+			f.Close()
+			err = os.ErrNotExist
+		}
+	}
+	if err != nil {
+		f, err = files.Open("/index.html")
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(404)
+			return
+		}
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	http.ServeContent(res, req, stat.Name(), stat.ModTime(), f)
+}
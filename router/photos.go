@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for recipe photos.
+ */
+
+package router
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/photos"
+)
+
+// The most we'll accept in one upload request, in bytes.
+const maxUploadSize = 32 << 20
+
+// deletePhotoBlobs removes a photo's images from blob storage. Failures are
+// only logged, since the database no longer refers to them.
+func deletePhotoBlobs(photoID int) {
+	// Older photos may have images we no longer make.
+	var names = append([]string{}, blob.OldPhotoFiles...)
+	for name := range blob.PhotoFiles {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		var err = blobs.Delete(blob.PhotoKey(photoID, name))
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// processUpload processes one uploaded photo.
+func processUpload(fh *multipart.FileHeader) (*photos.Processed, error) {
+	var f, err = fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return photos.Process(data)
+}
+
+// storePhoto processes an image and stores it as a photo of a recipe.
+func storePhoto(recipeID int, data []byte) (*defs.Photo, error) {
+	var processed, err = photos.Process(data)
+	if err != nil {
+		return nil, err
+	}
+	return storeProcessed(recipeID, processed)
+}
+
+// storeProcessed stores a processed image as a photo of a recipe.
+func storeProcessed(recipeID int,
+	processed *photos.Processed) (*defs.Photo, error) {
+	var photo, err = db.CreatePhoto(recipeID, processed.Width,
+		processed.Height)
+	if err != nil {
+		return nil, err
+	}
+	var images = map[string][]byte{
+		"full.jpg":  processed.Full,
+		"thumb.jpg": processed.ThumbJPEG,
+	}
+	for name, image := range images {
+		err = blobs.Put(blob.PhotoKey(photo.ID, name), image, blob.PhotoFiles[name])
+		if err != nil {
+			// Don't leave a record of a photo we can't serve.
+			deletePhotoBlobs(photo.ID)
+			if e := db.DeletePhoto(recipeID, photo.ID); e != nil {
+				log.Println(e)
+			}
+			return nil, err
+		}
+	}
+	return photo, nil
+}
+
+// handlePostPhotos adds photos to a recipe from a multipart upload, with each
+// file in a "photos" field. It responds with the new photos. If any file
+// can't be used, none are added.
+// POST /recipes/4/photos
+func handlePostPhotos(res http.ResponseWriter, req *http.Request) {
+	var _, id, _, ok = checkRecipeAccess(res, req, "")
+	if !ok {
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if req.ContentLength > maxUploadSize {
+		res.WriteHeader(413)
+		return
+	}
+	req.Body = http.MaxBytesReader(res, req.Body, maxUploadSize)
+	var err = req.ParseMultipartForm(maxUploadSize)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	defer req.MultipartForm.RemoveAll()
+	var files = req.MultipartForm.File["photos"]
+	if len(files) == 0 {
+		res.WriteHeader(400)
+		return
+	}
+
+	// Process every file before storing any, so a bad one stops them all.
+	var processed = make([]*photos.Processed, 0, len(files))
+	for _, fh := range files {
+		var p, err = processUpload(fh)
+		if err == photos.ErrUnsupported {
+			res.WriteHeader(415)
+			return
+		}
+		if err == photos.ErrTooLarge {
+			res.WriteHeader(413)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+		processed = append(processed, p)
+	}
+	var saved = make([]defs.Photo, 0, len(files))
+	for _, p := range processed {
+		photo, err := storeProcessed(id, p)
+		if err != nil {
+			log.Println(err)
+			// Take back the ones already stored, since we can't say so.
+			for _, photo := range saved {
+				deletePhotoBlobs(photo.ID)
+				if e := db.DeletePhoto(id, photo.ID); e != nil {
+					log.Println(e)
+				}
+			}
+			res.WriteHeader(500)
+			return
+		}
+		saved = append(saved, *photo)
+	}
+
+	j, e := json.Marshal(saved)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleGetPhoto serves one of the images of a photo.
+// GET /photos/12/thumb.jpg
+func handleGetPhoto(res http.ResponseWriter, req *http.Request) {
+	var params = mux.Vars(req)
+	var id, err = strconv.Atoi(params["id"])
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	var contentType, ok = blob.PhotoFiles[params["name"]]
+	if !ok {
+		res.WriteHeader(404)
+		return
+	}
+	// Photos of private recipes are only shown to those who may see them.
+	viewer, ok := checkViewer(res, req)
+	if !ok {
+		return
+	}
+	visible, public, err := db.FetchPhotoVisible(id, viewer)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if !visible {
+		res.WriteHeader(404)
+		return
+	}
+	data, err := blobs.Get(blob.PhotoKey(id, params["name"]))
+	if err == blob.ErrNotFound {
+		res.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Header().Set("Content-Type", contentType)
+	// A photo's images never change, so they can be cached for a long time,
+	// though only by the browser if the recipe is private.
+	if public {
+		res.Header().Set("Cache-Control",
+			"public, max-age=31536000, immutable")
+	} else {
+		res.Header().Set("Cache-Control",
+			"private, max-age=31536000, immutable")
+	}
+	http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(data))
+}
+
+// handleDeletePhoto removes a photo from a recipe.
+// DELETE /recipes/4/photos/12
+func handleDeletePhoto(res http.ResponseWriter, req *http.Request) {
+	var usr, recipeID, photoID, ok = checkRecipeAccess(res, req, "photo")
+	if !ok {
+		return
+	}
+	var err = db.DeletePhoto(recipeID, photoID)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	deletePhotoBlobs(photoID)
+	log.Println("user", usr.ID, "deleted photo", photoID)
+	res.WriteHeader(200)
+}
+
+// handlePutCoverPhoto makes a photo the cover of its recipe.
+// PUT /recipes/4/photos/12/cover
+func handlePutCoverPhoto(res http.ResponseWriter, req *http.Request) {
+	var _, recipeID, photoID, ok = checkRecipeAccess(res, req, "photo")
+	if !ok {
+		return
+	}
+	var err = db.SetCoverPhoto(recipeID, photoID)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.WriteHeader(200)
+}
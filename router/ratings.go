@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains the HTTP handler for rating recipes.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// handleRateRecipe sets the user's rating of a recipe, from 1 to 5, with a
+// body like {"rating": 4}. A rating of 0, or DELETE, removes theirs. Anyone
+// may rate any recipe they may see. It responds with the recipe, with its new average.
+// PUT /recipes/3/rating, DELETE /recipes/3/rating
+func handleRateRecipe(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" && usr.Role != "User" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Get id parameter.
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		res.WriteHeader(400)
+		return
+	}
+	var body struct {
+		Rating int `json:"rating"`
+	}
+	if req.Method == "PUT" {
+		err = json.NewDecoder(io.LimitReader(req.Body, 1024)).Decode(&body)
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(400)
+			return
+		}
+		if body.Rating != 0 &&
+			(body.Rating < defs.MinRating || body.Rating > defs.MaxRating) {
+			res.WriteHeader(400)
+			return
+		}
+	}
+
+	// Private recipes may only be rated by those who may see them.
+	var viewer = viewerFor(usr)
+	_, err = db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	err = db.RateRecipe(id, usr.ID, body.Rating)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	j, e := json.Marshal(recipe)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
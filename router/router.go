@@ -10,13 +10,21 @@ package router
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/blob"
+	"github.com/rwestlund/recipes/config"
 )
 
-// NewRouter builds a router by iterating over all routes.
-func NewRouter() *mux.Router {
+// Where uploaded files are kept.
+var blobs blob.Store
+
+// NewRouter builds a router by iterating over all routes. Uploaded files are
+// kept in store.
+func NewRouter(store blob.Store) *mux.Router {
+	blobs = store
 	router := mux.NewRouter()
 	apiRouter := router.PathPrefix("/api/").Subrouter()
 	for _, route := range routes {
@@ -25,9 +33,34 @@ func NewRouter() *mux.Router {
 			Path(route.pattern).
 			Handler(logger(route.handler))
 	}
+	for _, route := range pageRoutes {
+		router.
+			Methods(route.methods...).
+			Path(route.pattern).
+			Handler(logger(route.handler))
+	}
+	// Everything else outside the API is the client app.
+	if config.StaticDirectory != "" {
+		for _, route := range appRoutes {
+			router.
+				Methods(route.methods...).
+				Path(route.pattern).
+				Handler(logger(route.handler))
+		}
+		router.
+			Methods("GET", "HEAD").
+			MatcherFunc(outsideAPI).
+			Handler(logger(spaHandler(config.StaticDirectory)))
+	}
 	return router
 }
 
+// outsideAPI matches requests for paths not under /api/, so requests the API
+// has no route for aren't given the client app.
+func outsideAPI(req *http.Request, match *mux.RouteMatch) bool {
+	return !strings.HasPrefix(req.URL.Path, "/api/")
+}
+
 // Add logging functionality to HTTP requests.
 func logger(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,19 +38,146 @@ var routes = routelist{
 	},
 	route{
 		[]string{"GET", "HEAD"},
-		"/recipes/{id:[0-9]+}",
-		handleRecipe,
+		"/recipes/" + recipeRef + "/similar",
+		handleSimilarRecipes,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + "/scaled",
+		handleScaledRecipe,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + "/shopping-list",
+		handleShoppingList,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/{id:[0-9]+}/fork",
+		handleForkRecipe,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + "/diff",
+		handleRecipeDiff,
 	},
 	route{
 		[]string{"GET", "HEAD"},
 		"/recipes",
 		handleRecipes,
 	},
+	route{
+		[]string{"POST"},
+		"/recipes/{id:[0-9]+}/photos",
+		handlePostPhotos,
+	},
+	route{
+		[]string{"DELETE"},
+		"/recipes/{id:[0-9]+}/photos/{photo:[0-9]+}",
+		handleDeletePhoto,
+	},
+	route{
+		[]string{"PUT"},
+		"/recipes/{id:[0-9]+}/photos/{photo:[0-9]+}/cover",
+		handlePutCoverPhoto,
+	},
+	route{
+		[]string{"PUT", "DELETE"},
+		"/recipes/{id:[0-9]+}/rating",
+		handleRateRecipe,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/photos/{id:[0-9]+}/{name}",
+		handleGetPhoto,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/{id:[0-9]+}/attachments",
+		handlePostAttachments,
+	},
+	route{
+		[]string{"DELETE"},
+		"/recipes/{id:[0-9]+}/attachments/{attachment:[0-9]+}",
+		handleDeleteAttachment,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/attachments/{id:[0-9]+}",
+		handleGetAttachment,
+	},
 	route{
 		[]string{"GET", "HEAD"},
 		"/recipes/titles",
 		handleGetRecipeTitles,
 	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/cookable",
+		handleCookable,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/graph",
+		handleRecipeGraph,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/duplicates",
+		handleDuplicates,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/bulk",
+		handleBulkRecipes,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/import",
+		handleImportHTML,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/import/markdown",
+		handleImportMarkdown,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + ".md",
+		handleRecipeMarkdown,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/import/cooklang",
+		handleImportCooklang,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + ".cook",
+		handleRecipeCooklang,
+	},
+	route{
+		[]string{"POST"},
+		"/recipes/import/{format:paprika|mealie|mealmaster}",
+		handleImportApp,
+	},
+	// This must follow the other paths under /recipes, since a slug could
+	// look like one of them.
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef,
+		handleRecipe,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/cookbook.pdf",
+		handleCookbook,
+	},
+	route{
+		[]string{"GET"},
+		"/backup",
+		handleBackup,
+	},
 	route{
 		[]string{"GET", "HEAD"},
 		"/users",
@@ -91,9 +218,70 @@ var routes = routelist{
 		"/users/{id:[0-9]+}",
 		handleDeleteUser,
 	},
+	route{
+		[]string{"GET"},
+		"/users/{id:[0-9]+}/export",
+		handleUserExport,
+	},
 	route{
 		[]string{"GET", "HEAD"},
 		"/tags",
 		handleGetTags,
 	},
+	route{
+		[]string{"PUT"},
+		"/tags/{tag}",
+		handlePutTag,
+	},
+	route{
+		[]string{"DELETE"},
+		"/tags/{tag}",
+		handleDeleteTag,
+	},
+	route{
+		[]string{"POST"},
+		"/tags/merge",
+		handleMergeTags,
+	},
+	route{
+		[]string{"POST"},
+		"/tags/rename",
+		handleMergeTags,
+	},
+	route{
+		[]string{"PUT"},
+		"/tags/{tag}/synonyms",
+		handlePutTagRelations,
+	},
+	route{
+		[]string{"PUT"},
+		"/tags/{tag}/parents",
+		handlePutTagRelations,
+	},
+}
+
+// Pages served outside the API, alongside the client app, for readers that
+// can't run it: link previews, printers, and browsers without JavaScript.
+var pageRoutes = routelist{
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + "/page",
+		handleRecipePage,
+	},
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef + "/card",
+		handleRecipeCard,
+	},
+}
+
+// Pages of the client app that need more than the app's own page, when we
+// serve the app. The app shows them, but link previews need to see what
+// they're about.
+var appRoutes = routelist{
+	route{
+		[]string{"GET", "HEAD"},
+		"/recipes/" + recipeRef,
+		handleAppRecipe,
+	},
 }
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRecipeRoutes(t *testing.T) {
+	var router = NewRouter(nil)
+	var cases = map[string]string{
+		"/api/recipes/3":                "/api/recipes/" + recipeRef,
+		"/api/recipes/banana-bread":     "/api/recipes/" + recipeRef,
+		"/api/recipes/graph":            "/api/recipes/graph",
+		"/api/recipes/titles":           "/api/recipes/titles",
+		"/api/recipes/pie/similar":      "/api/recipes/" + recipeRef + "/similar",
+		"/api/recipes/pie.md":           "/api/recipes/" + recipeRef + ".md",
+		"/api/recipes/pie.cook":         "/api/recipes/" + recipeRef + ".cook",
+		"/api/recipes/4/photos/2/cover": "/api/recipes/{id:[0-9]+}/photos/{photo:[0-9]+}/cover",
+		"/api/recipes/import/paprika":   "/api/recipes/import/{format:paprika|mealie|mealmaster}",
+		"/api/recipes/bulk":             "/api/recipes/bulk",
+	}
+	for path, want := range cases {
+		var method = "GET"
+		switch path {
+		case "/api/recipes/4/photos/2/cover":
+			method = "PUT"
+		case "/api/recipes/import/paprika", "/api/recipes/bulk":
+			method = "POST"
+		}
+		var match mux.RouteMatch
+		if !router.Match(httptest.NewRequest(method, path, nil), &match) {
+			t.Errorf("%s: no match", path)
+			continue
+		}
+		if got, _ := match.Route.GetPathTemplate(); got != want {
+			t.Errorf("%s: matched %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestPageRoutes(t *testing.T) {
+	var router = NewRouter(nil)
+	var cases = map[string]string{
+		"/recipes/banana-bread/page": "/recipes/" + recipeRef + "/page",
+		"/recipes/banana-bread/card": "/recipes/" + recipeRef + "/card",
+		// The client app has the rest, recipes included.
+		"/":                          "",
+		"/recipes/3":                 "/recipes/" + recipeRef,
+		"/recipes/banana-bread":      "/recipes/" + recipeRef,
+		"/recipes/banana-bread/edit": "",
+	}
+	for path, want := range cases {
+		var match mux.RouteMatch
+		if !router.Match(httptest.NewRequest("GET", path, nil), &match) {
+			t.Errorf("%s: no match", path)
+			continue
+		}
+		if got, _ := match.Route.GetPathTemplate(); got != want {
+			t.Errorf("%s: matched %q, want %q", path, got, want)
+		}
+	}
+
+	// The app's recipe pages are its own, not pages for readers without it.
+	for _, route := range pageRoutes {
+		if route.pattern == "/recipes/"+recipeRef {
+			t.Errorf("%s is a page route, hiding the app's", route.pattern)
+		}
+	}
+
+	// API paths without a route aren't given to the client app.
+	var match mux.RouteMatch
+	if router.Match(httptest.NewRequest("GET", "/api/nothing", nil), &match) {
+		t.Error("/api/nothing matched a route")
+	}
+}
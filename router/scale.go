@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for scaling recipes and building shopping
+ * lists from them.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+	"github.com/rwestlund/recipes/scale"
+)
+
+// scaleRequest reads the recipe ID and the factor parameter from the URL,
+// and fetches the recipe. The Fetcher it returns finds its components, leaving
+// out any the user may not see. It writes the error response and returns
+// false if something is wrong.
+func scaleRequest(res http.ResponseWriter,
+	req *http.Request) (*defs.Recipe, float64, scale.Fetcher, bool) {
+	// Private recipes are only shown to those who may see them.
+	var viewer, ok = checkViewer(res, req)
+	if !ok {
+		return nil, 0, nil, false
+	}
+	id, ok := recipeIDParam(res, req)
+	if !ok {
+		return nil, 0, nil, false
+	}
+	var factor, err = parseFactor(req.URL)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return nil, 0, nil, false
+	}
+	recipe, err := db.FetchRecipe(id, viewer)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return nil, 0, nil, false
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return nil, 0, nil, false
+	}
+	var fetch = func(id int) (*defs.Recipe, error) {
+		var r, err = db.FetchRecipe(id, viewer)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return r, err
+	}
+	return recipe, factor, fetch, true
+}
+
+// parseFactor reads the factor parameter, which defaults to 1.
+func parseFactor(u *url.URL) (float64, error) {
+	var value = u.Query().Get("factor")
+	if value == "" {
+		return 1, nil
+	}
+	var factor, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if !(factor > 0 && factor <= 1000) {
+		return 0, errors.New("factor out of range: " + value)
+	}
+	return factor, nil
+}
+
+// handleScaledRecipe returns a recipe with its ingredients multiplied by the
+// factor parameter, including the ingredients of its components.
+// GET /recipes/3/scaled?factor=2
+func handleScaledRecipe(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	var recipe, factor, fetch, ok = scaleRequest(res, req)
+	if !ok {
+		return
+	}
+	scaled, err := scale.Recipe(recipe, factor, fetch)
+	if err == scale.ErrCycle {
+		res.WriteHeader(409)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	j, e := json.Marshal(scaled)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleShoppingList returns everything needed to make a recipe, including
+// its components, scaled by the factor parameter.
+// GET /recipes/3/shopping-list?factor=2
+func handleShoppingList(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	var recipe, factor, fetch, ok = scaleRequest(res, req)
+	if !ok {
+		return
+	}
+	items, err := scale.ShoppingList(recipe, factor, fetch)
+	if err == scale.ErrCycle {
+		res.WriteHeader(409)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	j, e := json.Marshal(items)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
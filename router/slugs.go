@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file lets routes find recipes by slug as well as by ID.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+)
+
+// The id parameter of routes that accept either a recipe ID or a slug.
+const recipeRef = "{id:[0-9a-z-]+}"
+
+// recipeIDParam reads the id URL parameter, which may be a recipe ID or slug.
+// An old slug is redirected to the same path with the recipe's current slug.
+// It returns false if it wrote an error response or redirect instead.
+func recipeIDParam(res http.ResponseWriter, req *http.Request) (int, bool) {
+	var ref = mux.Vars(req)["id"]
+	var id, current, err = lookupRecipeRef(ref)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(404)
+		return 0, false
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return 0, false
+	}
+	if current != ref {
+		redirectRecipeRef(res, req, ref, current)
+		return 0, false
+	}
+	return id, true
+}
+
+// lookupRecipeRef finds the recipe a recipe ID or slug refers to, returning
+// its ID and the ref it should be reached by. That's ref itself unless ref is
+// an old slug. An unknown slug is sql.ErrNoRows.
+func lookupRecipeRef(ref string) (int, string, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, ref, nil
+	}
+	return db.FetchRecipeIDBySlug(ref)
+}
+
+// redirectRecipeRef redirects a request for a recipe by ref to the same path
+// with current instead.
+func redirectRecipeRef(res http.ResponseWriter, req *http.Request,
+	ref string, current string) {
+	var u = *req.URL
+	u.Path = strings.Replace(u.Path, "/recipes/"+ref, "/recipes/"+current, 1)
+	u.RawPath = ""
+	http.Redirect(res, req, u.String(), 301)
+}
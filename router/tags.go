@@ -0,0 +1,260 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file contains HTTP handlers for managing tags.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Tag colors must be CSS hex colors.
+var tagColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// handleGetTags returns a list of all tag names. With details=true, it returns
+// objects with usage counts, descriptions, colors, synonyms, and parents
+// instead.
+// GET /tags
+func handleGetTags(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if details, _ := strconv.ParseBool(req.URL.Query().Get("details")); details {
+		var tags, err = db.FetchTagDetails()
+		if err != nil {
+			log.Println(err)
+			res.WriteHeader(500)
+			return
+		}
+		j, e := json.Marshal(tags)
+		if e != nil {
+			log.Println(e)
+			res.WriteHeader(500)
+			return
+		}
+		res.Write(j)
+		return
+	}
+	var tags, err = db.FetchTags()
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(tags)
+}
+
+// handlePutTag sets a tag's description and color.
+// PUT /tags/dessert
+func handlePutTag(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" {
+		res.WriteHeader(403)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	// Decode body.
+	var tag defs.Tag
+	err = json.NewDecoder(req.Body).Decode(&tag)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	// The tag name comes from the URL.
+	tag.Tag = mux.Vars(req)["tag"]
+	if tag.Color != "" && !tagColor.MatchString(tag.Color) {
+		res.WriteHeader(400)
+		return
+	}
+	err = db.DescribeTag(&tag)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+	j, e := json.Marshal(tag)
+	if e != nil {
+		log.Println(e)
+		res.WriteHeader(500)
+		return
+	}
+	res.Write(j)
+}
+
+// handleMergeTags replaces some tags with another on every recipe. Renaming is
+// merging a single tag into a new name.
+// POST /tags/merge {"from": ["Dessert", "desserts"], "to": "dessert"}
+// POST /tags/rename {"from": "Dessert", "to": "dessert"}
+func handleMergeTags(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" {
+		res.WriteHeader(403)
+		return
+	}
+
+	from, to, err := readMergeTags(req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+
+	err = db.MergeTags(from, to)
+	if err == db.ErrTagCycle {
+		res.WriteHeader(400)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.WriteHeader(200)
+}
+
+// readMergeTags decodes the body of a merge or rename, trimming the tags. It
+// fails if there's nothing to merge or any tag is blank.
+func readMergeTags(req *http.Request) ([]string, string, error) {
+	// Rename takes a single tag instead of a list.
+	var from []string
+	var to string
+	var err error
+	if strings.HasSuffix(req.URL.Path, "/rename") {
+		var body struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}
+		err = json.NewDecoder(req.Body).Decode(&body)
+		from, to = []string{body.From}, body.To
+	} else {
+		var body struct {
+			From []string `json:"from"`
+			To   string   `json:"to"`
+		}
+		err = json.NewDecoder(req.Body).Decode(&body)
+		from, to = body.From, body.To
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	to = strings.TrimSpace(to)
+	if to == "" || len(from) == 0 {
+		return nil, "", errors.New("no tags to merge")
+	}
+	for i := range from {
+		from[i] = strings.TrimSpace(from[i])
+		if from[i] == "" {
+			return nil, "", errors.New("blank tag to merge")
+		}
+	}
+	return from, to, nil
+}
+
+// handleDeleteTag removes a tag from every recipe.
+// DELETE /tags/dessert
+func handleDeleteTag(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" {
+		res.WriteHeader(403)
+		return
+	}
+
+	err = db.DeleteTag(mux.Vars(req)["tag"])
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.WriteHeader(200)
+}
+
+// handlePutTagRelations sets the synonyms or parents of a tag from a list in
+// the body.
+// PUT /tags/vegetarian/synonyms ["veggie", "meatless"]
+// PUT /tags/pasta/parents ["italian"]
+func handlePutTagRelations(res http.ResponseWriter, req *http.Request) {
+	// Access control.
+	var usr, err = checkAuth(res, req)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	if usr == nil {
+		res.WriteHeader(401)
+		return
+	}
+	if usr.Role != "Admin" && usr.Role != "Moderator" {
+		res.WriteHeader(403)
+		return
+	}
+
+	// Decode body.
+	var list []string
+	err = json.NewDecoder(req.Body).Decode(&list)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(400)
+		return
+	}
+
+	var tag = mux.Vars(req)["tag"]
+	if strings.HasSuffix(req.URL.Path, "/synonyms") {
+		err = db.SetTagSynonyms(tag, list)
+	} else {
+		err = db.SetTagParents(tag, list)
+	}
+	if err == db.ErrTagCycle {
+		res.WriteHeader(400)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(500)
+		return
+	}
+	res.WriteHeader(200)
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadMergeTags(t *testing.T) {
+	var cases = []struct {
+		path, body string
+		from       []string
+		to         string
+	}{
+		{"/tags/merge", `{"from": [" Dessert", "desserts"], "to": "dessert "}`,
+			[]string{"Dessert", "desserts"}, "dessert"},
+		{"/tags/rename", `{"from": "Dessert", "to": "dessert"}`,
+			[]string{"Dessert"}, "dessert"},
+		// Blank tags and nothing to merge are refused.
+		{"/tags/rename", `{"from": "", "to": "dessert"}`, nil, ""},
+		{"/tags/rename", `{"from": "  ", "to": "dessert"}`, nil, ""},
+		{"/tags/rename", `{"from": "Dessert", "to": " "}`, nil, ""},
+		{"/tags/merge", `{"from": ["Dessert", ""], "to": "dessert"}`, nil, ""},
+		{"/tags/merge", `{"from": [], "to": "dessert"}`, nil, ""},
+		{"/tags/merge", `{"from": "Dessert", "to": "dessert"}`, nil, ""},
+	}
+	for _, c := range cases {
+		var req = httptest.NewRequest("POST", c.path, strings.NewReader(c.body))
+		var from, to, err = readMergeTags(req)
+		if c.from == nil {
+			if err == nil {
+				t.Errorf("%s %s: got %q, %q", c.path, c.body, from, to)
+			}
+			continue
+		}
+		if err != nil || strings.Join(from, ",") != strings.Join(c.from, ",") ||
+			to != c.to {
+			t.Errorf("%s %s: got %q, %q, %v", c.path, c.body, from, to, err)
+		}
+	}
+}
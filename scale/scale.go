@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file scales recipes, including the recipes they use as components.
+ */
+
+package scale
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Fetcher loads a recipe by ID, like db.FetchRecipe. A nil recipe without an
+// error is one the reader may not see, which is left out.
+type Fetcher func(id int) (*defs.Recipe, error)
+
+// ErrCycle is returned when a recipe is, through its components, a component
+// of itself.
+var ErrCycle = errors.New("recipe is a component of itself")
+
+// walk calls visit for r and then for each of its components in turn, with
+// the factor each is scaled by. A component's factor is its parent's times
+// the quantity of the link. The path passed to visit runs from the top recipe
+// down to the one being visited.
+func walk(path []*defs.Recipe, factor float64, fetch Fetcher,
+	visit func(path []*defs.Recipe, factor float64)) error {
+	var r = path[len(path)-1]
+	visit(path, factor)
+	for _, link := range r.LinkedRecipes {
+		if link.Role != defs.LinkComponent {
+			continue
+		}
+		for _, p := range path {
+			if p.ID == link.ID {
+				return ErrCycle
+			}
+		}
+		var component, err = fetch(link.ID)
+		if err != nil {
+			return err
+		}
+		if component == nil {
+			continue
+		}
+		err = walk(append(path[:len(path):len(path)], component),
+			factor*batches(link.Quantity), fetch, visit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batches reads the quantity of a component link, like "2 batches" or "1/2".
+// Anything else counts as one batch.
+func batches(quantity string) float64 {
+	var _, high, ok = defs.ParseQuantity(defs.ParseIngredient(quantity).Quantity)
+	if !ok || high == 0 {
+		return 1
+	}
+	return high
+}
+
+// Recipe returns a copy of r with its amount and ingredients multiplied by
+// factor. The ingredients of its components are added as sections headed with
+// their titles, so "Apple Pie" lists what goes in its "Pie Crust".
+func Recipe(r *defs.Recipe, factor float64, fetch Fetcher) (*defs.Recipe,
+	error) {
+	var scaled = *r
+	scaled.Amount = defs.ScaleIngredient(r.Amount, factor)
+	scaled.Ingredients = defs.IngredientSections{}
+	var err = walk([]*defs.Recipe{r}, factor, fetch,
+		func(path []*defs.Recipe, factor float64) {
+			var titles []string
+			for _, p := range path[1:] {
+				titles = append(titles, p.Title)
+			}
+			for _, section := range path[len(path)-1].Ingredients {
+				var heading = strings.Join(append(titles, section.Heading), ": ")
+				if section.Heading == "" {
+					heading = strings.Join(titles, ": ")
+				}
+				var items = make([]string, len(section.Items))
+				for i, line := range section.Items {
+					items[i] = defs.ScaleIngredient(line, factor)
+				}
+				scaled.Ingredients = append(scaled.Ingredients,
+					defs.IngredientSection{Heading: heading, Items: items})
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &scaled, nil
+}
@@ -0,0 +1,102 @@
+package scale
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// fetcher serves recipes from a map.
+func fetcher(recipes ...*defs.Recipe) Fetcher {
+	var byID = make(map[int]*defs.Recipe)
+	for _, r := range recipes {
+		byID[r.ID] = r
+	}
+	return func(id int) (*defs.Recipe, error) {
+		if r, ok := byID[id]; ok {
+			return r, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+}
+
+var crust = &defs.Recipe{ID: 2, Title: "Pie Crust",
+	Ingredients: defs.IngredientSections{{Items: []string{
+		"1 1/4 cups flour", "1/2 cup butter", "salt"}}}}
+
+var pie = &defs.Recipe{ID: 1, Title: "Apple Pie", Amount: "8 slices",
+	Ingredients: defs.IngredientSections{{Heading: "Filling", Items: []string{
+		"6 apples", "1/4 cup butter", "1 tbsp flour"}}},
+	LinkedRecipes: []defs.LinkedRecipe{
+		{ID: 2, Title: "Pie Crust", Role: defs.LinkComponent,
+			Quantity: "2 batches"},
+		{ID: 3, Title: "Ice Cream", Role: defs.LinkServeWith},
+	}}
+
+func TestRecipe(t *testing.T) {
+	var got, err = Recipe(pie, 2, fetcher(pie, crust))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = defs.IngredientSections{
+		{Heading: "Filling", Items: []string{"12 apples", "1/2 cup butter",
+			"2 tbsp flour"}},
+		{Heading: "Pie Crust", Items: []string{"5 cups flour", "2 cup butter",
+			"salt"}},
+	}
+	if !reflect.DeepEqual(got.Ingredients, want) {
+		t.Errorf("got %+v, want %+v", got.Ingredients, want)
+	}
+	if got.Amount != "16 slices" {
+		t.Errorf("got amount %q", got.Amount)
+	}
+	// The original is left alone.
+	if pie.Ingredients[0].Items[0] != "6 apples" {
+		t.Errorf("original changed: %+v", pie.Ingredients)
+	}
+}
+
+func TestHiddenComponent(t *testing.T) {
+	var got, err = Recipe(pie, 1, func(id int) (*defs.Recipe, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Ingredients) != 1 || got.Ingredients[0].Heading != "Filling" {
+		t.Errorf("got %+v", got.Ingredients)
+	}
+}
+
+func TestShoppingList(t *testing.T) {
+	var got, err = ShoppingList(pie, 1, fetcher(pie, crust))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []defs.ShoppingItem{
+		{Quantity: "6", Name: "apples", Recipes: []string{"Apple Pie"}},
+		{Quantity: "1 1/4", Unit: "cup", Name: "butter",
+			Recipes: []string{"Apple Pie", "Pie Crust"}},
+		{Quantity: "1", Unit: "tbsp", Name: "flour",
+			Recipes: []string{"Apple Pie"}},
+		{Quantity: "2 1/2", Unit: "cups", Name: "flour",
+			Recipes: []string{"Pie Crust"}},
+		{Name: "salt", Recipes: []string{"Pie Crust"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	var a = &defs.Recipe{ID: 1, Title: "A", LinkedRecipes: []defs.LinkedRecipe{
+		{ID: 2, Role: defs.LinkComponent}}}
+	var b = &defs.Recipe{ID: 2, Title: "B", LinkedRecipes: []defs.LinkedRecipe{
+		{ID: 1, Role: defs.LinkComponent}}}
+	var _, err = Recipe(a, 1, fetcher(a, b))
+	if err != ErrCycle {
+		t.Errorf("got %v, want ErrCycle", err)
+	}
+}
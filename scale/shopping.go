@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file builds shopping lists from recipes.
+ */
+
+package scale
+
+import (
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// shoppingEntry is a ShoppingItem being built up.
+type shoppingEntry struct {
+	item defs.ShoppingItem
+	// The total amount, if the quantities could be read.
+	amount float64
+	// Whether amount is in use.
+	measured bool
+}
+
+// ShoppingList returns everything needed to make r scaled by factor,
+// including its components. Amounts of the same ingredient in the same unit
+// are added together; ones that can't be read are listed separately.
+func ShoppingList(r *defs.Recipe, factor float64,
+	fetch Fetcher) ([]defs.ShoppingItem, error) {
+	var entries []*shoppingEntry
+	var byKey = make(map[string]*shoppingEntry)
+	var err = walk([]*defs.Recipe{r}, factor, fetch,
+		func(path []*defs.Recipe, factor float64) {
+			var recipe = path[len(path)-1]
+			for _, line := range recipe.Ingredients.Lines() {
+				var ing = defs.ParseIngredient(line)
+				if ing.Name == "" {
+					continue
+				}
+				var _, high, measured = defs.ParseQuantity(ing.Quantity)
+				// Only like amounts can be added, so "2 eggs" and "a few
+				// eggs" stay apart.
+				var key = defs.Singular(strings.ToLower(ing.Unit)) + "|" +
+					defs.NormalizeTag(ing.Name, defs.TagFormSingular)
+				if !measured {
+					key += "|" + ing.Quantity
+				}
+				var e = byKey[key]
+				if e == nil {
+					e = &shoppingEntry{
+						item: defs.ShoppingItem{
+							Quantity: ing.Quantity,
+							Unit:     ing.Unit,
+							Name:     ing.Name,
+							Recipes:  []string{},
+						},
+						measured: measured,
+					}
+					byKey[key] = e
+					entries = append(entries, e)
+				}
+				if measured {
+					// Buy enough for the top of a range.
+					e.amount += high * factor
+				}
+				var seen bool
+				for _, title := range e.item.Recipes {
+					seen = seen || title == recipe.Title
+				}
+				if !seen {
+					e.item.Recipes = append(e.item.Recipes, recipe.Title)
+				}
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var items = make([]defs.ShoppingItem, len(entries))
+	for i, e := range entries {
+		items[i] = e.item
+		if e.measured {
+			items[i].Quantity = defs.FormatQuantity(e.amount)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file finds recipes that can be made from the ingredients on hand.
+ */
+
+package search
+
+import (
+	"sort"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// Cookable ranks recipes by the share of their ingredients found in have.
+// Ingredients matching staples don't count either way. An item matches an
+// ingredient if all its words are in the ingredient's name, so "chicken"
+// matches "2 boneless chicken breasts". Recipes with none of the ingredients
+// on hand are left out.
+func Cookable(recipes []defs.Recipe, have []string,
+	staples []string) []defs.CookableRecipe {
+	var haveWords = wordLists(have)
+	var stapleWords = wordLists(staples)
+
+	var results = make([]defs.CookableRecipe, 0, len(recipes))
+	for _, r := range recipes {
+		var result = defs.CookableRecipe{Recipe: r, Missing: []string{}}
+		var total, found int
+		for _, line := range r.Ingredients.Lines() {
+			var name = words(defs.ParseIngredient(line).Name)
+			if len(name) == 0 || matchesAny(name, stapleWords) {
+				continue
+			}
+			total++
+			if matchesAny(name, haveWords) {
+				found++
+			} else {
+				result.Missing = append(result.Missing, line)
+			}
+		}
+		if found == 0 {
+			continue
+		}
+		result.Coverage = float64(found) / float64(total)
+		results = append(results, result)
+	}
+
+	// Best coverage first, then the fewest things to buy.
+	sort.SliceStable(results, func(i, j int) bool {
+		var a, b = results[i], results[j]
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+		return a.Recipe.Title < b.Recipe.Title
+	})
+	return results
+}
+
+// wordLists splits each item into words, dropping empty ones.
+func wordLists(items []string) [][]string {
+	var lists = make([][]string, 0, len(items))
+	for _, item := range items {
+		if w := words(item); len(w) != 0 {
+			lists = append(lists, w)
+		}
+	}
+	return lists
+}
+
+// matchesAny reports whether name contains all the words of any item.
+func matchesAny(name []string, items [][]string) bool {
+	for _, item := range items {
+		if containsAll(name, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// ingredients puts lines in a single section without a heading.
+func ingredients(lines ...string) defs.IngredientSections {
+	return defs.IngredientSections{{Items: lines}}
+}
+
+func TestCookable(t *testing.T) {
+	var recipes = []defs.Recipe{
+		{Title: "Omelet", Ingredients: ingredients("3 eggs", "1 tbsp butter",
+			"1/4 cup shredded cheese", "salt")},
+		{Title: "Pancakes", Ingredients: defs.IngredientSections{
+			{Heading: "Dry", Items: []string{"2 cups flour", "1 tsp salt"}},
+			{Heading: "Wet", Items: []string{"2 eggs", "1 1/2 cups milk"}},
+		}},
+		{Title: "Salad", Ingredients: ingredients("1 head lettuce",
+			"2 tomatoes")},
+	}
+	var results = Cookable(recipes, []string{"Egg", "milk", "cheese"},
+		[]string{"salt", "butter"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Recipe.Title != "Omelet" || results[0].Coverage != 1 {
+		t.Errorf("wrong first result: %+v", results[0])
+	}
+	if results[1].Recipe.Title != "Pancakes" ||
+		len(results[1].Missing) != 1 ||
+		results[1].Missing[0] != "2 cups flour" {
+		t.Errorf("wrong second result: %+v", results[1])
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file finds recipes that are likely the same as each other.
+ */
+
+package search
+
+import (
+	"sort"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// DuplicateThreshold is the score from which two recipes are likely the same.
+const DuplicateThreshold = 0.7
+
+// fingerprint is the part of a recipe that duplicates are found by.
+type fingerprint struct {
+	title       map[string]bool
+	ingredients map[string]bool
+}
+
+func fingerprintOf(r *defs.Recipe) fingerprint {
+	return fingerprint{
+		title:       set(words(r.Title)),
+		ingredients: ingredientNames(r),
+	}
+}
+
+// score compares two fingerprints, from 0 for nothing in common to 1 for the
+// same title and ingredients. If either has no ingredients, as when a recipe
+// is first created, only the titles count.
+func (a fingerprint) score(b fingerprint) float64 {
+	var title = jaccard(a.title, b.title)
+	if len(a.ingredients) == 0 || len(b.ingredients) == 0 {
+		return title
+	}
+	return (title + jaccard(a.ingredients, b.ingredients)) / 2
+}
+
+// Duplicates returns the candidates that are likely the same recipe as r,
+// best match first. The recipe itself is left out.
+func Duplicates(r *defs.Recipe, candidates []defs.Recipe) []defs.SimilarRecipe {
+	var print = fingerprintOf(r)
+	var results = []defs.SimilarRecipe{}
+	for i := range candidates {
+		var c = &candidates[i]
+		if c.ID == r.ID && r.ID != 0 {
+			continue
+		}
+		var score = print.score(fingerprintOf(c))
+		if score >= DuplicateThreshold {
+			results = append(results,
+				defs.SimilarRecipe{ID: c.ID, Title: c.Title, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// DuplicatePairs finds the pairs of recipes that are likely the same, best
+// match first. Only recipes with a title word in common are compared.
+func DuplicatePairs(recipes []defs.Recipe) []defs.DuplicatePair {
+	var prints = make([]fingerprint, len(recipes))
+	// Which recipes have each title word.
+	var byWord = make(map[string][]int)
+	for i := range recipes {
+		prints[i] = fingerprintOf(&recipes[i])
+		for w := range prints[i].title {
+			byWord[w] = append(byWord[w], i)
+		}
+	}
+
+	var pairs = []defs.DuplicatePair{}
+	var compared = make(map[[2]int]bool)
+	for _, group := range byWord {
+		for x, i := range group {
+			for _, j := range group[x+1:] {
+				if compared[[2]int{i, j}] {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+				var score = prints[i].score(prints[j])
+				if score < DuplicateThreshold {
+					continue
+				}
+				pairs = append(pairs, defs.DuplicatePair{
+					FirstID:     recipes[i].ID,
+					FirstTitle:  recipes[i].Title,
+					SecondID:    recipes[j].ID,
+					SecondTitle: recipes[j].Title,
+					Score:       score,
+				})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		if pairs[i].FirstID != pairs[j].FirstID {
+			return pairs[i].FirstID < pairs[j].FirstID
+		}
+		return pairs[i].SecondID < pairs[j].SecondID
+	})
+	return pairs
+}
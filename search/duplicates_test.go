@@ -0,0 +1,32 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestDuplicates(t *testing.T) {
+	var recipes = []defs.Recipe{
+		{ID: 1, Title: "Banana Bread",
+			Ingredients: ingredients("3 bananas", "2 cups flour", "1 egg")},
+		{ID: 2, Title: "banana bread",
+			Ingredients: ingredients("3 ripe bananas", "2 cups flour",
+				"2 eggs")},
+		{ID: 3, Title: "Banana Bread",
+			Ingredients: ingredients("1 cup peanut butter", "1 banana")},
+		{ID: 4, Title: "Zucchini Bread",
+			Ingredients: ingredients("2 zucchini", "2 cups flour", "1 egg")},
+	}
+
+	// A new recipe has only a title.
+	var got = Duplicates(&defs.Recipe{Title: "Banana breads"}, recipes)
+	if len(got) != 3 || got[0].Score != 1 {
+		t.Errorf("Duplicates: got %+v", got)
+	}
+
+	var pairs = DuplicatePairs(recipes)
+	if len(pairs) != 1 || pairs[0].FirstID != 1 || pairs[0].SecondID != 2 {
+		t.Errorf("DuplicatePairs: got %+v", pairs)
+	}
+}
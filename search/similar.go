@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file suggests recipes that are like a given one.
+ */
+
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// How much each kind of overlap counts toward similarity. They add up to 1.
+const (
+	tagWeight        = 0.4
+	ingredientWeight = 0.4
+	titleWeight      = 0.2
+)
+
+// Similar ranks candidates by how much they have in common with the recipe,
+// returning at most limit of them. Tags, ingredient names, and title words
+// each count. The recipe itself, recipes it already links to, and recipes with
+// nothing in common are left out.
+func Similar(recipe *defs.Recipe, candidates []defs.Recipe,
+	limit int) []defs.SimilarRecipe {
+	// Things we won't suggest.
+	var skip = map[int]bool{recipe.ID: true}
+	for _, lr := range recipe.LinkedRecipes {
+		skip[lr.ID] = true
+	}
+	var tags = set(lowerAll(recipe.Tags))
+	var ingredients = ingredientNames(recipe)
+	var title = set(words(recipe.Title))
+
+	var results = make([]defs.SimilarRecipe, 0, 20)
+	for i := range candidates {
+		var c = &candidates[i]
+		if skip[c.ID] {
+			continue
+		}
+		var score = tagWeight*jaccard(tags, set(lowerAll(c.Tags))) +
+			ingredientWeight*jaccard(ingredients, ingredientNames(c)) +
+			titleWeight*jaccard(title, set(words(c.Title)))
+		if score == 0 {
+			continue
+		}
+		results = append(results,
+			defs.SimilarRecipe{ID: c.ID, Title: c.Title, Score: score})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Title < results[j].Title
+	})
+	if limit != 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// lowerAll returns a lower case copy of a list.
+func lowerAll(list []string) []string {
+	var lower = make([]string, len(list))
+	for i, s := range list {
+		lower[i] = strings.ToLower(s)
+	}
+	return lower
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+func TestSimilar(t *testing.T) {
+	var pie = defs.Recipe{ID: 1, Title: "Apple Pie",
+		Tags:          []string{"dessert", "baked"},
+		Ingredients:   ingredients("6 apples", "1 cup sugar", "1 pie crust"),
+		LinkedRecipes: []defs.LinkedRecipe{{ID: 4, Title: "Pie Crust"}}}
+	var candidates = []defs.Recipe{
+		pie,
+		{ID: 2, Title: "Cherry Pie", Tags: []string{"Dessert", "baked"},
+			Ingredients: ingredients("4 cups cherries", "1 cup sugar",
+				"1 pie crust")},
+		{ID: 3, Title: "Apple Sauce", Tags: []string{"side"},
+			Ingredients: ingredients("6 apples", "1/2 cup water")},
+		{ID: 4, Title: "Pie Crust", Ingredients: ingredients("flour")},
+		{ID: 5, Title: "Chili", Ingredients: ingredients("beans")},
+	}
+	var results = Similar(&pie, candidates, 10)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].ID != 2 || results[1].ID != 3 {
+		t.Errorf("wrong order: %+v", results)
+	}
+	if results = Similar(&pie, candidates, 1); len(results) != 1 {
+		t.Errorf("limit not applied: %+v", results)
+	}
+}
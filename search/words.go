@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * This file has helpers for comparing bits of recipe text.
+ */
+
+// Package search ranks recipes by how well they match something, like the
+// ingredients on hand or another recipe.
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rwestlund/recipes/defs"
+)
+
+// words splits text into lower case words, dropping punctuation and simple
+// plural endings so "Eggs" and "egg" compare equal.
+func words(s string) []string {
+	var list = strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range list {
+		list[i] = defs.Singular(w)
+	}
+	return list
+}
+
+// containsAll reports whether every word in want is in have.
+func containsAll(have []string, want []string) bool {
+	if len(want) == 0 {
+		return false
+	}
+	for _, w := range want {
+		var found = false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// set turns a list of strings into a set.
+func set(list []string) map[string]bool {
+	var s = make(map[string]bool, len(list))
+	for _, item := range list {
+		s[item] = true
+	}
+	return s
+}
+
+// jaccard returns the size of the intersection of two sets over the size of
+// their union, from 0 for nothing in common to 1 for the same set.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	var shared = 0
+	for item := range a {
+		if b[item] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// ingredientNames returns the normalized names of a recipe's ingredients, so
+// "2 cups Flour, sifted" and "1 cup flour" compare equal.
+func ingredientNames(r *defs.Recipe) map[string]bool {
+	var lines = r.Ingredients.Lines()
+	var names = make(map[string]bool, len(lines))
+	for _, line := range lines {
+		var name = strings.Join(words(defs.ParseIngredient(line).Name), " ")
+		if name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
@@ -15,14 +15,24 @@ export const RecipeCard: FC<RecipeCardProps> = ({ recipe }) => {
       <div>{recipe.summary}</div>
 
       <h4>Ingredients</h4>
-      <ul>
-        {recipe.ingredients.map((i, index) => <li key={index}>{i}</li>)}
-      </ul>
+      {recipe.ingredients.map((s, index) =>
+        <div key={index}>
+          {s.heading ? <h5>{s.heading}</h5> : null}
+          <ul>
+            {s.items.map((i, index) => <li key={index}>{i}</li>)}
+          </ul>
+        </div>
+      )}
 
       <h4>Directions</h4>
-      <ol>
-        {recipe.directions.map((d, index) => <li key={index}>{d}</li>)}
-      </ol>
+      {recipe.directions.map((s, index) =>
+        <div key={index}>
+          {s.heading ? <h5>{s.heading}</h5> : null}
+          <ol>
+            {s.items.map((d, index) => <li key={index}>{d.text}</li>)}
+          </ol>
+        </div>
+      )}
 
       <table>
         <tbody>
@@ -30,6 +40,13 @@ export const RecipeCard: FC<RecipeCardProps> = ({ recipe }) => {
           {recipe.time ? <tr><td>Time</td><td>{recipe.time}</td></tr>: null}
           {recipe.oven ? <tr><td>Oven</td><td>{recipe.oven}</td></tr>: null}
           {recipe.author_name ? <tr><td>Author</td><td>{recipe.author_name}</td></tr>: null}
+          {recipe.parent
+            ? <tr>
+                <td>Variant Of</td>
+                <td><Link to={`/recipes/${recipe.parent.id}`}>{recipe.parent.title}</Link></td>
+              </tr>
+            : null
+          }
           {recipe.notes ? <tr><td>Notes</td><td>{recipe.notes}</td></tr>: null}
           {recipe.linked_recipes.length > 0
             ? <tr>
@@ -39,6 +56,14 @@ export const RecipeCard: FC<RecipeCardProps> = ({ recipe }) => {
               </tr>
             : null
           }
+          {recipe.used_in.length > 0
+            ? <tr>
+                <td>Used In</td>
+                <td>{recipe.used_in.map((r,i,o) =>
+                  <LinkedRecipeLink key={r.id} recipe={r} showComma={i+1 !== o.length} />)}</td>
+              </tr>
+            : null
+          }
         </tbody>
       </table>
     </div>
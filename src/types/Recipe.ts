@@ -3,20 +3,72 @@ export interface Recipe {
 	revision: number
 	amount: string
 	author_id: number
-	directions: string[]
-	ingredients: string[]
+	directions: DirectionSection[]
+	ingredients: IngredientSection[]
 	notes: string
 	oven: string
 	source: string
 	summary: string
 	time: string
 	title: string
+	slug: string
+	minutes: number
+	visibility: 'public' | 'private'
+	creation_date: string
+	modified_date: string
 	tags: string[]
 	author_name: string
+	parent: RecipeParent | null
 	linked_recipes: LinkedRecipe[]
+	used_in: LinkedRecipe[]
+	photos: Photo[]
+	attachments: Attachment[]
+	rating: number
+	rating_count: number
+}
+
+export interface RecipeParent {
+	id: number
+	title: string
+	revision: number
+	current_revision: number
 }
 
 export interface LinkedRecipe {
 	id: number
 	title: string
+	role: '' | 'component' | 'variation' | 'serve-with'
+	quantity: string
+}
+
+export interface Photo {
+	id: number
+	cover: boolean
+	width: number
+	height: number
+	url: string
+	thumb_url: string
+}
+
+export interface IngredientSection {
+	heading: string
+	items: string[]
+}
+
+export interface DirectionSection {
+	heading: string
+	items: Direction[]
+}
+
+export interface Direction {
+	text: string
+	attachments?: number[]
+}
+
+export interface Attachment {
+	id: number
+	filename: string
+	content_type: string
+	size: number
+	url: string
 }
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2016-2017, Randy Westlund. All rights reserved.
+ * This code is under the BSD-2-Clause license.
+ *
+ * Check recipes written in Markdown, and optionally import them. See the
+ * markdown package for the format. Problems are printed as file:line: message.
+ *
+ * Usage: go run tools/importmd/main.go [-author id] file.md...
+ *
+ * Without -author, files are only checked. With it, each file without
+ * problems is saved as a new recipe owned by that user.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/rwestlund/recipes/config"
+	"github.com/rwestlund/recipes/db"
+	"github.com/rwestlund/recipes/markdown"
+)
+
+func main() {
+	var author = flag.Int("author", 0,
+		"ID of the user to import recipes as; without it, only check them")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: importmd [-author id] file.md...")
+		os.Exit(2)
+	}
+	if *author != 0 {
+		var err = db.Init(config.DatabaseName, config.DatabaseUserName)
+		if err != nil {
+			log.Println(err)
+			log.Fatal("ERROR: failed to connect to the DB")
+		}
+	}
+
+	var failed = false
+	for _, name := range flag.Args() {
+		var data, err = ioutil.ReadFile(name)
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+		recipe, err := markdown.Parse(data)
+		if errs, ok := err.(markdown.Errors); ok {
+			for _, e := range errs {
+				fmt.Printf("%s:%d: %s\n", name, e.Line, e.Message)
+			}
+			failed = true
+			continue
+		} else if err != nil {
+			log.Println(name+":", err)
+			failed = true
+			continue
+		}
+		if *author == 0 {
+			continue
+		}
+		saved, err := db.ImportRecipe(recipe, *author)
+		if err != nil {
+			log.Println(name+":", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: imported as recipe %d\n", name, saved.ID)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
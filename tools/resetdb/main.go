@@ -33,7 +33,14 @@ func main() {
 	}
 
 	log.Println("dropping old objects")
+	wrap_sql(db, "DROP TABLE IF EXISTS ratings")
+	wrap_sql(db, "DROP TABLE IF EXISTS attachments")
+	wrap_sql(db, "DROP TABLE IF EXISTS photos")
 	wrap_sql(db, "DROP TABLE IF EXISTS linked_recipes")
+	wrap_sql(db, "DROP TABLE IF EXISTS tag_parents")
+	wrap_sql(db, "DROP TABLE IF EXISTS tag_synonyms")
+	wrap_sql(db, "DROP TABLE IF EXISTS tag_info")
+	wrap_sql(db, "DROP TABLE IF EXISTS recipe_slugs")
 	wrap_sql(db, "DROP TABLE IF EXISTS tags CASCADE")
 	wrap_sql(db, "DROP TABLE IF EXISTS recipes CASCADE")
 	wrap_sql(db, "DROP TABLE IF EXISTS users")
@@ -62,19 +69,90 @@ func main() {
         source      text NOT NULL DEFAULT '',
         summary     text NOT NULL DEFAULT '',
         time        text NOT NULL DEFAULT '',
-        title       text NOT NULL
+        title       text NOT NULL,
+        -- Made from the title, for readable URLs.
+        slug        text NOT NULL UNIQUE,
+        -- Time parsed into minutes for filtering and sorting, 0 if unknown.
+        minutes     integer NOT NULL DEFAULT 0,
+        -- Who may see the recipe: 'public' or 'private'.
+        visibility  text NOT NULL DEFAULT 'public'
+                        CHECK (visibility IN ('public', 'private')),
+        creation_date   timestamp WITH TIME ZONE NOT NULL
+                            DEFAULT CURRENT_TIMESTAMP,
+        modified_date   timestamp WITH TIME ZONE NOT NULL
+                            DEFAULT CURRENT_TIMESTAMP,
+        -- The recipe this was forked from, and its revision at the time.
+        parent_id       integer REFERENCES recipes(id) ON DELETE SET NULL,
+        parent_revision integer
+    )`)
+	// Slugs recipes used to have, so old links still work.
+	wrap_sql(db, `CREATE TABLE recipe_slugs (
+        slug        text PRIMARY KEY,
+        recipe_id   integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL
     )`)
 	wrap_sql(db, `CREATE TABLE tags (
         recipe_id       integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
         tag             text NOT NULL,
         UNIQUE(recipe_id, tag)
+    )`)
+	wrap_sql(db, `CREATE TABLE tag_info (
+        tag             text PRIMARY KEY,
+        description     text NOT NULL DEFAULT '',
+        color           text NOT NULL DEFAULT ''
+    )`)
+	wrap_sql(db, `CREATE TABLE tag_synonyms (
+        synonym         text PRIMARY KEY,
+        tag             text NOT NULL
+    )`)
+	wrap_sql(db, `CREATE TABLE tag_parents (
+        tag             text NOT NULL,
+        parent          text NOT NULL,
+        CONSTRAINT must_be_different CHECK ( tag != parent ),
+        UNIQUE (tag, parent)
     )`)
 	wrap_sql(db, `CREATE TABLE linked_recipes (
         src     integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
         dest    integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
+        -- Empty for a plain link. Components form no cycles, which is
+        -- checked when saving.
+        role        text NOT NULL DEFAULT '' CHECK ( role IN
+                        ('', 'component', 'variation', 'serve-with') ),
+        -- How much of dest a component uses, like "2 batches".
+        quantity    text NOT NULL DEFAULT '',
         CONSTRAINT must_be_different CHECK ( src != dest ),
         UNIQUE (src, dest)
     )`)
+	wrap_sql(db, `CREATE TABLE photos (
+        id          serial PRIMARY KEY,
+        recipe_id   integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
+        position    integer NOT NULL,
+        cover       boolean NOT NULL DEFAULT false,
+        width       integer NOT NULL,
+        height      integer NOT NULL,
+        creation_date   timestamp WITH TIME ZONE NOT NULL
+                            DEFAULT CURRENT_TIMESTAMP
+    )`)
+	// Each recipe has at most one cover photo.
+	wrap_sql(db, `CREATE UNIQUE INDEX photos_one_cover
+        ON photos (recipe_id) WHERE cover`)
+
+	wrap_sql(db, `CREATE TABLE attachments (
+        id              serial PRIMARY KEY,
+        recipe_id       integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
+        filename        text NOT NULL,
+        -- Sniffed from the contents when uploaded.
+        content_type    text NOT NULL,
+        size            bigint NOT NULL,
+        creation_date   timestamp WITH TIME ZONE NOT NULL
+                            DEFAULT CURRENT_TIMESTAMP
+    )`)
+	// Each user gives a recipe at most one rating.
+	wrap_sql(db, `CREATE TABLE ratings (
+        recipe_id   integer REFERENCES recipes(id) ON DELETE CASCADE NOT NULL,
+        user_id     integer REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+        rating      integer NOT NULL CHECK ( rating BETWEEN 1 AND 5 ),
+        UNIQUE (recipe_id, user_id)
+    )`)
 
 	log.Println("complete")
 }